@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -18,14 +19,21 @@ import (
 	"github.com/unkeyed/unkey/apps/agent/pkg/cluster"
 	"github.com/unkeyed/unkey/apps/agent/pkg/config"
 	"github.com/unkeyed/unkey/apps/agent/pkg/connect"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/geoip"
+	"github.com/unkeyed/unkey/apps/agent/pkg/loadshedding"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/membership"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
 	"github.com/unkeyed/unkey/apps/agent/pkg/profiling"
 	"github.com/unkeyed/unkey/apps/agent/pkg/prometheus"
+	"github.com/unkeyed/unkey/apps/agent/pkg/repeat"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tinybird"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
 	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
 	"github.com/unkeyed/unkey/apps/agent/pkg/version"
+	"github.com/unkeyed/unkey/apps/agent/services/alerting"
+	"github.com/unkeyed/unkey/apps/agent/services/billing"
 	"github.com/unkeyed/unkey/apps/agent/services/eventrouter"
 	"github.com/unkeyed/unkey/apps/agent/services/ratelimit"
 	"github.com/unkeyed/unkey/apps/agent/services/vault"
@@ -85,25 +93,65 @@ func run(c *cli.Context) error {
 		return err
 	}
 
-	{
-		if cfg.Tracing != nil && cfg.Tracing.Axiom != nil {
-			var closeTracer tracing.Closer
+	// Shared by traces and metrics
+	otelResource, err := tracing.NewResource(tracing.ResourceConfig{
+		Application: "agent",
+		Version:     version.Version,
+		NodeId:      cfg.NodeId,
+		Region:      cfg.Region,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg.Tracing != nil {
+		var closeTracer tracing.Closer
+		switch {
+		case cfg.Tracing.Axiom != nil:
 			closeTracer, err = tracing.Init(context.Background(), tracing.Config{
 				Dataset:     cfg.Tracing.Axiom.Dataset,
 				Application: "agent",
 				Version:     "1.0.0",
 				AxiomToken:  cfg.Tracing.Axiom.Token,
+				Resource:    otelResource,
+				SampleRatio: cfg.Tracing.SampleRatio,
 			})
-			if err != nil {
-				return err
-			}
+			logger.Info().Msg("tracing to axiom")
+		case cfg.Tracing.Otlp != nil:
+			closeTracer, err = tracing.InitOtlp(context.Background(), tracing.OtlpConfig{
+				Endpoint:    cfg.Tracing.Otlp.Endpoint,
+				Insecure:    cfg.Tracing.Otlp.Insecure,
+				Headers:     cfg.Tracing.Otlp.Headers,
+				Resource:    otelResource,
+				SampleRatio: cfg.Tracing.SampleRatio,
+			})
+			logger.Info().Str("endpoint", cfg.Tracing.Otlp.Endpoint).Msg("tracing to otlp collector")
+		case cfg.Tracing.Zipkin != nil:
+			closeTracer, err = tracing.InitZipkin(context.Background(), tracing.ZipkinConfig{
+				Url:         cfg.Tracing.Zipkin.Url,
+				Resource:    otelResource,
+				SampleRatio: cfg.Tracing.SampleRatio,
+			})
+			logger.Info().Str("url", cfg.Tracing.Zipkin.Url).Msg("tracing to zipkin")
+		case cfg.Tracing.Jaeger != nil:
+			closeTracer, err = tracing.InitJaeger(context.Background(), tracing.JaegerConfig{
+				Endpoint:    cfg.Tracing.Jaeger.Endpoint,
+				Insecure:    cfg.Tracing.Jaeger.Insecure,
+				Resource:    otelResource,
+				SampleRatio: cfg.Tracing.SampleRatio,
+			})
+			logger.Info().Str("endpoint", cfg.Tracing.Jaeger.Endpoint).Msg("tracing to jaeger")
+		}
+		if err != nil {
+			return err
+		}
+		if closeTracer != nil {
 			defer func() {
 				err = closeTracer()
 				if err != nil {
 					logger.Error().Err(err).Msg("failed to close tracer")
 				}
 			}()
-			logger.Info().Msg("tracing to axiom")
 		}
 	}
 
@@ -120,58 +168,105 @@ func run(c *cli.Context) error {
 			logger.Fatal().Err(err).Msg("unable to start metrics")
 		}
 
+	} else if cfg.Metrics != nil && cfg.Metrics.Otlp != nil {
+		m, err = metrics.NewOtel(metrics.OtelConfig{
+			Endpoint: cfg.Metrics.Otlp.Endpoint,
+			Headers:  cfg.Metrics.Otlp.Headers,
+			Interval: time.Duration(cfg.Metrics.Otlp.Interval) * time.Second,
+			Resource: otelResource,
+			Logger:   logger.With().Str("pkg", "metrics").Logger(),
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("unable to start metrics")
+		}
 	}
 	defer m.Close()
+	if cfg.Metrics != nil {
+		stopRuntimeMetrics := metrics.ReportRuntime(m, 10*time.Second)
+		defer stopRuntimeMetrics()
+	}
+
+	if cfg.Prometheus != nil && cfg.Prometheus.MaxLabelValues > 0 {
+		prometheus.SetLabelLimit(cfg.Prometheus.MaxLabelValues)
+	}
 
 	if cfg.Heartbeat != nil {
 		setupHeartbeat(cfg, logger)
 	}
 
+	// Reported by /readyz, by dependency
+	readinessChecks := map[string]func(ctx context.Context) error{}
+
 	var ch clickhouse.Bufferer = clickhouse.NewNoop()
+	var analytics clickhouse.Querier = clickhouse.NewNoop()
+	var chClient *clickhouse.Clickhouse
 	if cfg.Clickhouse != nil {
-		ch, err = clickhouse.New(clickhouse.Config{
-			URL:    cfg.Clickhouse.Url,
-			Logger: logger.With().Str("pkg", "clickhouse").Logger(),
+		chLogger := logging.Module(logger, "clickhouse")
+		var chErr error
+		chClient, chErr = clickhouse.New(clickhouse.Config{
+			URL:           cfg.Clickhouse.Url,
+			Logger:        chLogger,
+			DeadLetterDir: cfg.Clickhouse.DeadLetterDir,
 		})
-		if err != nil {
-			return err
+		if chErr != nil {
+			return chErr
 		}
-	}
+		ch = chClient
+		defer func() {
+			logger.Info().Msg("flushing clickhouse buffers")
+			shutdownErr := chClient.Shutdown(context.Background())
+			if shutdownErr != nil {
+				logger.Error().Err(shutdownErr).Msg("failed to shutdown clickhouse")
+			}
+		}()
 
-	s3, err := storage.NewS3(storage.S3Config{
-		S3URL:             cfg.Services.Vault.S3Url,
-		S3Bucket:          cfg.Services.Vault.S3Bucket,
-		S3AccessKeyId:     cfg.Services.Vault.S3AccessKeyId,
-		S3AccessKeySecret: cfg.Services.Vault.S3AccessKeySecret,
-		Logger:            logger,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create s3 storage: %w", err)
-	}
-	s3 = storageMiddleware.WithTracing("s3", s3)
-	v, err := vault.New(vault.Config{
-		Logger:     logger,
-		Metrics:    m,
-		Storage:    s3,
-		MasterKeys: strings.Split(cfg.Services.Vault.MasterKeys, ","),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create vault: %w", err)
-	}
+		slowQueryThreshold := time.Second
+		if cfg.Clickhouse.SlowQueryThreshold > 0 {
+			slowQueryThreshold = time.Duration(cfg.Clickhouse.SlowQueryThreshold) * time.Millisecond
+		}
+		analytics = clickhouse.WithCircuitBreaker(
+			clickhouse.WithSlowQueryLog(chClient, slowQueryThreshold, chLogger),
+			chLogger,
+		)
+
+		if cfg.Clickhouse.Export != nil {
+			stopExport := chClient.StartExport(clickhouse.ExportConfig{
+				Url:             cfg.Clickhouse.Export.Url,
+				AccessKeyId:     cfg.Clickhouse.Export.AccessKeyId,
+				AccessKeySecret: cfg.Clickhouse.Export.AccessKeySecret,
+			})
+			defer stopExport()
+		}
 
-	if err != nil {
-		return fmt.Errorf("failed to create vault service: %w", err)
+		if cfg.Clickhouse.UsageWebhook != nil {
+			b, billingErr := billing.New(billing.Config{
+				Logger:          logger.With().Str("service", "billing").Logger(),
+				Analytics:       analytics,
+				WebhookUrl:      cfg.Clickhouse.UsageWebhook.Url,
+				Secret:          cfg.Clickhouse.UsageWebhook.Secret,
+				Interval:        time.Duration(cfg.Clickhouse.UsageWebhook.Interval) * time.Second,
+				SettlementDelay: time.Duration(cfg.Clickhouse.UsageWebhook.SettlementDelay) * time.Second,
+			})
+			if billingErr != nil {
+				return billingErr
+			}
+			stopBilling := b.Start()
+			defer stopBilling()
+		}
 	}
 
 	var clus cluster.Cluster
+	var memb membership.Membership
 
 	if cfg.Cluster != nil {
 
-		memb, membershipErr := membership.New(membership.Config{
+		var membershipErr error
+		memb, membershipErr = membership.New(membership.Config{
 			NodeId:   cfg.NodeId,
 			RpcAddr:  cfg.Cluster.RpcAddr,
 			SerfAddr: cfg.Cluster.SerfAddr,
-			Logger:   logger,
+			Region:   cfg.Region,
+			Logger:   logging.Module(logger, "cluster"),
 		})
 		if membershipErr != nil {
 			return fmt.Errorf("failed to create membership: %w", membershipErr)
@@ -205,7 +300,7 @@ func run(c *cli.Context) error {
 			NodeId:     cfg.NodeId,
 			RpcAddr:    cfg.Cluster.RpcAddr,
 			Membership: memb,
-			Logger:     logger,
+			Logger:     logging.Module(logger, "cluster"),
 			Metrics:    m,
 			Debug:      true,
 			AuthToken:  cfg.Cluster.AuthToken,
@@ -213,6 +308,19 @@ func run(c *cli.Context) error {
 		if err != nil {
 			return fmt.Errorf("failed to create cluster: %w", err)
 		}
+		// The first node of a cluster has nobody to join, dns usually returns
+		// the node itself too
+		peers := membership.Peers(join, cfg.Cluster.SerfAddr)
+		readinessChecks["cluster"] = func(ctx context.Context) error {
+			members, membersErr := memb.Members()
+			if membersErr != nil {
+				return membersErr
+			}
+			if len(peers) > 0 && len(members) < 2 {
+				return fmt.Errorf("node has not joined the cluster")
+			}
+			return nil
+		}
 		defer func() {
 			shutdownErr := clus.Shutdown()
 			if shutdownErr != nil {
@@ -222,14 +330,110 @@ func run(c *cli.Context) error {
 
 	}
 
-	rl, err := ratelimit.New(ratelimit.Config{
-		Logger:  logger,
+	s3, err := storage.NewS3(storage.S3Config{
+		S3URL:             cfg.Services.Vault.S3Url,
+		S3Bucket:          cfg.Services.Vault.S3Bucket,
+		S3AccessKeyId:     cfg.Services.Vault.S3AccessKeyId,
+		S3AccessKeySecret: cfg.Services.Vault.S3AccessKeySecret,
+		Logger:            logging.Module(logger, "vault"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create s3 storage: %w", err)
+	}
+	s3 = storageMiddleware.WithTracing("s3", s3)
+	cacheLogger := logging.Module(logger, "cache")
+	v, err := vault.New(vault.Config{
+		Logger:      logging.Module(logger, "vault"),
+		CacheLogger: &cacheLogger,
+		Metrics:     m,
+		Storage:     s3,
+		MasterKeys:  strings.Split(cfg.Services.Vault.MasterKeys, ","),
+		Membership:  memb,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create vault: %w", err)
+	}
+	defer v.Close()
+	if cfg.Services.Vault.KeyCacheSnapshot != "" {
+		stopSnapshots, snapshotErr := v.PersistKeyCache(context.Background(), cfg.Services.Vault.KeyCacheSnapshot)
+		if snapshotErr != nil {
+			return fmt.Errorf("failed to persist vault cache: %w", snapshotErr)
+		}
+		defer func() {
+			snapshotErr := stopSnapshots()
+			if snapshotErr != nil {
+				logger.Error().Err(snapshotErr).Msg("failed to write vault cache snapshot")
+			}
+		}()
+	}
+	if len(cfg.Services.Vault.WarmupKeyrings) > 0 {
+		warmupCtx, cancelWarmup := context.WithTimeout(context.Background(), 30*time.Second)
+		warmupErr := v.Warmup(warmupCtx, cfg.Services.Vault.WarmupKeyrings)
+		cancelWarmup()
+		if warmupErr != nil {
+			// A cold cache is not fatal, we just serve the first requests slower
+			logger.Warn().Err(warmupErr).Msg("failed to warm up vault cache")
+		}
+	}
+
+	rlService, err := ratelimit.New(ratelimit.Config{
+		Logger:  logging.Module(logger, "ratelimit"),
 		Metrics: m,
 		Cluster: clus,
 	})
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to create service")
 	}
+	ratelimitExceeded := events.NewTopic[ratelimit.ExceededEvent](100)
+	rl := ratelimit.WithExceededEvents(ratelimitExceeded)(ratelimit.WithMetrics(m)(rlService))
+
+	var loadShedding *loadshedding.Limiter
+	if cfg.LoadShedding != nil {
+		targetLatency := 250 * time.Millisecond
+		if cfg.LoadShedding.TargetLatency > 0 {
+			targetLatency = time.Duration(cfg.LoadShedding.TargetLatency) * time.Millisecond
+		}
+		minLimit, maxLimit := 10, 1000
+		if cfg.LoadShedding.MinLimit > 0 {
+			minLimit = cfg.LoadShedding.MinLimit
+		}
+		if cfg.LoadShedding.MaxLimit > 0 {
+			maxLimit = cfg.LoadShedding.MaxLimit
+		}
+		// shared by the http and rpc ratelimit endpoints, they compete for the same resources
+		loadShedding = loadshedding.New("ratelimit",
+			loadshedding.WithTargetLatency(targetLatency),
+			loadshedding.WithMinLimit(minLimit),
+			loadshedding.WithMaxLimit(maxLimit),
+			loadshedding.WithLogger(logging.Module(logger, "ratelimit")),
+		)
+
+		if memb != nil {
+			// let the other nodes know how busy we are
+			stopReportingLoad := repeat.Every(10*time.Second, func() {
+				loadErr := memb.SetLoad(loadShedding.Utilization())
+				if loadErr != nil {
+					logger.Error().Err(loadErr).Msg("failed to share load with cluster")
+				}
+			})
+			defer stopReportingLoad()
+		}
+	}
+
+	var audit *logging.Logger
+	if cfg.Logging != nil && cfg.Logging.Audit != nil {
+		auditWriter, auditErr := logging.NewAuditWriter(cfg.Logging.Audit.Path, []byte(cfg.Logging.Audit.Key))
+		if auditErr != nil {
+			return auditErr
+		}
+		defer auditWriter.Close()
+		if torn := auditWriter.TornBytes(); torn > 0 {
+			logger.Warn().Int64("bytes", torn).Msg("truncated a partially written line of the audit log")
+		}
+		auditLogger := logging.NewAuditLogger(auditWriter)
+		audit = &auditLogger
+		logger.Info().Str("path", cfg.Logging.Audit.Path).Msg("writing audit log")
+	}
 
 	srv, err := api.New(api.Config{
 		NodeId:     cfg.NodeId,
@@ -239,28 +443,102 @@ func run(c *cli.Context) error {
 		Clickhouse: ch,
 		AuthToken:  cfg.Cluster.AuthToken,
 		Vault:      v,
+		Caches:     v.Caches(),
+		Analytics:  analytics,
+		Audit:      audit,
+
+		ReadinessChecks: readinessChecks,
+		RatelimitStats:  rlService,
+		TrustedProxies:  cfg.TrustedProxies,
+		LoadShedding:    loadShedding,
 	})
 	if err != nil {
 		return err
 	}
 
 	if cfg.Services.EventRouter != nil {
+		geo := geoip.NewNoop()
+		if cfg.Services.EventRouter.Geo != nil {
+			geo, err = geoip.New(geoip.Config{
+				CountryDatabase: cfg.Services.EventRouter.Geo.CountryDatabase,
+				AsnDatabase:     cfg.Services.EventRouter.Geo.AsnDatabase,
+				Logger:          logger.With().Str("pkg", "geoip").Logger(),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to load geoip databases: %w", err)
+			}
+		}
+
+		var sampling *eventrouter.SamplingConfig
+		if s := cfg.Services.EventRouter.Sampling; s != nil {
+			sampling = &eventrouter.SamplingConfig{
+				Threshold:      s.Threshold,
+				Rate:           s.Rate,
+				WorkspaceRates: s.WorkspaceRates,
+			}
+		}
+
 		var er *eventrouter.Service
 		er, err = eventrouter.New(eventrouter.Config{
-			Logger:        logger,
-			Metrics:       m,
-			BatchSize:     cfg.Services.EventRouter.Tinybird.BatchSize,
-			BufferSize:    cfg.Services.EventRouter.Tinybird.BufferSize,
-			FlushInterval: time.Duration(cfg.Services.EventRouter.Tinybird.FlushInterval) * time.Second,
-			Tinybird:      tinybird.New("https://api.tinybird.co", cfg.Services.EventRouter.Tinybird.Token),
-			Clickhouse:    ch,
-			AuthToken:     cfg.AuthToken,
+			Logger:           logging.Module(logger, "eventrouter"),
+			Metrics:          m,
+			BatchSize:        cfg.Services.EventRouter.Tinybird.BatchSize,
+			BufferSize:       cfg.Services.EventRouter.Tinybird.BufferSize,
+			FlushInterval:    time.Duration(cfg.Services.EventRouter.Tinybird.FlushInterval) * time.Second,
+			Tinybird:         tinybird.New("https://api.tinybird.co", cfg.Services.EventRouter.Tinybird.Token),
+			Clickhouse:       ch,
+			AuthToken:        cfg.AuthToken,
+			NodeId:           cfg.NodeId,
+			Geo:              geo,
+			Sampling:         sampling,
+			WorkspaceMetrics: cfg.Services.EventRouter.WorkspaceMetrics,
+			DeadLetterDir:    cfg.Services.EventRouter.Tinybird.DeadLetterDir,
 		})
 		if err != nil {
 			return err
 		}
+		// Deferred calls run after the servers stopped accepting requests
+		defer er.Close()
 		srv.WithEventRouter(er)
+		srv.WithBuffer("eventrouter", er.Flush)
+
+		if cfg.Services.Alerting != nil {
+			rules := make([]alerting.Rule, len(cfg.Services.Alerting.Rules))
+			for i, r := range cfg.Services.Alerting.Rules {
+				rules[i] = alerting.Rule{
+					Id:               r.Id,
+					WorkspaceId:      r.WorkspaceId,
+					ApiId:            r.ApiId,
+					KeyId:            r.KeyId,
+					Window:           time.Duration(r.Window) * time.Second,
+					MaxVerifications: r.MaxVerifications,
+					MaxErrorRate:     r.MaxErrorRate,
+					MinVerifications: r.MinVerifications,
+				}
+			}
+			var a *alerting.Service
+			a, err = alerting.New(alerting.Config{
+				Logger:            logger.With().Str("service", "alerting").Logger(),
+				Verifications:     er.Verifications(),
+				Rules:             rules,
+				WebhookUrl:        cfg.Services.Alerting.WebhookUrl,
+				Secret:            cfg.Services.Alerting.Secret,
+				RatelimitExceeded: ratelimitExceeded,
+				UsageExceeded:     er.UsageExceeded(),
+			})
+			if err != nil {
+				return err
+			}
+			srv.WithDeadLetters("alerting", a.DeadLetters())
+			srv.WithDeadLetters("alerting.thresholds", a.ThresholdDeadLetters())
+			stopAlerting := a.Start()
+			defer stopAlerting()
+		}
+	}
 
+	// After the event router, which writes into clickhouse
+	if chClient != nil {
+		srv.WithBuffer("clickhouse", chClient.Flush)
 	}
 
 	connectSrv, err := connect.New(connect.Config{Logger: logger, Image: cfg.Image, Metrics: m})
@@ -273,7 +551,7 @@ func run(c *cli.Context) error {
 		return fmt.Errorf("failed to add cluster service: %w", err)
 
 	}
-	err = connectSrv.AddService(connect.NewRatelimitServer(rl, logger, cfg.AuthToken))
+	err = connectSrv.AddService(connect.NewRatelimitServer(rl, logging.Module(logger, "ratelimit"), cfg.AuthToken).WithLoadShedding(loadShedding))
 	if err != nil {
 		return fmt.Errorf("failed to add ratelimit service: %w", err)
 	}
@@ -309,13 +587,25 @@ func run(c *cli.Context) error {
 	<-cShutdown
 	logger.Info().Msg("shutting down")
 
-	err = connectSrv.Shutdown()
+	shutdownTimeout := 20 * time.Second
+	if cfg.ShutdownTimeout > 0 {
+		shutdownTimeout = time.Duration(cfg.ShutdownTimeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Drain both servers, even if one of them fails
+	var shutdownErrs []error
+	err = connectSrv.Shutdown(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to shutdown connect service: %w", err)
+		shutdownErrs = append(shutdownErrs, fmt.Errorf("failed to shutdown connect service: %w", err))
 	}
-	err = srv.Shutdown()
+	err = srv.Shutdown(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to shutdown service: %w", err)
+		shutdownErrs = append(shutdownErrs, fmt.Errorf("failed to shutdown service: %w", err))
+	}
+	if len(shutdownErrs) > 0 {
+		return errors.Join(shutdownErrs...)
 	}
 	err = clus.Shutdown()
 	if err != nil {
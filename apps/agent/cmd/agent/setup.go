@@ -12,26 +12,65 @@ import (
 
 func setupLogging(cfg config.Agent) (logging.Logger, error) {
 
-	logger := logging.New(nil)
-
-	// runId is unique per start of the agent, this is useful for differnetiating logs between
-	// deployments
-	// If the agent is restarted, the runId will change
-	logger = logger.With().Str("runId", uid.New("run")).Logger()
-
+	writers := []io.Writer{}
 	if cfg.Logging != nil && cfg.Logging.Axiom != nil {
 		ax, err := logging.NewAxiomWriter(logging.AxiomWriterConfig{
 			Token:   cfg.Logging.Axiom.Token,
 			Dataset: cfg.Logging.Axiom.Dataset,
 		})
 		if err != nil {
-			return logger, err
+			return logging.New(nil), err
+		}
+		writers = append(writers, ax)
+	}
+	if cfg.Logging != nil && cfg.Logging.Http != nil {
+		hw, err := logging.NewHttpWriter(logging.HttpWriterConfig{
+			Url:     cfg.Logging.Http.Url,
+			Headers: cfg.Logging.Http.Headers,
+		})
+		if err != nil {
+			return logging.New(nil), err
 		}
-		logger = logging.New(&logging.Config{
-			Writer: []io.Writer{ax},
+		writers = append(writers, hw)
+	}
+	if cfg.Logging != nil && cfg.Logging.File != nil {
+		fw, err := logging.NewFileWriter(logging.FileWriterConfig{
+			Path:       cfg.Logging.File.Path,
+			MaxSize:    int64(cfg.Logging.File.MaxSizeMb) * 1024 * 1024,
+			MaxAge:     time.Duration(cfg.Logging.File.MaxAge) * time.Second,
+			MaxBackups: cfg.Logging.File.MaxBackups,
+			Compress:   cfg.Logging.File.Compress,
 		})
+		if err != nil {
+			return logging.New(nil), err
+		}
+		writers = append(writers, fw)
+	}
+
+	keyPrefixes := []string{}
+	if cfg.Logging != nil {
+		keyPrefixes = cfg.Logging.KeyPrefixes
+	}
+
+	logger := logging.New(&logging.Config{
+		Writer:      writers,
+		KeyPrefixes: keyPrefixes,
+	})
+
+	// runId is unique per start of the agent, this is useful for differnetiating logs between
+	// deployments
+	// If the agent is restarted, the runId will change
+	logger = logger.With().Str("runId", uid.New("run")).Logger()
+
+	if cfg.Logging != nil && cfg.Logging.Axiom != nil {
 		logger.Info().Msg("Logging to axiom")
 	}
+	if cfg.Logging != nil && cfg.Logging.Http != nil {
+		logger.Info().Str("url", cfg.Logging.Http.Url).Msg("Logging to http endpoint")
+	}
+	if cfg.Logging != nil && cfg.Logging.File != nil {
+		logger.Info().Str("path", cfg.Logging.File.Path).Msg("Logging to file")
+	}
 	return logger, nil
 }
 
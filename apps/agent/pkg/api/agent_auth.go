@@ -5,42 +5,32 @@ import (
 	"net/http"
 	"strings"
 
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
 )
 
-func newBearerAuthMiddleware(secret string) routes.Middeware {
+func newBearerAuthMiddleware(secret string, sender routes.Sender) routes.Middeware {
 	secretB := []byte(secret)
 
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
 
 			authorizationHeader := r.Header.Get("Authorization")
 			if authorizationHeader == "" {
-				w.WriteHeader(401)
-				_, err := w.Write([]byte("Authorization header is required"))
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-				}
+				sender.Send(ctx, w, 401, apiErrors.Unauthorized(ctx, "Authorization header is required"))
 				return
 			}
 
 			token := strings.TrimPrefix(authorizationHeader, "Bearer ")
 			if token == "" {
-				w.WriteHeader(401)
-				_, err := w.Write([]byte("Bearer token is required"))
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-				}
+				sender.Send(ctx, w, 401, apiErrors.Unauthorized(ctx, "Bearer token is required"))
 				return
 
 			}
 
 			if subtle.ConstantTimeCompare([]byte(token), secretB) != 1 {
-				w.WriteHeader(401)
-				_, err := w.Write([]byte("Bearer token is invalid"))
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-				}
+				sender.Send(ctx, w, 401, apiErrors.Unauthorized(ctx, "Bearer token is invalid"))
 				return
 			}
 
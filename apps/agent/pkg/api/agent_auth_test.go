@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestBearerAuthRespondsWithProblem(t *testing.T) {
+	auth := newBearerAuthMiddleware("secret", routes.NewJsonSender(logging.NewNoopLogger()))
+	handler := withRequestId(auth(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/cache.flush", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, 401, rr.Code)
+	require.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var problem openapi.BaseError
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	require.Equal(t, "UNAUTHORIZED", problem.Code)
+	require.Equal(t, 401, problem.Status)
+	require.Equal(t, "Unauthorized", problem.Title)
+	require.Equal(t, rr.Header().Get(RequestIdHeader), problem.RequestId)
+	require.Equal(t, "https://unkey.com/docs/agent/errors/unauthorized", problem.Type)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, 204, rr.Code)
+}
@@ -1,11 +1,21 @@
 package ctxutil
 
-import "context"
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"go.opentelemetry.io/otel/trace"
+)
 
 type contextKey string
 
 const (
-	request_id contextKey = "request_id"
+	request_id   contextKey = "request_id"
+	workspace_id contextKey = "workspace_id"
+	key_id       contextKey = "key_id"
+	source_ip    contextKey = "source_ip"
 )
 
 // getValue returns the value for the given key from the context or its zero value if it doesn't exist.
@@ -25,3 +35,65 @@ func GetRequestId(ctx context.Context) string {
 func SetRequestId(ctx context.Context, requestId string) context.Context {
 	return context.WithValue(ctx, request_id, requestId)
 }
+
+// GetTraceId returns the id of the trace the request is part of, or an empty
+// string if it is not traced.
+func GetTraceId(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+func GetWorkspaceId(ctx context.Context) string {
+	return getValue[string](ctx, workspace_id)
+}
+
+func SetWorkspaceId(ctx context.Context, workspaceId string) context.Context {
+	return context.WithValue(ctx, workspace_id, workspaceId)
+}
+
+func GetKeyId(ctx context.Context) string {
+	return getValue[string](ctx, key_id)
+}
+
+func SetKeyId(ctx context.Context, keyId string) context.Context {
+	return context.WithValue(ctx, key_id, keyId)
+}
+
+// GetSourceIp returns the ip of the client, as far as it can be trusted. See
+// the source ip middleware of the api.
+func GetSourceIp(ctx context.Context) string {
+	return getValue[string](ctx, source_ip)
+}
+
+func SetSourceIp(ctx context.Context, sourceIp string) context.Context {
+	return context.WithValue(ctx, source_ip, sourceIp)
+}
+
+// Logger derives a logger that carries the request, trace, workspace and key
+// ids and the source ip of the context, so all lines logged while handling one
+// request can be correlated. Values that are not set are omitted.
+//
+// The key id is hashed, so it can't be looked up from the logs alone.
+func Logger(ctx context.Context, logger logging.Logger) logging.Logger {
+	c := logger.With()
+	if requestId := GetRequestId(ctx); requestId != "" {
+		c = c.Str("requestId", requestId)
+	}
+	if traceId := GetTraceId(ctx); traceId != "" {
+		c = c.Str("traceId", traceId)
+	}
+	if workspaceId := GetWorkspaceId(ctx); workspaceId != "" {
+		c = c.Str("workspaceId", workspaceId)
+	}
+	if sourceIp := GetSourceIp(ctx); sourceIp != "" {
+		c = c.Str("sourceIp", sourceIp)
+	}
+	if keyId := GetKeyId(ctx); keyId != "" {
+		h := sha256.Sum256([]byte(keyId))
+		c = c.Str("keyIdHash", hex.EncodeToString(h[:8]))
+	}
+	return c.Logger()
+}
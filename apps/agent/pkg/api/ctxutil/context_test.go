@@ -0,0 +1,31 @@
+package ctxutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	ctx := SetRequestId(context.Background(), "req_123")
+	ctx = SetWorkspaceId(ctx, "ws_123")
+	ctx = SetKeyId(ctx, "key_123")
+	ctx = SetSourceIp(ctx, "203.0.113.7")
+	logger := Logger(ctx, zerolog.New(buf))
+	logger.Info().Msg("hello")
+
+	e := map[string]string{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	require.Equal(t, "req_123", e["requestId"])
+	require.Equal(t, "ws_123", e["workspaceId"])
+	require.Equal(t, "203.0.113.7", e["sourceIp"])
+	require.Len(t, e["keyIdHash"], 16)
+	require.NotContains(t, buf.String(), "key_123")
+	require.NotContains(t, e, "traceId")
+}
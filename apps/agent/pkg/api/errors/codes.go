@@ -0,0 +1,73 @@
+package errors
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+// Code classifies an error, so clients can handle it without parsing the
+// detail. Clients match on codes, do not change existing ones.
+type Code string
+
+const (
+	CodeBadRequest          Code = "BAD_REQUEST"
+	CodeUnauthorized        Code = "UNAUTHORIZED"
+	CodeNotFound            Code = "NOT_FOUND"
+	CodeTooManyRequests     Code = "TOO_MANY_REQUESTS"
+	CodeInternalServerError Code = "INTERNAL_SERVER_ERROR"
+	CodeDeadlineExceeded    Code = "DEADLINE_EXCEEDED"
+)
+
+// DocsUrl links to the documentation of the code and is sent as the problem type.
+func (c Code) DocsUrl() string {
+	return "https://unkey.com/docs/agent/errors/" + strings.ToLower(strings.ReplaceAll(string(c), "_", "-"))
+}
+
+// New returns a problem to be sent to the client. The title is derived from
+// the status.
+func New(ctx context.Context, status int, code Code, detail string) openapi.BaseError {
+	return openapi.BaseError{
+		Code:      string(code),
+		Title:     http.StatusText(status),
+		Detail:    detail,
+		Instance:  instance(ctx),
+		Status:    status,
+		RequestId: ctxutil.GetRequestId(ctx),
+		TraceId:   traceId(ctx),
+		Type:      code.DocsUrl(),
+	}
+}
+
+func NotFound(ctx context.Context, detail string) openapi.BaseError {
+	return New(ctx, http.StatusNotFound, CodeNotFound, detail)
+}
+
+func Unauthorized(ctx context.Context, detail string) openapi.BaseError {
+	return New(ctx, http.StatusUnauthorized, CodeUnauthorized, detail)
+}
+
+func TooManyRequests(ctx context.Context, detail string) openapi.BaseError {
+	return New(ctx, http.StatusTooManyRequests, CodeTooManyRequests, detail)
+}
+
+func DeadlineExceeded(ctx context.Context, detail string) openapi.BaseError {
+	return New(ctx, http.StatusGatewayTimeout, CodeDeadlineExceeded, detail)
+}
+
+// instance identifies this occurrence of the problem by the request id, which
+// operators can look up in the logs.
+func instance(ctx context.Context) string {
+	return "urn:unkey:request:" + ctxutil.GetRequestId(ctx)
+}
+
+func traceId(ctx context.Context) *string {
+	id := ctxutil.GetTraceId(ctx)
+	if id == "" {
+		return nil
+	}
+	return &id
+}
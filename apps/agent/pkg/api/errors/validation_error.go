@@ -19,14 +19,26 @@ func HandleValidationError(ctx context.Context, err error) openapi.ValidationErr
 		}
 	}
 
+	return NewValidationError(ctx, "One or more fields failed validation", details)
+
+}
+
+// NewValidationError returns a problem for a request that did not pass
+// validation, with one detail per failed field.
+func NewValidationError(ctx context.Context, detail string, errors []openapi.ValidationErrorDetail) openapi.ValidationError {
+	if errors == nil {
+		errors = []openapi.ValidationErrorDetail{}
+	}
+
 	return openapi.ValidationError{
-		Title:     "Internal Server Error",
-		Detail:    "An internal server error occurred",
-		Errors:    details,
-		Instance:  "https://errors.unkey.com/todo",
+		Code:      string(CodeBadRequest),
+		Title:     http.StatusText(http.StatusBadRequest),
+		Detail:    detail,
+		Errors:    errors,
+		Instance:  instance(ctx),
 		Status:    http.StatusBadRequest,
 		RequestId: ctxutil.GetRequestId(ctx),
-		Type:      "TODO docs link",
+		TraceId:   traceId(ctx),
+		Type:      CodeBadRequest.DocsUrl(),
 	}
-
 }
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+// Responses smaller than this are sent as is, they would barely shrink and
+// fit into a single packet anyway.
+const compressionMinSize = 1400
+
+// Safe for concurrent use with EncodeAll
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil)
+})
+
+type responseWriterBuffer struct {
+	w          http.ResponseWriter
+	body       *bytes.Buffer
+	statusCode int
+}
+
+// Pass through
+func (w *responseWriterBuffer) Header() http.Header {
+	return w.w.Header()
+}
+
+// Capture
+func (w *responseWriterBuffer) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// Capture
+func (w *responseWriterBuffer) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+}
+
+// newCompressionMiddleware compresses responses of at least minSize bytes with
+// zstd or gzip, whichever the client prefers. The response is buffered, so
+// only use it for routes that send their body at once.
+func newCompressionMiddleware(minSize int, logger logging.Logger) routes.Middeware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" {
+				next(w, r)
+				return
+			}
+
+			wb := &responseWriterBuffer{w: w, body: &bytes.Buffer{}, statusCode: http.StatusOK}
+			next(wb, r)
+
+			body := wb.body.Bytes()
+			if wb.body.Len() >= minSize && w.Header().Get("Content-Encoding") == "" {
+				compressed, err := compress(encoding, body)
+				if err != nil {
+					logger.Error().Err(err).Str("encoding", encoding).Msg("failed to compress response")
+				} else {
+					body = compressed
+					w.Header().Set("Content-Encoding", encoding)
+					w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				}
+			}
+
+			w.WriteHeader(wb.statusCode)
+			_, err := w.Write(body)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to write response body")
+			}
+		}
+	}
+}
+
+func compress(encoding string, body []byte) ([]byte, error) {
+	switch encoding {
+	case "zstd":
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll(body, nil), nil
+	default:
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		_, err := gz.Write(body)
+		if err != nil {
+			return nil, err
+		}
+		err = gz.Close()
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// negotiateEncoding returns the supported encoding with the highest quality
+// in the Accept-Encoding header, preferring zstd on ties, or an empty string
+// if the response should not be compressed.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		qualities[name] = q
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, encoding := range []string{"zstd", "gzip"} {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
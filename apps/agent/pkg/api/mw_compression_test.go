@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	testCases := map[string]string{
+		"":                        "",
+		"identity":                "",
+		"gzip":                    "gzip",
+		"gzip, deflate, br, zstd": "zstd",
+		"zstd;q=0.5, gzip":        "gzip",
+		"*":                       "zstd",
+		"*, zstd;q=0":             "gzip",
+		"gzip;q=0, zstd;q=0":      "",
+		"GZIP;q=0.8":              "gzip",
+	}
+	for header, expected := range testCases {
+		require.Equal(t, expected, negotiateEncoding(header), header)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat(`{"id":"ws_123","keys":42}`, 100)
+
+	compress := newCompressionMiddleware(compressionMinSize, logging.NewNoopLogger())
+	handler := func(body string) http.HandlerFunc {
+		return compress(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			_, err := w.Write([]byte(body))
+			require.NoError(t, err)
+		})
+	}
+
+	call := func(h http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/cache.inspect", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rr := httptest.NewRecorder()
+		h(rr, req)
+		return rr
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		rr := call(handler(large), "gzip")
+		require.Equal(t, 201, rr.Code)
+		require.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+		require.Less(t, rr.Body.Len(), len(large))
+
+		gz, err := gzip.NewReader(rr.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		require.Equal(t, large, string(body))
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		rr := call(handler(large), "gzip, zstd")
+		require.Equal(t, "zstd", rr.Header().Get("Content-Encoding"))
+
+		dec, err := zstd.NewReader(bytes.NewReader(rr.Body.Bytes()))
+		require.NoError(t, err)
+		defer dec.Close()
+		body, err := io.ReadAll(dec)
+		require.NoError(t, err)
+		require.Equal(t, large, string(body))
+	})
+
+	t.Run("small responses are not compressed", func(t *testing.T) {
+		rr := call(handler(`{"id":"ws_123"}`), "gzip")
+		require.Equal(t, 201, rr.Code)
+		require.Empty(t, rr.Header().Get("Content-Encoding"))
+		require.Equal(t, `{"id":"ws_123"}`, rr.Body.String())
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		rr := call(handler(large), "")
+		require.Empty(t, rr.Header().Get("Content-Encoding"))
+		require.Equal(t, large, rr.Body.String())
+	})
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/loadshedding"
+)
+
+// newLoadSheddingMiddleware rejects requests with a 429 while the limiter is
+// at capacity. Without a limiter, all requests are admitted.
+func newLoadSheddingMiddleware(limiter *loadshedding.Limiter, sender routes.Sender) routes.Middeware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		if limiter == nil {
+			return next
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			release, ok := limiter.Acquire()
+			if !ok {
+				w.Header().Set("Retry-After", "1")
+				sender.Send(r.Context(), w, http.StatusTooManyRequests, apiErrors.TooManyRequests(r.Context(), "The agent is overloaded, please retry later"))
+				return
+			}
+			defer release()
+			next(w, r)
+		}
+	}
+}
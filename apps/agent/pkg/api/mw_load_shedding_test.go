@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/loadshedding"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestLoadShedding(t *testing.T) {
+	limiter := loadshedding.New("test", loadshedding.WithInitialLimit(1), loadshedding.WithMinLimit(1), loadshedding.WithMaxLimit(1))
+	shed := newLoadSheddingMiddleware(limiter, routes.NewJsonSender(logging.NewNoopLogger()))
+
+	call := func(h http.HandlerFunc) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodPost, "/ratelimit.v1.RatelimitService/Ratelimit", nil))
+		return rr
+	}
+
+	var shedResponse *httptest.ResponseRecorder
+	admitted := call(shed(func(w http.ResponseWriter, r *http.Request) {
+		// the only slot is taken while we are in here
+		shedResponse = call(shed(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("must not be admitted")
+		}))
+		w.WriteHeader(200)
+	}))
+	require.Equal(t, 200, admitted.Code)
+
+	require.Equal(t, 429, shedResponse.Code)
+	require.Equal(t, "1", shedResponse.Header().Get("Retry-After"))
+	var problem openapi.BaseError
+	require.NoError(t, json.Unmarshal(shedResponse.Body.Bytes(), &problem))
+	require.Equal(t, "TOO_MANY_REQUESTS", problem.Code)
+
+	// the slot is free again
+	require.Equal(t, 204, call(shed(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	})).Code)
+}
+
+func TestLoadSheddingWithoutLimiter(t *testing.T) {
+	shed := newLoadSheddingMiddleware(nil, routes.NewJsonSender(logging.NewNoopLogger()))
+	rr := httptest.NewRecorder()
+	shed(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	})(rr, httptest.NewRequest(http.MethodPost, "/ratelimit.v1.RatelimitService/Ratelimit", nil))
+	require.Equal(t, 204, rr.Code)
+}
@@ -29,6 +29,11 @@ func (w *responseWriterStatusInterceptor) WriteHeader(statusCode int) {
 	w.w.WriteHeader(statusCode)
 }
 
+// Lets http.ResponseController flush streamed responses
+func (w *responseWriterStatusInterceptor) Unwrap() http.ResponseWriter {
+	return w.w
+}
+
 func withMetrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		wi := &responseWriterStatusInterceptor{w: w}
@@ -37,12 +42,13 @@ func withMetrics(next http.Handler) http.Handler {
 		next.ServeHTTP(wi, r)
 		serviceLatency := time.Since(start)
 
+		path := prometheus.HTTPPathLabel.Value(r.URL.Path)
 		prometheus.HTTPRequests.With(map[string]string{
 			"method": r.Method,
-			"path":   r.URL.Path,
+			"path":   path,
 			"status": fmt.Sprintf("%d", wi.statusCode),
 		}).Inc()
 
-		prometheus.ServiceLatency.WithLabelValues(r.URL.Path).Observe(serviceLatency.Seconds())
+		prometheus.ServiceLatency.WithLabelValues(path).Observe(serviceLatency.Seconds())
 	})
 }
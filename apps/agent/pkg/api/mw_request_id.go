@@ -7,10 +7,22 @@ import (
 	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
 )
 
+const (
+	RequestIdHeader = "Unkey-Request-Id"
+	TraceIdHeader   = "Unkey-Trace-Id"
+)
+
 func withRequestId(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		ctx = ctxutil.SetRequestId(ctx, uid.New(uid.Request()))
+		requestId := uid.New(uid.Request())
+		ctx = ctxutil.SetRequestId(ctx, requestId)
+
+		// So callers can quote them in support requests
+		w.Header().Set(RequestIdHeader, requestId)
+		if traceId := ctxutil.GetTraceId(ctx); traceId != "" {
+			w.Header().Set(TraceIdHeader, traceId)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+)
+
+func TestRequestIdHeaders(t *testing.T) {
+	var requestId string
+	handler := withTracing(withRequestId(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId = ctxutil.GetRequestId(r.Context())
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/liveness", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.NotEmpty(t, requestId)
+	require.Equal(t, requestId, rr.Header().Get(RequestIdHeader))
+	require.Equal(t, "0af7651916cd43dd8448eb211c80319c", rr.Header().Get(TraceIdHeader))
+
+	// untraced requests only get a request id
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/liveness", nil))
+	require.NotEmpty(t, rr.Header().Get(RequestIdHeader))
+	require.Empty(t, rr.Header().Get(TraceIdHeader))
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+)
+
+// parseTrustedProxies accepts CIDR ranges as well as single addresses.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, len(proxies))
+	for i, p := range proxies {
+		if strings.Contains(p, "/") {
+			prefix, err := netip.ParsePrefix(p)
+			if err != nil {
+				return nil, fault.Wrap(err, fmsg.With("invalid trusted proxy "+p))
+			}
+			prefixes[i] = prefix.Masked()
+			continue
+		}
+		addr, err := netip.ParseAddr(p)
+		if err != nil {
+			return nil, fault.Wrap(err, fmsg.With("invalid trusted proxy "+p))
+		}
+		addr = addr.Unmap()
+		prefixes[i] = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return prefixes, nil
+}
+
+// withSourceIp stores the ip of the client in the request context.
+//
+// Every proxy appends the address it received the request from to the
+// Forwarded or X-Forwarded-For header, but clients can send any value they
+// like in front of that. So starting at the immediate peer, we walk the hops
+// from right to left only as long as the current one is a trusted proxy. The
+// first hop that isn't trusted is the client.
+func withSourceIp(next http.Handler, trustedProxies []netip.Prefix) http.Handler {
+	trusted := func(addr netip.Addr) bool {
+		for _, p := range trustedProxies {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sourceIp := r.RemoteAddr
+
+		addr, err := parseHop(r.RemoteAddr)
+		if err == nil {
+			hops := forwardedFor(r.Header)
+			for i := len(hops) - 1; i >= 0 && trusted(addr); i-- {
+				hop, err := parseHop(hops[i])
+				if err != nil {
+					// Obfuscated or unknown, we can't tell who sent it
+					break
+				}
+				addr = hop
+			}
+			sourceIp = addr.String()
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctxutil.SetSourceIp(r.Context(), sourceIp)))
+	})
+}
+
+// forwardedFor returns the hops of the Forwarded header, or of X-Forwarded-For
+// if there is none, in the order they were appended.
+func forwardedFor(header http.Header) []string {
+	hops := []string{}
+
+	if forwarded := header.Values("Forwarded"); len(forwarded) > 0 {
+		for _, element := range strings.Split(strings.Join(forwarded, ","), ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, strings.Trim(value, `"`))
+				}
+			}
+		}
+		return hops
+	}
+
+	for _, hop := range strings.Split(strings.Join(header.Values("X-Forwarded-For"), ","), ",") {
+		hop = strings.TrimSpace(hop)
+		if hop != "" {
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseHop accepts addresses with or without port, ipv6 addresses may be
+// enclosed in brackets.
+func parseHop(hop string) (netip.Addr, error) {
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		hop = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(hop, "[]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
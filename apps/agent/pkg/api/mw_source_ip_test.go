@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+)
+
+func TestSourceIp(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::1"})
+	require.NoError(t, err)
+
+	var sourceIp string
+	handler := withSourceIp(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sourceIp = ctxutil.GetSourceIp(r.Context())
+	}), trustedProxies)
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "no proxy",
+			remoteAddr: "203.0.113.7:4711",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer can not spoof",
+			remoteAddr: "203.0.113.7:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.2:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "client prepends a spoofed hop",
+			remoteAddr: "10.0.0.2:4711",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.0.0.3"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "forwarded takes precedence",
+			remoteAddr: "[2001:db8::1]:4711",
+			headers: map[string]string{
+				"Forwarded":       `for=1.1.1.1, for="[2001:db8:cafe::17]:4711";proto=https`,
+				"X-Forwarded-For": "198.51.100.1",
+			},
+			expected: "2001:db8:cafe::17",
+		},
+		{
+			name:       "obfuscated hop",
+			remoteAddr: "10.0.0.2:4711",
+			headers:    map[string]string{"Forwarded": "for=_hidden"},
+			expected:   "10.0.0.2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/liveness", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			require.Equal(t, tc.expected, sourceIp)
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsInvalid(t *testing.T) {
+	_, err := parseTrustedProxies([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+	_, err = parseTrustedProxies([]string{"load-balancer"})
+	require.Error(t, err)
+}
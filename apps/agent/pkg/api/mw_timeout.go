@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+// Route timeouts must stay below the WriteTimeout of the server, or the
+// connection is closed before we can tell the client what happened.
+const (
+	defaultRouteTimeout = 5 * time.Second
+	// analytics queries and flushing buffers take longer
+	slowRouteTimeout = 15 * time.Second
+)
+
+// timeoutWriter buffers the response until the handler returns. After the
+// deadline it rejects writes, as the client got its response already.
+type timeoutWriter struct {
+	mu         sync.Mutex
+	header     http.Header
+	body       bytes.Buffer
+	statusCode int
+	timedOut   bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.body.Write(b)
+}
+
+func (w *timeoutWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.statusCode != 0 {
+		return
+	}
+	w.statusCode = statusCode
+}
+
+// newTimeoutMiddleware cancels the context of a request after the timeout and
+// responds with DEADLINE_EXCEEDED, like http.TimeoutHandler does for plain
+// text. Handlers must pass the context on to their dependencies, so they
+// return soon after the deadline instead of pinning their goroutine.
+func newTimeoutMiddleware(timeout time.Duration, sender routes.Sender, logger logging.Logger) routes.Middeware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{header: http.Header{}}
+			done := make(chan struct{})
+			panics := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panics <- p
+					}
+				}()
+				next(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			deadlineExceeded := func() {
+				sender.Send(r.Context(), w, http.StatusGatewayTimeout, apiErrors.DeadlineExceeded(r.Context(),
+					fmt.Sprintf("The request did not complete within %s", timeout)))
+			}
+
+			select {
+			case p := <-panics:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				// The handler failed because it ran out of time
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) && tw.statusCode >= 500 {
+					deadlineExceeded()
+					return
+				}
+
+				for k, vv := range tw.header {
+					w.Header()[k] = vv
+				}
+				if tw.statusCode == 0 {
+					tw.statusCode = http.StatusOK
+				}
+				w.WriteHeader(tw.statusCode)
+				_, err := w.Write(tw.body.Bytes())
+				if err != nil {
+					logger.Error().Err(err).Msg("failed to write response body")
+				}
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				tw.mu.Unlock()
+
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					deadlineExceeded()
+				}
+				// Otherwise the client went away, there is nobody to respond to
+			}
+		}
+	}
+}
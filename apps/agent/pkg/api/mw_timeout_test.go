@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestTimeout(t *testing.T) {
+	logger := logging.NewNoopLogger()
+	sender := routes.NewJsonSender(logger)
+	timeout := newTimeoutMiddleware(50*time.Millisecond, sender, logger)
+
+	call := func(h http.HandlerFunc) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodPost, "/v1/cache.inspect", nil))
+		return rr
+	}
+
+	t.Run("fast handlers pass through", func(t *testing.T) {
+		rr := call(timeout(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Test", "1")
+			w.WriteHeader(201)
+			_, err := w.Write([]byte("ok"))
+			require.NoError(t, err)
+		}))
+		require.Equal(t, 201, rr.Code)
+		require.Equal(t, "1", rr.Header().Get("X-Test"))
+		require.Equal(t, "ok", rr.Body.String())
+	})
+
+	deadlineExceeded := func(t *testing.T, rr *httptest.ResponseRecorder) {
+		require.Equal(t, 504, rr.Code)
+		require.Equal(t, routes.ProblemContentType, rr.Header().Get("Content-Type"))
+		var problem openapi.BaseError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		require.Equal(t, string(apiErrors.CodeDeadlineExceeded), problem.Code)
+	}
+
+	t.Run("handlers that ignore the context", func(t *testing.T) {
+		written := make(chan error, 1)
+		rr := call(timeout(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+			_, err := w.Write([]byte("too late"))
+			written <- err
+		}))
+		deadlineExceeded(t, rr)
+		require.ErrorIs(t, <-written, http.ErrHandlerTimeout)
+	})
+
+	t.Run("handlers that fail because of the deadline", func(t *testing.T) {
+		rr := call(timeout(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			sender.Send(r.Context(), w, 500, apiErrors.HandleError(r.Context(), r.Context().Err()))
+		}))
+		deadlineExceeded(t, rr)
+	})
+}
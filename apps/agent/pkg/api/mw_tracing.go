@@ -8,7 +8,10 @@ import (
 
 func withTracing(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		ctx := tracing.Extract(r.Context(), r.Header)
+		if r.Header.Get(tracing.DebugHeader) != "" {
+			ctx = tracing.ForceSample(ctx)
+		}
 		ctx, span := tracing.Start(ctx, tracing.NewSpanName("api", r.URL.Path))
 		defer span.End()
 		r = r.WithContext(ctx)
@@ -2,10 +2,26 @@ package api
 
 import (
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes/healthz"
 	notFound "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/not_found"
 	openapi "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/openapi"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes/readyz"
+	v1AnalyticsGetApiStats "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getApiStats"
+	v1AnalyticsGetLatencyStats "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getLatencyStats"
+	v1AnalyticsGetMonthlyActiveKeys "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getMonthlyActiveKeys"
+	v1AnalyticsGetOwnerStats "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getOwnerStats"
+	v1AnalyticsGetWorkspaceStats "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getWorkspaceStats"
+	v1CacheEvict "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_cache_evict"
+	v1CacheFlush "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_cache_flush"
+	v1CacheInspect "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_cache_inspect"
+	v1CacheWarmup "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_cache_warmup"
+	v1EventsFlush "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_events_flush"
+	v1EventsListDeadLetters "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_events_listDeadLetters"
+	v1EventsRequeueDeadLetter "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_events_requeueDeadLetter"
 	v1Liveness "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_liveness"
+	v1LoggingSetLevel "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_logging_setLevel"
 	v1RatelimitCommitLease "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_ratelimit_commitLease"
+	v1RatelimitInspect "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_ratelimit_inspect"
 	v1RatelimitMultiRatelimit "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_ratelimit_multiRatelimit"
 	v1RatelimitRatelimit "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_ratelimit_ratelimit"
 	v1VaultDecrypt "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_vault_decrypt"
@@ -16,42 +32,114 @@ import (
 func (s *Server) RegisterRoutes() {
 	svc := routes.Services{
 		Logger:           s.logger,
+		Audit:            s.audit,
 		Metrics:          s.metrics,
 		Vault:            s.vault,
 		Ratelimit:        s.ratelimit,
+		RatelimitStats:   s.ratelimitStats,
 		OpenApiValidator: s.validator,
 		Sender:           routes.NewJsonSender(s.logger),
+		Caches:           s.caches,
+		Analytics:        s.analytics,
+		DeadLetters:      s.deadLetters,
+		ReadinessChecks:  s.readinessChecks,
+		Buffers:          s.buffers,
 	}
 
 	s.logger.Info().Interface("svc", svc).Msg("Registering routes")
 
-	staticBearerAuth := newBearerAuthMiddleware(s.authToken)
+	staticBearerAuth := newBearerAuthMiddleware(s.authToken, svc.Sender)
+	// for routes whose responses grow with the data they report on
+	compress := newCompressionMiddleware(compressionMinSize, s.logger)
+	timeout := newTimeoutMiddleware(defaultRouteTimeout, svc.Sender, s.logger)
+	slowTimeout := newTimeoutMiddleware(slowRouteTimeout, svc.Sender, s.logger)
+	shed := newLoadSheddingMiddleware(s.loadShedding, svc.Sender)
 
 	v1Liveness.New(svc).Register(s.mux)
-	openapi.New(svc).Register(s.mux)
+	healthz.New(svc).Register(s.mux)
+	readyz.New(svc).Register(s.mux)
+	openapi.New(svc).
+		WithMiddleware(compress).
+		Register(s.mux)
+
+	v1AnalyticsGetApiStats.New(svc).
+		WithMiddleware(slowTimeout, staticBearerAuth, compress).
+		Register(s.mux)
+
+	v1AnalyticsGetLatencyStats.New(svc).
+		WithMiddleware(slowTimeout, staticBearerAuth, compress).
+		Register(s.mux)
+
+	v1AnalyticsGetMonthlyActiveKeys.New(svc).
+		WithMiddleware(slowTimeout, staticBearerAuth, compress).
+		Register(s.mux)
+
+	v1AnalyticsGetOwnerStats.New(svc).
+		WithMiddleware(slowTimeout, staticBearerAuth, compress).
+		Register(s.mux)
+
+	v1AnalyticsGetWorkspaceStats.New(svc).
+		WithMiddleware(slowTimeout, staticBearerAuth, compress).
+		Register(s.mux)
+
+	v1CacheEvict.New(svc).
+		WithMiddleware(timeout, staticBearerAuth).
+		Register(s.mux)
+
+	v1CacheFlush.New(svc).
+		WithMiddleware(timeout, staticBearerAuth).
+		Register(s.mux)
+
+	v1CacheInspect.New(svc).
+		WithMiddleware(timeout, staticBearerAuth, compress).
+		Register(s.mux)
+
+	v1CacheWarmup.New(svc).
+		WithMiddleware(slowTimeout, staticBearerAuth).
+		Register(s.mux)
+
+	v1EventsFlush.New(svc).
+		WithMiddleware(slowTimeout, staticBearerAuth).
+		Register(s.mux)
+
+	v1EventsListDeadLetters.New(svc).
+		WithMiddleware(timeout, staticBearerAuth, compress).
+		Register(s.mux)
+
+	v1EventsRequeueDeadLetter.New(svc).
+		WithMiddleware(timeout, staticBearerAuth).
+		Register(s.mux)
+
+	v1LoggingSetLevel.New(svc).
+		WithMiddleware(timeout, staticBearerAuth).
+		Register(s.mux)
 
 	v1RatelimitCommitLease.New(svc).
-		WithMiddleware(staticBearerAuth).
+		WithMiddleware(timeout, staticBearerAuth).
+		Register(s.mux)
+
+	v1RatelimitInspect.New(svc).
+		WithMiddleware(timeout, staticBearerAuth, compress).
 		Register(s.mux)
 
 	v1RatelimitMultiRatelimit.New(svc).
-		WithMiddleware(staticBearerAuth).
+		WithMiddleware(timeout, staticBearerAuth, shed).
 		Register(s.mux)
 
 	v1RatelimitRatelimit.New(svc).
-		WithMiddleware(staticBearerAuth).
+		WithMiddleware(timeout, staticBearerAuth, shed).
 		Register(s.mux)
 
 	v1VaultDecrypt.New(svc).
-		WithMiddleware(staticBearerAuth).
+		WithMiddleware(timeout, staticBearerAuth).
 		Register(s.mux)
 
 	v1VaultEncrypt.New(svc).
-		WithMiddleware(staticBearerAuth).
+		WithMiddleware(timeout, staticBearerAuth).
 		Register(s.mux)
 
 	v1VaultEncryptBulk.New(svc).
-		WithMiddleware(staticBearerAuth).
+		WithMiddleware(timeout, staticBearerAuth).
 		Register(s.mux)
 
 	notFound.New(svc).Register(s.mux)
@@ -0,0 +1,20 @@
+package healthz
+
+import (
+	"net/http"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+// New reports that the process is alive, without checking any dependencies.
+// Use readyz to decide whether the agent should receive traffic.
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("GET", "/healthz",
+		func(w http.ResponseWriter, r *http.Request) {
+			svc.Sender.Send(r.Context(), w, 200, openapi.V1LivenessResponseBody{
+				Message: "OK",
+			})
+		},
+	)
+}
@@ -0,0 +1,19 @@
+package healthz_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes/healthz"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestHealthz(t *testing.T) {
+
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(healthz.New)
+	res := testutil.CallRoute[any, openapi.V1LivenessResponseBody](t, route, nil, nil)
+
+	require.Equal(t, 200, res.Status)
+}
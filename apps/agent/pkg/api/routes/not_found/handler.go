@@ -3,9 +3,8 @@ package notFound
 import (
 	"net/http"
 
-	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
-	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
 )
 
 // This is a hack, because / matches everything, so we need to make sure this is the last route
@@ -13,14 +12,7 @@ func New(svc routes.Services) *routes.Route {
 	return routes.NewRoute("", "/",
 		func(w http.ResponseWriter, r *http.Request) {
 
-			svc.Sender.Send(r.Context(), w, 200, openapi.BaseError{
-				Title:     "Not Found",
-				Detail:    "This route does not exist",
-				Instance:  "https://errors.unkey.com/todo",
-				Status:    http.StatusNotFound,
-				RequestId: ctxutil.GetRequestId(r.Context()),
-				Type:      "TODO docs link",
-			})
+			svc.Sender.Send(r.Context(), w, 404, apiErrors.NotFound(r.Context(), "This route does not exist"))
 		},
 	)
 }
@@ -12,8 +12,8 @@ func New(svc routes.Services) *routes.Route {
 	return routes.NewRoute("GET", "/openapi.json",
 		func(w http.ResponseWriter, r *http.Request) {
 
-			w.WriteHeader(200)
 			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(200)
 			_, err := w.Write(openapi.Spec)
 			if err != nil {
 				http.Error(w, "failed to write response", http.StatusInternalServerError)
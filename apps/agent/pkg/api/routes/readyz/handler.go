@@ -0,0 +1,66 @@
+package readyz
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+// A single slow dependency must not hold up the probe, kubernetes gives up
+// after 1s by default.
+const checkTimeout = 800 * time.Millisecond
+
+// New runs all readiness checks concurrently and responds with 503 if any of
+// them fails, so the agent is taken out of rotation until it recovers.
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("GET", "/readyz",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+			defer cancel()
+
+			res := openapi.ReadyzResponseBody{
+				Status: "ok",
+				Checks: make(map[string]openapi.ReadinessCheck, len(svc.ReadinessChecks)),
+			}
+
+			mu := sync.Mutex{}
+			wg := sync.WaitGroup{}
+			for name, check := range svc.ReadinessChecks {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					start := time.Now()
+					err := check(ctx)
+					c := openapi.ReadinessCheck{
+						Status:  "ok",
+						Latency: time.Since(start).Milliseconds(),
+					}
+					if err != nil {
+						c.Status = "failing"
+						msg := err.Error()
+						c.Error = &msg
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					res.Checks[name] = c
+					if err != nil {
+						res.Status = "unavailable"
+					}
+				}()
+			}
+			wg.Wait()
+
+			status := http.StatusOK
+			if res.Status != "ok" {
+				svc.Logger.Warn().Interface("checks", res.Checks).Msg("not ready")
+				status = http.StatusServiceUnavailable
+			}
+			svc.Sender.Send(r.Context(), w, status, res)
+		},
+	)
+}
@@ -0,0 +1,40 @@
+package readyz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes/readyz"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestReady(t *testing.T) {
+
+	h := testutil.NewHarness(t)
+	h.RegisterReadinessCheck("clickhouse", func(ctx context.Context) error { return nil })
+	route := h.SetupRoute(readyz.New)
+	res := testutil.CallRoute[any, openapi.ReadyzResponseBody](t, route, nil, nil)
+
+	require.Equal(t, 200, res.Status)
+	require.Equal(t, "ok", res.Body.Status)
+	require.Equal(t, "ok", res.Body.Checks["clickhouse"].Status)
+}
+
+func TestNotReady(t *testing.T) {
+
+	h := testutil.NewHarness(t)
+	h.RegisterReadinessCheck("clickhouse", func(ctx context.Context) error { return nil })
+	h.RegisterReadinessCheck("cluster", func(ctx context.Context) error { return errors.New("no members") })
+	route := h.SetupRoute(readyz.New)
+	res := testutil.CallRoute[any, openapi.ReadyzResponseBody](t, route, nil, nil)
+
+	require.Equal(t, 503, res.Status)
+	require.Equal(t, "unavailable", res.Body.Status)
+	require.Equal(t, "ok", res.Body.Checks["clickhouse"].Status)
+	require.Equal(t, "failing", res.Body.Checks["cluster"].Status)
+	require.NotNil(t, res.Body.Checks["cluster"].Error)
+	require.Equal(t, "no members", *res.Body.Checks["cluster"].Error)
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
 )
@@ -17,6 +19,9 @@ type Sender interface {
 	Send(ctx context.Context, w http.ResponseWriter, status int, body any)
 }
 
+// ProblemContentType is sent with error responses, see RFC 7807.
+const ProblemContentType = "application/problem+json"
+
 type JsonSender struct {
 	logger logging.Logger
 }
@@ -26,42 +31,38 @@ func NewJsonSender(logger logging.Logger) Sender {
 }
 
 // Send returns a JSON response with the given status code and body.
+// Errors are sent as application/problem+json.
 // If marshalling fails, it will return a 500 response with the error message.
 func (r *JsonSender) Send(ctx context.Context, w http.ResponseWriter, status int, body any) {
 	if body == nil {
+		w.WriteHeader(status)
 		return
 	}
 
 	b, err := json.Marshal(body)
 	if err != nil {
 		r.logger.Error().Err(err).Interface("body", body).Msg("failed to marshal response body")
-		w.WriteHeader(http.StatusInternalServerError)
-
-		error := openapi.BaseError{
-			Title:     "Internal Server Error",
-			Detail:    "failed to marshal response body",
-			Instance:  "https://errors.unkey.com/todo",
-			Status:    http.StatusInternalServerError,
-			RequestId: ctxutil.GetRequestId(ctx),
-			Type:      "TODO docs link",
-		}
 
-		b, err = json.Marshal(error)
+		b, err = json.Marshal(apiErrors.HandleError(ctx, fault.Wrap(err, fmsg.With("failed to marshal response body"))))
 		if err != nil {
-			_, err = w.Write([]byte("failed to marshal response body"))
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+			http.Error(w, "failed to marshal response body", http.StatusInternalServerError)
 			return
 		}
+		w.Header().Set("Content-Type", ProblemContentType)
+		w.WriteHeader(http.StatusInternalServerError)
 		_, err = w.Write(b)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			r.logger.Error().Err(err).Msg("failed to write response body")
 		}
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	contentType := "application/json"
+	switch body.(type) {
+	case openapi.BaseError, openapi.ValidationError:
+		contentType = ProblemContentType
+	}
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 	_, err = w.Write(b)
 	if err != nil {
@@ -1,7 +1,12 @@
 package routes
 
 import (
+	"context"
+
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/validation"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
 	"github.com/unkeyed/unkey/apps/agent/services/ratelimit"
@@ -9,10 +14,28 @@ import (
 )
 
 type Services struct {
-	Logger           logging.Logger
+	Logger logging.Logger
+	// Security relevant actions, separate from operational logs
+	Audit            logging.Logger
 	Metrics          metrics.Metrics
 	Vault            *vault.Service
 	Ratelimit        ratelimit.Service
+	RatelimitStats   ratelimit.Inspector
 	OpenApiValidator validation.OpenAPIValidator
 	Sender           Sender
+	// All caches that can be inspected, by their resource name
+	Caches    map[string]cache.Inspector
+	Analytics clickhouse.Querier
+	// Dead letter queues that can be inspected, by name
+	DeadLetters map[string]events.DeadLetterInspector
+	// Dependencies that must be healthy before the agent receives traffic, by name
+	ReadinessChecks map[string]func(ctx context.Context) error
+	// Flushed in order by the events.flush route
+	Buffers []Buffer
+}
+
+// Buffer holds events in memory until they are flushed to their destination.
+type Buffer struct {
+	Name  string
+	Flush func(ctx context.Context) error
 }
@@ -0,0 +1,68 @@
+package v1AnalyticsGetApiStats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1AnalyticsGetApiStats "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getApiStats"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+type fakeQuerier struct {
+	clickhouse.Querier
+	req   clickhouse.ApiStatsRequest
+	stats clickhouse.UsageStats
+}
+
+func (f *fakeQuerier) GetApiStats(ctx context.Context, req clickhouse.ApiStatsRequest) (clickhouse.UsageStats, error) {
+	f.req = req
+	return f.stats, nil
+}
+
+func TestGetApiStats(t *testing.T) {
+	h := testutil.NewHarness(t)
+	q := &fakeQuerier{stats: clickhouse.UsageStats{
+		Verifications: 12,
+		ActiveKeys:    2,
+		Outcomes:      map[string]uint64{"VALID": 10, "RATE_LIMITED": 2},
+	}}
+	h.SetAnalytics(q)
+
+	route := h.SetupRoute(v1AnalyticsGetApiStats.New)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	resp := testutil.CallRoute[openapi.V1AnalyticsGetApiStatsRequestBody, openapi.V1AnalyticsGetApiStatsResponseBody](t, route, nil, openapi.V1AnalyticsGetApiStatsRequestBody{
+		WorkspaceId: "ws_1",
+		KeySpaceId:  "ks_1",
+		Start:       start.UnixMilli(),
+		End:         end.UnixMilli(),
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, int64(12), resp.Body.Verifications)
+	require.Equal(t, int64(2), resp.Body.ActiveKeys)
+	require.Equal(t, map[string]int64{"VALID": 10, "RATE_LIMITED": 2}, resp.Body.Outcomes)
+
+	require.Equal(t, "ws_1", q.req.WorkspaceID)
+	require.Equal(t, "ks_1", q.req.KeySpaceID)
+	require.True(t, start.Equal(q.req.Start))
+	require.True(t, end.Equal(q.req.End))
+}
+
+func TestGetApiStatsInvalidRange(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1AnalyticsGetApiStats.New)
+
+	now := time.Now().UnixMilli()
+	resp := testutil.CallRoute[openapi.V1AnalyticsGetApiStatsRequestBody, openapi.ValidationError](t, route, nil, openapi.V1AnalyticsGetApiStatsRequestBody{
+		WorkspaceId: "ws_1",
+		KeySpaceId:  "ks_1",
+		Start:       now,
+		End:         now,
+	})
+	require.Equal(t, 400, resp.Status)
+}
@@ -0,0 +1,51 @@
+package v1AnalyticsGetLatencyStats
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/analytics.getLatencyStats",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1AnalyticsGetLatencyStatsRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			if req.Start >= req.End {
+				svc.Sender.Send(ctx, w, 400, errors.HandleValidationError(ctx, fault.New("invalid range",
+					fmsg.WithDesc("invalid_range", "start must be before end"),
+				)))
+				return
+			}
+
+			stats, err := svc.Analytics.GetLatencyStats(ctx, clickhouse.LatencyStatsRequest{
+				WorkspaceID: req.WorkspaceId,
+				KeySpaceID:  req.KeySpaceId,
+				Start:       time.UnixMilli(req.Start),
+				End:         time.UnixMilli(req.End),
+			})
+			if err != nil {
+				svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, err))
+				return
+			}
+
+			svc.Sender.Send(ctx, w, 200, openapi.V1AnalyticsGetLatencyStatsResponseBody{
+				P50: stats.P50,
+				P95: stats.P95,
+				P99: stats.P99,
+			})
+		})
+}
@@ -0,0 +1,50 @@
+package v1AnalyticsGetLatencyStats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1AnalyticsGetLatencyStats "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getLatencyStats"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+type fakeQuerier struct {
+	clickhouse.Querier
+	req   clickhouse.LatencyStatsRequest
+	stats clickhouse.LatencyStats
+}
+
+func (f *fakeQuerier) GetLatencyStats(ctx context.Context, req clickhouse.LatencyStatsRequest) (clickhouse.LatencyStats, error) {
+	f.req = req
+	return f.stats, nil
+}
+
+func TestGetLatencyStats(t *testing.T) {
+	h := testutil.NewHarness(t)
+	q := &fakeQuerier{stats: clickhouse.LatencyStats{P50: 1.5, P95: 12, P99: 40.25}}
+	h.SetAnalytics(q)
+
+	route := h.SetupRoute(v1AnalyticsGetLatencyStats.New)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	resp := testutil.CallRoute[openapi.V1AnalyticsGetLatencyStatsRequestBody, openapi.V1AnalyticsGetLatencyStatsResponseBody](t, route, nil, openapi.V1AnalyticsGetLatencyStatsRequestBody{
+		WorkspaceId: "ws_1",
+		KeySpaceId:  "ks_1",
+		Start:       start.UnixMilli(),
+		End:         end.UnixMilli(),
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, 1.5, resp.Body.P50)
+	require.Equal(t, float64(12), resp.Body.P95)
+	require.Equal(t, 40.25, resp.Body.P99)
+
+	require.Equal(t, "ws_1", q.req.WorkspaceID)
+	require.Equal(t, "ks_1", q.req.KeySpaceID)
+	require.True(t, start.Equal(q.req.Start))
+	require.True(t, end.Equal(q.req.End))
+}
@@ -0,0 +1,60 @@
+package v1AnalyticsGetMonthlyActiveKeys
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+const monthLayout = "2006-01"
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/analytics.getMonthlyActiveKeys",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1AnalyticsGetMonthlyActiveKeysRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			month, err := time.Parse(monthLayout, req.Month)
+			if err != nil {
+				svc.Sender.Send(ctx, w, 400, errors.HandleValidationError(ctx, fault.Wrap(err,
+					fmsg.WithDesc("invalid_month", "month must be a valid calendar month formatted as YYYY-MM"),
+				)))
+				return
+			}
+
+			query := clickhouse.MonthlyActiveKeysRequest{Month: month}
+			if req.WorkspaceId != nil {
+				query.WorkspaceID = *req.WorkspaceId
+			}
+			rows, err := svc.Analytics.GetMonthlyActiveKeys(ctx, query)
+			if err != nil {
+				svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, err))
+				return
+			}
+
+			res := openapi.V1AnalyticsGetMonthlyActiveKeysResponseBody{
+				Month:      month.Format(monthLayout),
+				Workspaces: make([]openapi.WorkspaceActiveKeys, len(rows)),
+			}
+			for i, row := range rows {
+				res.Workspaces[i] = openapi.WorkspaceActiveKeys{
+					WorkspaceId: row.WorkspaceID,
+					ActiveKeys:  int64(row.ActiveKeys),
+				}
+			}
+
+			svc.Sender.Send(ctx, w, 200, res)
+		})
+}
@@ -0,0 +1,56 @@
+package v1AnalyticsGetMonthlyActiveKeys_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1AnalyticsGetMonthlyActiveKeys "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getMonthlyActiveKeys"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+)
+
+type fakeQuerier struct {
+	clickhouse.Querier
+	req  clickhouse.MonthlyActiveKeysRequest
+	rows []clickhouse.MonthlyActiveKeys
+}
+
+func (f *fakeQuerier) GetMonthlyActiveKeys(ctx context.Context, req clickhouse.MonthlyActiveKeysRequest) ([]clickhouse.MonthlyActiveKeys, error) {
+	f.req = req
+	return f.rows, nil
+}
+
+func TestGetMonthlyActiveKeys(t *testing.T) {
+	h := testutil.NewHarness(t)
+	q := &fakeQuerier{rows: []clickhouse.MonthlyActiveKeys{
+		{WorkspaceID: "ws_1", ActiveKeys: 3},
+	}}
+	h.SetAnalytics(q)
+
+	route := h.SetupRoute(v1AnalyticsGetMonthlyActiveKeys.New)
+
+	resp := testutil.CallRoute[openapi.V1AnalyticsGetMonthlyActiveKeysRequestBody, openapi.V1AnalyticsGetMonthlyActiveKeysResponseBody](t, route, nil, openapi.V1AnalyticsGetMonthlyActiveKeysRequestBody{
+		Month:       "2024-02",
+		WorkspaceId: util.Pointer("ws_1"),
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, "2024-02", resp.Body.Month)
+	require.Equal(t, []openapi.WorkspaceActiveKeys{{WorkspaceId: "ws_1", ActiveKeys: 3}}, resp.Body.Workspaces)
+
+	require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), q.req.Month)
+	require.Equal(t, "ws_1", q.req.WorkspaceID)
+}
+
+func TestGetMonthlyActiveKeysInvalidMonth(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1AnalyticsGetMonthlyActiveKeys.New)
+
+	resp := testutil.CallRoute[openapi.V1AnalyticsGetMonthlyActiveKeysRequestBody, openapi.ValidationError](t, route, nil, openapi.V1AnalyticsGetMonthlyActiveKeysRequestBody{
+		Month: "2024-13",
+	})
+	require.Equal(t, 400, resp.Status)
+}
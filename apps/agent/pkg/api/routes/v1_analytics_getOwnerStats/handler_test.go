@@ -0,0 +1,68 @@
+package v1AnalyticsGetOwnerStats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1AnalyticsGetOwnerStats "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getOwnerStats"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+type fakeQuerier struct {
+	clickhouse.Querier
+	req   clickhouse.OwnerStatsRequest
+	stats clickhouse.UsageStats
+}
+
+func (f *fakeQuerier) GetOwnerStats(ctx context.Context, req clickhouse.OwnerStatsRequest) (clickhouse.UsageStats, error) {
+	f.req = req
+	return f.stats, nil
+}
+
+func TestGetOwnerStats(t *testing.T) {
+	h := testutil.NewHarness(t)
+	q := &fakeQuerier{stats: clickhouse.UsageStats{
+		Verifications: 12,
+		ActiveKeys:    2,
+		Outcomes:      map[string]uint64{"VALID": 10, "RATE_LIMITED": 2},
+	}}
+	h.SetAnalytics(q)
+
+	route := h.SetupRoute(v1AnalyticsGetOwnerStats.New)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	resp := testutil.CallRoute[openapi.V1AnalyticsGetOwnerStatsRequestBody, openapi.V1AnalyticsGetOwnerStatsResponseBody](t, route, nil, openapi.V1AnalyticsGetOwnerStatsRequestBody{
+		WorkspaceId: "ws_1",
+		OwnerId:     "owner_1",
+		Start:       start.UnixMilli(),
+		End:         end.UnixMilli(),
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, int64(12), resp.Body.Verifications)
+	require.Equal(t, int64(2), resp.Body.ActiveKeys)
+	require.Equal(t, map[string]int64{"VALID": 10, "RATE_LIMITED": 2}, resp.Body.Outcomes)
+
+	require.Equal(t, "ws_1", q.req.WorkspaceID)
+	require.Equal(t, "owner_1", q.req.OwnerID)
+	require.True(t, start.Equal(q.req.Start))
+	require.True(t, end.Equal(q.req.End))
+}
+
+func TestGetOwnerStatsInvalidRange(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1AnalyticsGetOwnerStats.New)
+
+	now := time.Now().UnixMilli()
+	resp := testutil.CallRoute[openapi.V1AnalyticsGetOwnerStatsRequestBody, openapi.ValidationError](t, route, nil, openapi.V1AnalyticsGetOwnerStatsRequestBody{
+		WorkspaceId: "ws_1",
+		OwnerId:     "owner_1",
+		Start:       now,
+		End:         now,
+	})
+	require.Equal(t, 400, resp.Status)
+}
@@ -0,0 +1,55 @@
+package v1AnalyticsGetWorkspaceStats
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/analytics.getWorkspaceStats",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1AnalyticsGetWorkspaceStatsRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			if req.Start >= req.End {
+				svc.Sender.Send(ctx, w, 400, errors.HandleValidationError(ctx, fault.New("invalid range",
+					fmsg.WithDesc("invalid_range", "start must be before end"),
+				)))
+				return
+			}
+
+			stats, err := svc.Analytics.GetWorkspaceStats(ctx, clickhouse.WorkspaceStatsRequest{
+				WorkspaceID: req.WorkspaceId,
+				Start:       time.UnixMilli(req.Start),
+				End:         time.UnixMilli(req.End),
+			})
+			if err != nil {
+				svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, err))
+				return
+			}
+
+			res := openapi.V1AnalyticsGetWorkspaceStatsResponseBody{
+				Verifications: int64(stats.Verifications),
+				ActiveKeys:    int64(stats.ActiveKeys),
+				Outcomes:      make(map[string]int64, len(stats.Outcomes)),
+			}
+			for outcome, count := range stats.Outcomes {
+				res.Outcomes[outcome] = int64(count)
+			}
+
+			svc.Sender.Send(ctx, w, 200, res)
+		})
+}
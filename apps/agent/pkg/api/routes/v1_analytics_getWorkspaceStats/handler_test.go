@@ -0,0 +1,52 @@
+package v1AnalyticsGetWorkspaceStats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1AnalyticsGetWorkspaceStats "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_analytics_getWorkspaceStats"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+type fakeQuerier struct {
+	clickhouse.Querier
+	req   clickhouse.WorkspaceStatsRequest
+	stats clickhouse.UsageStats
+}
+
+func (f *fakeQuerier) GetWorkspaceStats(ctx context.Context, req clickhouse.WorkspaceStatsRequest) (clickhouse.UsageStats, error) {
+	f.req = req
+	return f.stats, nil
+}
+
+func TestGetWorkspaceStats(t *testing.T) {
+	h := testutil.NewHarness(t)
+	q := &fakeQuerier{stats: clickhouse.UsageStats{
+		Verifications: 30,
+		ActiveKeys:    5,
+		Outcomes:      map[string]uint64{"VALID": 29, "USAGE_EXCEEDED": 1},
+	}}
+	h.SetAnalytics(q)
+
+	route := h.SetupRoute(v1AnalyticsGetWorkspaceStats.New)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	resp := testutil.CallRoute[openapi.V1AnalyticsGetWorkspaceStatsRequestBody, openapi.V1AnalyticsGetWorkspaceStatsResponseBody](t, route, nil, openapi.V1AnalyticsGetWorkspaceStatsRequestBody{
+		WorkspaceId: "ws_1",
+		Start:       start.UnixMilli(),
+		End:         end.UnixMilli(),
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, int64(30), resp.Body.Verifications)
+	require.Equal(t, int64(5), resp.Body.ActiveKeys)
+	require.Equal(t, map[string]int64{"VALID": 29, "USAGE_EXCEEDED": 1}, resp.Body.Outcomes)
+
+	require.Equal(t, "ws_1", q.req.WorkspaceID)
+	require.True(t, start.Equal(q.req.Start))
+	require.True(t, end.Equal(q.req.End))
+}
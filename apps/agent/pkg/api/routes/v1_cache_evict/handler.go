@@ -0,0 +1,44 @@
+package v1CacheEvict
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/cache.evict",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1CacheEvictRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			c, ok := svc.Caches[req.Resource]
+			if !ok {
+				svc.Sender.Send(ctx, w, 404, apiErrors.NotFound(ctx, fmt.Sprintf("cache %s does not exist", req.Resource)))
+				return
+			}
+
+			deleted := req.Deleted != nil && *req.Deleted
+			if deleted {
+				for _, key := range req.Keys {
+					c.Tombstone(ctx, key)
+				}
+			} else {
+				c.Remove(ctx, req.Keys...)
+			}
+			logger := ctxutil.Logger(ctx, svc.Audit)
+			logger.Info().Str("resource", req.Resource).Int("keys", len(req.Keys)).Bool("deleted", deleted).Msg("evicted keys from cache")
+
+			svc.Sender.Send(ctx, w, 204, nil)
+		})
+}
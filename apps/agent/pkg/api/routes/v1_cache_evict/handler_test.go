@@ -0,0 +1,87 @@
+package v1CacheEvict_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1CacheEvict "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_cache_evict"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+)
+
+func TestEvict(t *testing.T) {
+	h := testutil.NewHarness(t)
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize:  100,
+		Fresh:    time.Minute,
+		Stale:    time.Minute,
+		Logger:   logging.NewNoopLogger(),
+		Resource: "test",
+	})
+	require.NoError(t, err)
+	h.RegisterCache("test", c)
+
+	ctx := context.Background()
+	c.Set(ctx, "a", "value")
+
+	route := h.SetupRoute(v1CacheEvict.New)
+	resp := testutil.CallRoute[openapi.V1CacheEvictRequestBody, any](t, route, nil, openapi.V1CacheEvictRequestBody{
+		Resource: "test",
+		Keys:     []string{"a"},
+	})
+	require.Equal(t, 204, resp.Status)
+
+	_, hit := c.Get(ctx, "a")
+	require.Equal(t, cache.Miss, hit)
+
+	c.Set(ctx, "a", "value")
+	_, hit = c.Get(ctx, "a")
+	require.Equal(t, cache.Hit, hit)
+}
+
+func TestEvictDeletedKeysLeavesTombstones(t *testing.T) {
+	h := testutil.NewHarness(t)
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize:  100,
+		Fresh:    time.Minute,
+		Stale:    time.Minute,
+		Logger:   logging.NewNoopLogger(),
+		Resource: "test",
+	})
+	require.NoError(t, err)
+	h.RegisterCache("test", c)
+
+	ctx := context.Background()
+	c.Set(ctx, "a", "value")
+
+	route := h.SetupRoute(v1CacheEvict.New)
+	resp := testutil.CallRoute[openapi.V1CacheEvictRequestBody, any](t, route, nil, openapi.V1CacheEvictRequestBody{
+		Resource: "test",
+		Keys:     []string{"a"},
+		Deleted:  util.Pointer(true),
+	})
+	require.Equal(t, 204, resp.Status)
+
+	// a load that started before the deletion must not write the value back
+	c.Set(ctx, "a", "value")
+	_, hit := c.Get(ctx, "a")
+	require.Equal(t, cache.Null, hit)
+}
+
+func TestEvictUnknownCache(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1CacheEvict.New)
+
+	resp := testutil.CallRoute[openapi.V1CacheEvictRequestBody, openapi.BaseError](t, route, nil, openapi.V1CacheEvictRequestBody{
+		Resource: "does_not_exist",
+		Keys:     []string{"a"},
+	})
+	require.Equal(t, 404, resp.Status)
+}
@@ -0,0 +1,37 @@
+package v1CacheFlush
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/cache.flush",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1CacheFlushRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			c, ok := svc.Caches[req.Resource]
+			if !ok {
+				svc.Sender.Send(ctx, w, 404, apiErrors.NotFound(ctx, fmt.Sprintf("cache %s does not exist", req.Resource)))
+				return
+			}
+
+			c.Clear(ctx)
+			logger := ctxutil.Logger(ctx, svc.Audit)
+			logger.Info().Str("resource", req.Resource).Msg("flushed cache")
+
+			svc.Sender.Send(ctx, w, 204, nil)
+		})
+}
@@ -0,0 +1,54 @@
+package v1CacheFlush_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1CacheFlush "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_cache_flush"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestFlush(t *testing.T) {
+	h := testutil.NewHarness(t)
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize:  100,
+		Fresh:    time.Minute,
+		Stale:    time.Minute,
+		Logger:   logging.NewNoopLogger(),
+		Resource: "test",
+	})
+	require.NoError(t, err)
+	h.RegisterCache("test", c)
+
+	ctx := context.Background()
+	c.Set(ctx, "a", "value")
+	c.Set(ctx, "b", "value")
+
+	route := h.SetupRoute(v1CacheFlush.New)
+
+	resp := testutil.CallRoute[openapi.V1CacheFlushRequestBody, any](t, route, nil, openapi.V1CacheFlushRequestBody{
+		Resource: "test",
+	})
+	require.Equal(t, 204, resp.Status)
+
+	_, hit := c.Get(ctx, "a")
+	require.Equal(t, cache.Miss, hit)
+	_, hit = c.Get(ctx, "b")
+	require.Equal(t, cache.Miss, hit)
+}
+
+func TestFlushUnknownCache(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1CacheFlush.New)
+
+	resp := testutil.CallRoute[openapi.V1CacheFlushRequestBody, openapi.BaseError](t, route, nil, openapi.V1CacheFlushRequestBody{
+		Resource: "does_not_exist",
+	})
+	require.Equal(t, 404, resp.Status)
+}
@@ -0,0 +1,55 @@
+package v1CacheInspect
+
+import (
+	"fmt"
+	"net/http"
+
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/cache.inspect",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1CacheInspectRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			c, ok := svc.Caches[req.Resource]
+			if !ok {
+				svc.Sender.Send(ctx, w, 404, apiErrors.NotFound(ctx, fmt.Sprintf("cache %s does not exist", req.Resource)))
+				return
+			}
+
+			topN := int64(10)
+			if req.TopN != nil {
+				topN = *req.TopN
+			}
+			summary := c.Inspect(ctx, int(topN))
+
+			res := openapi.V1CacheInspectResponseBody{
+				Resource: summary.Resource,
+				Entries:  int64(summary.Entries),
+				HitRatio: summary.HitRatio,
+				Hottest:  make([]openapi.HotKey, len(summary.Hottest)),
+			}
+			if !summary.Oldest.IsZero() {
+				res.Oldest = util.Pointer(summary.Oldest.UnixMilli())
+			}
+			for i, hot := range summary.Hottest {
+				res.Hottest[i] = openapi.HotKey{
+					Id:    hot.Id,
+					Reads: hot.Reads,
+				}
+			}
+
+			svc.Sender.Send(ctx, w, 200, res)
+		})
+}
@@ -0,0 +1,58 @@
+package v1CacheInspect_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1CacheInspect "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_cache_inspect"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+)
+
+func TestInspect(t *testing.T) {
+	h := testutil.NewHarness(t)
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize:  100,
+		Fresh:    time.Minute,
+		Stale:    time.Minute,
+		Logger:   logging.NewNoopLogger(),
+		Resource: "test",
+	})
+	require.NoError(t, err)
+	h.RegisterCache("test", c)
+
+	ctx := context.Background()
+	c.Set(ctx, "a", "value")
+	c.Set(ctx, "b", "value")
+	c.Get(ctx, "b")
+
+	route := h.SetupRoute(v1CacheInspect.New)
+
+	resp := testutil.CallRoute[openapi.V1CacheInspectRequestBody, openapi.V1CacheInspectResponseBody](t, route, nil, openapi.V1CacheInspectRequestBody{
+		Resource: "test",
+		TopN:     util.Pointer(int64(1)),
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, int64(2), resp.Body.Entries)
+	require.NotNil(t, resp.Body.Oldest)
+	require.Equal(t, []openapi.HotKey{{Id: cache.HashKey("b"), Reads: 1}}, resp.Body.Hottest)
+}
+
+func TestInspectUnknownCache(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1CacheInspect.New)
+
+	resp := testutil.CallRoute[openapi.V1CacheInspectRequestBody, openapi.BaseError](t, route, nil, openapi.V1CacheInspectRequestBody{
+		Resource: "does_not_exist",
+	})
+	require.Equal(t, 404, resp.Status)
+	require.Equal(t, "application/problem+json", resp.Headers.Get("Content-Type"))
+	require.Equal(t, "NOT_FOUND", resp.Body.Code)
+	require.Equal(t, 404, resp.Body.Status)
+}
@@ -0,0 +1,36 @@
+package v1CacheWarmup
+
+import (
+	"net/http"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+// New loads the latest data encryption key of the given keyrings into the
+// vault cache, for example after flushing it.
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/cache.warmup",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1CacheWarmupRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			err := svc.Vault.Warmup(ctx, req.Keyrings)
+			if err != nil {
+				svc.Sender.Send(ctx, w, 500, apiErrors.HandleError(ctx, err))
+				return
+			}
+			logger := ctxutil.Logger(ctx, svc.Audit)
+			logger.Info().Int("keyrings", len(req.Keyrings)).Msg("warmed up cache")
+
+			svc.Sender.Send(ctx, w, 204, nil)
+		})
+}
@@ -0,0 +1,53 @@
+package v1CacheWarmup_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1CacheWarmup "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_cache_warmup"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+	"github.com/unkeyed/unkey/apps/agent/services/vault"
+	"github.com/unkeyed/unkey/apps/agent/services/vault/keys"
+	"github.com/unkeyed/unkey/apps/agent/services/vault/storage"
+)
+
+func TestWarmup(t *testing.T) {
+	h := testutil.NewHarness(t)
+
+	s, err := storage.NewMemory(storage.MemoryConfig{Logger: logging.NewNoopLogger()})
+	require.NoError(t, err)
+	_, masterKey, err := keys.GenerateMasterKey()
+	require.NoError(t, err)
+	v, err := vault.New(vault.Config{
+		Logger:     logging.NewNoopLogger(),
+		Metrics:    metrics.NewNoop(),
+		Storage:    s,
+		MasterKeys: []string{masterKey},
+	})
+	require.NoError(t, err)
+	h.SetVault(v)
+
+	route := h.SetupRoute(v1CacheWarmup.New)
+
+	resp := testutil.CallRoute[openapi.V1CacheWarmupRequestBody, any](t, route, nil, openapi.V1CacheWarmupRequestBody{
+		Keyrings: []string{"keyring_a", "keyring_b"},
+	})
+	require.Equal(t, 204, resp.Status)
+
+	summary := v.Caches()["data_encryption_key"].Inspect(context.Background(), 0)
+	require.Equal(t, 2, summary.Entries)
+}
+
+func TestWarmupWithoutKeyrings(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1CacheWarmup.New)
+
+	resp := testutil.CallRoute[openapi.V1CacheWarmupRequestBody, openapi.ValidationError](t, route, nil, openapi.V1CacheWarmupRequestBody{
+		Keyrings: []string{},
+	})
+	require.Equal(t, 400, resp.Status)
+}
@@ -0,0 +1,38 @@
+package v1EventsFlush
+
+import (
+	"net/http"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+// New flushes all buffers in order, so events routed into another buffer are
+// flushed along with it.
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/events.flush",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			logger := ctxutil.Logger(ctx, svc.Audit)
+
+			res := openapi.V1EventsFlushResponseBody{
+				Flushed: []string{},
+			}
+			for _, b := range svc.Buffers {
+				err := b.Flush(ctx)
+				if err != nil {
+					err = fault.Wrap(err, fmsg.WithDesc("flush_failed", "failed to flush "+b.Name))
+					svc.Sender.Send(ctx, w, 500, apiErrors.HandleError(ctx, err))
+					return
+				}
+				res.Flushed = append(res.Flushed, b.Name)
+			}
+			logger.Info().Strs("buffers", res.Flushed).Msg("flushed buffers")
+
+			svc.Sender.Send(ctx, w, 200, res)
+		})
+}
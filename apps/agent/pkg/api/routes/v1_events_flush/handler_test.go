@@ -0,0 +1,44 @@
+package v1EventsFlush_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1EventsFlush "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_events_flush"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestFlushInOrder(t *testing.T) {
+	h := testutil.NewHarness(t)
+
+	flushed := []string{}
+	h.RegisterBuffer("eventrouter", func(ctx context.Context) error {
+		flushed = append(flushed, "eventrouter")
+		return nil
+	})
+	h.RegisterBuffer("clickhouse", func(ctx context.Context) error {
+		flushed = append(flushed, "clickhouse")
+		return nil
+	})
+	route := h.SetupRoute(v1EventsFlush.New)
+
+	resp := testutil.CallRoute[any, openapi.V1EventsFlushResponseBody](t, route, nil, nil)
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, []string{"eventrouter", "clickhouse"}, resp.Body.Flushed)
+	require.Equal(t, []string{"eventrouter", "clickhouse"}, flushed)
+}
+
+func TestFlushError(t *testing.T) {
+	h := testutil.NewHarness(t)
+	h.RegisterBuffer("clickhouse", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	route := h.SetupRoute(v1EventsFlush.New)
+
+	resp := testutil.CallRoute[any, openapi.BaseError](t, route, nil, nil)
+	require.Equal(t, 500, resp.Status)
+	require.Contains(t, resp.Body.Detail, "clickhouse")
+}
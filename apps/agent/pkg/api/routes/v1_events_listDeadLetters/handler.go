@@ -0,0 +1,48 @@
+package v1EventsListDeadLetters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/events.listDeadLetters",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1EventsListDeadLettersRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			q, ok := svc.DeadLetters[req.Queue]
+			if !ok {
+				svc.Sender.Send(ctx, w, 404, apiErrors.NotFound(ctx, fmt.Sprintf("dead letter queue %s does not exist", req.Queue)))
+				return
+			}
+
+			summaries := q.Inspect()
+			res := openapi.V1EventsListDeadLettersResponseBody{
+				DeadLetters: make([]openapi.DeadLetter, len(summaries)),
+			}
+			for i, l := range summaries {
+				res.DeadLetters[i] = openapi.DeadLetter{
+					Id:         l.Id,
+					Subscriber: l.Subscriber,
+					Event:      json.RawMessage(l.Event),
+					Error:      l.Error,
+					Attempts:   int64(l.Attempts),
+					Time:       l.Time.UnixMilli(),
+				}
+			}
+
+			svc.Sender.Send(ctx, w, 200, res)
+		})
+}
@@ -0,0 +1,64 @@
+package v1EventsListDeadLetters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1EventsListDeadLetters "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_events_listDeadLetters"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+type event struct {
+	Name string `json:"name"`
+}
+
+func TestListDeadLetters(t *testing.T) {
+	h := testutil.NewHarness(t)
+
+	topic := events.NewTopic[event]()
+	dlq := events.NewDeadLetterQueue[event](10, logging.NewNoopLogger())
+	stop := events.Handle[event](topic, events.HandlerConfig[event]{
+		Id:          "test",
+		MaxAttempts: 1,
+		DeadLetters: dlq,
+		Logger:      logging.NewNoopLogger(),
+		Handle: func(ctx context.Context, e event) error {
+			return errors.New("unavailable")
+		},
+	})
+	defer stop()
+	h.RegisterDeadLetters("test", dlq)
+
+	topic.Publish(context.Background(), event{Name: "a"})
+	require.Eventually(t, func() bool { return len(dlq.List()) == 1 }, time.Second, time.Millisecond)
+
+	route := h.SetupRoute(v1EventsListDeadLetters.New)
+
+	resp := testutil.CallRoute[openapi.V1EventsListDeadLettersRequestBody, openapi.V1EventsListDeadLettersResponseBody](t, route, nil, openapi.V1EventsListDeadLettersRequestBody{
+		Queue: "test",
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Len(t, resp.Body.DeadLetters, 1)
+	l := resp.Body.DeadLetters[0]
+	require.Equal(t, dlq.List()[0].Id, l.Id)
+	require.Equal(t, "test", l.Subscriber)
+	require.Equal(t, "unavailable", l.Error)
+	require.Equal(t, int64(1), l.Attempts)
+	require.Equal(t, map[string]any{"name": "a"}, l.Event)
+}
+
+func TestListUnknownQueue(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1EventsListDeadLetters.New)
+
+	resp := testutil.CallRoute[openapi.V1EventsListDeadLettersRequestBody, openapi.BaseError](t, route, nil, openapi.V1EventsListDeadLettersRequestBody{
+		Queue: "does_not_exist",
+	})
+	require.Equal(t, 404, resp.Status)
+}
@@ -0,0 +1,51 @@
+package v1EventsRequeueDeadLetter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/events.requeueDeadLetter",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1EventsRequeueDeadLetterRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			notFound := func(detail string) {
+				svc.Sender.Send(ctx, w, 404, apiErrors.NotFound(ctx, detail))
+			}
+
+			q, ok := svc.DeadLetters[req.Queue]
+			if !ok {
+				notFound(fmt.Sprintf("dead letter queue %s does not exist", req.Queue))
+				return
+			}
+
+			err := q.Requeue(ctx, req.Id)
+			if err != nil {
+				if errors.Is(err, events.ErrDeadLetterNotFound) {
+					notFound(fmt.Sprintf("dead letter %s does not exist", req.Id))
+					return
+				}
+				svc.Sender.Send(ctx, w, 500, apiErrors.HandleError(ctx, err))
+				return
+			}
+			logger := ctxutil.Logger(ctx, svc.Audit)
+			logger.Info().Str("queue", req.Queue).Str("id", req.Id).Msg("requeued dead letter")
+
+			svc.Sender.Send(ctx, w, 204, nil)
+		})
+}
@@ -0,0 +1,59 @@
+package v1EventsRequeueDeadLetter_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1EventsRequeueDeadLetter "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_events_requeueDeadLetter"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestRequeueDeadLetter(t *testing.T) {
+	h := testutil.NewHarness(t)
+
+	topic := events.NewTopic[string]()
+	dlq := events.NewDeadLetterQueue[string](10, logging.NewNoopLogger())
+	fail := atomic.Bool{}
+	fail.Store(true)
+	stop := events.Handle[string](topic, events.HandlerConfig[string]{
+		Id:          "test",
+		MaxAttempts: 1,
+		DeadLetters: dlq,
+		Logger:      logging.NewNoopLogger(),
+		Handle: func(ctx context.Context, e string) error {
+			if fail.Load() {
+				return errors.New("unavailable")
+			}
+			return nil
+		},
+	})
+	defer stop()
+	h.RegisterDeadLetters("test", dlq)
+
+	topic.Publish(context.Background(), "a")
+	require.Eventually(t, func() bool { return len(dlq.List()) == 1 }, time.Second, time.Millisecond)
+	id := dlq.List()[0].Id
+
+	route := h.SetupRoute(v1EventsRequeueDeadLetter.New)
+
+	fail.Store(false)
+	resp := testutil.CallRoute[openapi.V1EventsRequeueDeadLetterRequestBody, any](t, route, nil, openapi.V1EventsRequeueDeadLetterRequestBody{
+		Queue: "test",
+		Id:    id,
+	})
+	require.Equal(t, 204, resp.Status)
+	require.Empty(t, dlq.List())
+
+	notFound := testutil.CallRoute[openapi.V1EventsRequeueDeadLetterRequestBody, openapi.BaseError](t, route, nil, openapi.V1EventsRequeueDeadLetterRequestBody{
+		Queue: "test",
+		Id:    id,
+	})
+	require.Equal(t, 404, notFound.Status)
+}
@@ -0,0 +1,48 @@
+package v1LoggingSetLevel
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/logging.setLevel",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1LoggingSetLevelRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			level, err := zerolog.ParseLevel(string(req.Level))
+			if err != nil {
+				svc.Sender.Send(ctx, w, 500, apiErrors.HandleError(ctx, err))
+				return
+			}
+
+			err = logging.SetModuleLevel(req.Module, level)
+			if err != nil {
+				svc.Sender.Send(ctx, w, 404, apiErrors.NotFound(ctx, err.Error()))
+				return
+			}
+			logger := ctxutil.Logger(ctx, svc.Audit)
+			logger.Info().Str("module", req.Module).Str("level", level.String()).Msg("changed log level")
+
+			levels := map[string]string{}
+			for module, l := range logging.ModuleLevels() {
+				levels[module] = l.String()
+			}
+			svc.Sender.Send(ctx, w, 200, openapi.V1LoggingSetLevelResponseBody{
+				Levels: levels,
+			})
+		})
+}
@@ -0,0 +1,38 @@
+package v1LoggingSetLevel_test
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	v1LoggingSetLevel "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_logging_setLevel"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func TestSetLevel(t *testing.T) {
+	h := testutil.NewHarness(t)
+	logging.Module(zerolog.New(nil).Level(zerolog.InfoLevel), "test")
+
+	route := h.SetupRoute(v1LoggingSetLevel.New)
+
+	resp := testutil.CallRoute[openapi.V1LoggingSetLevelRequestBody, openapi.V1LoggingSetLevelResponseBody](t, route, nil, openapi.V1LoggingSetLevelRequestBody{
+		Module: "test",
+		Level:  openapi.Debug,
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, "debug", resp.Body.Levels["test"])
+	require.Equal(t, zerolog.DebugLevel, logging.ModuleLevels()["test"])
+}
+
+func TestSetLevelUnknownModule(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1LoggingSetLevel.New)
+
+	resp := testutil.CallRoute[openapi.V1LoggingSetLevelRequestBody, openapi.BaseError](t, route, nil, openapi.V1LoggingSetLevelRequestBody{
+		Module: "does_not_exist",
+		Level:  openapi.Debug,
+	})
+	require.Equal(t, 404, resp.Status)
+}
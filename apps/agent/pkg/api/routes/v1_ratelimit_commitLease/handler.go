@@ -38,7 +38,7 @@ func New(svc routes.Services) *routes.Route {
 				Cost:  req.Cost,
 			})
 			if err != nil {
-				errors.HandleError(ctx, fault.Wrap(err, fmsg.With("failed to commit lease")))
+				svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, fault.Wrap(err, fmsg.With("failed to commit lease"))))
 				return
 
 			}
@@ -0,0 +1,44 @@
+package v1RatelimitInspect
+
+import (
+	"net/http"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+)
+
+func New(svc routes.Services) *routes.Route {
+	return routes.NewRoute("POST", "/v1/ratelimit.inspect",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			req := &openapi.V1RatelimitInspectRequestBody{}
+			errorResponse, valid := svc.OpenApiValidator.Body(r, req)
+			if !valid {
+				svc.Sender.Send(ctx, w, 400, errorResponse)
+				return
+			}
+
+			topN := int64(10)
+			if req.TopN != nil {
+				topN = *req.TopN
+			}
+			stats := svc.RatelimitStats.Inspect(ctx, int(topN))
+
+			res := openapi.V1RatelimitInspectResponseBody{
+				Buckets: int64(stats.Buckets),
+				Windows: int64(stats.Windows),
+				Hottest: make([]openapi.RatelimitBucket, len(stats.Hottest)),
+			}
+			for i, b := range stats.Hottest {
+				res.Hottest[i] = openapi.RatelimitBucket{
+					Id:       b.Id,
+					Limit:    b.Limit,
+					Duration: b.Duration.Milliseconds(),
+					Current:  b.Current,
+				}
+			}
+
+			svc.Sender.Send(ctx, w, 200, res)
+		})
+}
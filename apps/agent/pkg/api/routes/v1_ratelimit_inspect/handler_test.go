@@ -0,0 +1,40 @@
+package v1RatelimitInspect_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1RatelimitInspect "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_ratelimit_inspect"
+	v1RatelimitRatelimit "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_ratelimit_ratelimit"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+)
+
+func TestInspect(t *testing.T) {
+	h := testutil.NewHarness(t)
+	ratelimit := h.SetupRoute(v1RatelimitRatelimit.New)
+	inspect := h.SetupRoute(v1RatelimitInspect.New)
+
+	hot := uid.New("test")
+	for _, identifier := range []string{hot, hot, hot, uid.New("test")} {
+		resp := testutil.CallRoute[openapi.V1RatelimitRatelimitRequestBody, openapi.V1RatelimitRatelimitResponseBody](t, ratelimit, nil, openapi.V1RatelimitRatelimitRequestBody{
+			Identifier: identifier,
+			Limit:      10,
+			Duration:   time.Hour.Milliseconds(),
+		})
+		require.Equal(t, 200, resp.Status)
+	}
+
+	resp := testutil.CallRoute[openapi.V1RatelimitInspectRequestBody, openapi.V1RatelimitInspectResponseBody](t, inspect, nil, openapi.V1RatelimitInspectRequestBody{
+		TopN: util.Pointer(int64(1)),
+	})
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, int64(2), resp.Body.Buckets)
+	require.Len(t, resp.Body.Hottest, 1)
+	require.Equal(t, int64(3), resp.Body.Hottest[0].Current)
+	require.Equal(t, int64(10), resp.Body.Hottest[0].Limit)
+	require.Equal(t, time.Hour.Milliseconds(), resp.Body.Hottest[0].Duration)
+}
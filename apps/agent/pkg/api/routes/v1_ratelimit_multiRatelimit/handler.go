@@ -7,6 +7,7 @@ import (
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
 	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
 )
 
 func New(svc routes.Services) *routes.Route {
@@ -31,15 +32,19 @@ func New(svc routes.Services) *routes.Route {
 				Limit:      r.Limit,
 				Duration:   r.Duration,
 				Cost:       cost,
+				Shadow:     r.Shadow != nil && *r.Shadow,
 			}
 		}
-		svcRes, err := svc.Ratelimit.MultiRatelimit(ctx, &ratelimitv1.RatelimitMultiRequest{})
+		svcRes, err := svc.Ratelimit.MultiRatelimit(ctx, &ratelimitv1.RatelimitMultiRequest{
+			Ratelimits: ratelimits,
+		})
 		if err != nil {
-			errors.HandleError(ctx, err)
+			svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, err))
 			return
 
 		}
-		res.Ratelimits = make([]openapi.SingleRatelimitResponse, len(res.Ratelimits))
+		res.Success = svcRes.Success
+		res.Ratelimits = make([]openapi.SingleRatelimitResponse, len(svcRes.Ratelimits))
 		for i, r := range svcRes.Ratelimits {
 			res.Ratelimits[i] = openapi.SingleRatelimitResponse{
 				Current:   r.Current,
@@ -48,6 +53,9 @@ func New(svc routes.Services) *routes.Route {
 				Reset:     r.Reset_,
 				Success:   r.Success,
 			}
+			if ratelimits[i].Shadow {
+				res.Ratelimits[i].WouldBlock = util.Pointer(r.WouldBlock)
+			}
 		}
 
 		svc.Sender.Send(ctx, w, 200, res)
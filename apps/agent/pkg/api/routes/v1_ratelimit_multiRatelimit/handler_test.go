@@ -0,0 +1,45 @@
+package v1RatelimitMultiRatelimit_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1RatelimitMultiRatelimit "github.com/unkeyed/unkey/apps/agent/pkg/api/routes/v1_ratelimit_multiRatelimit"
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
+	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
+	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+)
+
+func TestMultiRatelimit(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1RatelimitMultiRatelimit.New)
+
+	req := openapi.V1RatelimitMultiRatelimitRequestBody{
+		Ratelimits: []openapi.Item{
+			{
+				Identifier: uid.New("test"),
+				Limit:      10,
+				Duration:   1000,
+			},
+			{
+				Identifier: uid.New("test"),
+				Limit:      1,
+				Duration:   1000,
+			},
+		},
+	}
+
+	resp := testutil.CallRoute[openapi.V1RatelimitMultiRatelimitRequestBody, openapi.V1RatelimitMultiRatelimitResponseBody](t, route, nil, req)
+	require.Equal(t, 200, resp.Status)
+	require.True(t, resp.Body.Success)
+	require.Len(t, resp.Body.Ratelimits, 2)
+	require.Equal(t, int64(9), resp.Body.Ratelimits[0].Remaining)
+	require.Equal(t, int64(0), resp.Body.Ratelimits[1].Remaining)
+
+	resp = testutil.CallRoute[openapi.V1RatelimitMultiRatelimitRequestBody, openapi.V1RatelimitMultiRatelimitResponseBody](t, route, nil, req)
+	require.Equal(t, 200, resp.Status)
+	require.False(t, resp.Body.Success)
+	require.True(t, resp.Body.Ratelimits[0].Success)
+	require.False(t, resp.Body.Ratelimits[1].Success)
+	require.Equal(t, int64(9), resp.Body.Ratelimits[0].Remaining)
+}
@@ -41,9 +41,10 @@ func New(svc routes.Services) *routes.Route {
 			Duration:   req.Duration,
 			Cost:       *req.Cost,
 			Lease:      lease,
+			Shadow:     req.Shadow != nil && *req.Shadow,
 		})
 		if err != nil {
-			errors.HandleError(ctx, err)
+			svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, err))
 			return
 		}
 
@@ -54,11 +55,14 @@ func New(svc routes.Services) *routes.Route {
 			Success:   res.Success,
 			Current:   res.Current,
 		}
+		if req.Shadow != nil && *req.Shadow {
+			response.WouldBlock = util.Pointer(res.WouldBlock)
+		}
 
 		if res.Lease != nil {
 			b, err := proto.Marshal(res.Lease)
 			if err != nil {
-				errors.HandleError(ctx, err)
+				svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, err))
 				return
 			}
 			response.Lease = base58.Encode(b)
@@ -9,6 +9,7 @@ import (
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/testutil"
 	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
 	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
 )
 
 func TestRatelimit(t *testing.T) {
@@ -54,3 +55,28 @@ func TestRatelimitWithLease(t *testing.T) {
 	require.Equal(t, int64(10), resp.Body.Current)
 	require.NotNil(t, resp.Body.Lease)
 }
+
+func TestRatelimitShadow(t *testing.T) {
+	h := testutil.NewHarness(t)
+	route := h.SetupRoute(v1RatelimitRatelimit.New)
+
+	req := openapi.V1RatelimitRatelimitRequestBody{
+		Identifier: uid.New("test"),
+		Limit:      1,
+		Duration:   time.Minute.Milliseconds(),
+		Shadow:     util.Pointer(true),
+	}
+
+	resp := testutil.CallRoute[openapi.V1RatelimitRatelimitRequestBody, openapi.V1RatelimitRatelimitResponseBody](t, route, nil, req)
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, true, resp.Body.Success)
+	require.NotNil(t, resp.Body.WouldBlock)
+	require.Equal(t, false, *resp.Body.WouldBlock)
+
+	resp = testutil.CallRoute[openapi.V1RatelimitRatelimitRequestBody, openapi.V1RatelimitRatelimitResponseBody](t, route, nil, req)
+	require.Equal(t, 200, resp.Status)
+	require.Equal(t, true, resp.Body.Success)
+	require.NotNil(t, resp.Body.WouldBlock)
+	require.Equal(t, true, *resp.Body.WouldBlock)
+	require.Equal(t, int64(1), resp.Body.Current)
+}
@@ -25,7 +25,8 @@ func New(svc routes.Services) *routes.Route {
 			Encrypted: req.Encrypted,
 		})
 		if err != nil {
-			errors.HandleError(ctx, fault.Wrap(err, fmsg.With("failed to decrypt")))
+			svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, fault.Wrap(err, fmsg.With("failed to decrypt"))))
+			return
 		}
 
 		svc.Sender.Send(ctx, w, 200, openapi.V1DecryptResponseBody{
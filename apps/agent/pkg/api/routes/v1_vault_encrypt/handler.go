@@ -24,7 +24,7 @@ func New(svc routes.Services) *routes.Route {
 				Data:    req.Data,
 			})
 			if err != nil {
-				errors.HandleError(ctx, err)
+				svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, err))
 				return
 			}
 
@@ -28,7 +28,7 @@ func New(svc routes.Services) *routes.Route {
 			Data:    req.Data,
 		})
 		if err != nil {
-			errors.HandleError(ctx, fault.Wrap(err, fmsg.With("failed to encrypt")))
+			svc.Sender.Send(ctx, w, 500, errors.HandleError(ctx, fault.Wrap(err, fmsg.With("failed to encrypt"))))
 			return
 		}
 
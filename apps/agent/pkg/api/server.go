@@ -1,11 +1,18 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/validation"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/loadshedding"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
 	"github.com/unkeyed/unkey/apps/agent/services/eventrouter"
@@ -16,6 +23,7 @@ import (
 type Server struct {
 	sync.Mutex
 	logger      logging.Logger
+	audit       logging.Logger
 	metrics     metrics.Metrics
 	isListening bool
 	mux         *http.ServeMux
@@ -25,6 +33,19 @@ type Server struct {
 	authToken string
 	vault     *vault.Service
 	ratelimit ratelimit.Service
+	caches    map[string]cache.Inspector
+	analytics clickhouse.Querier
+	// dead letter queues by name, registered before listening
+	deadLetters map[string]events.DeadLetterInspector
+	// reported by /readyz
+	readinessChecks map[string]func(ctx context.Context) error
+	// registered before listening, flushed in order
+	buffers        []routes.Buffer
+	ratelimitStats ratelimit.Inspector
+	// sheds ratelimit requests beyond our capacity, may be nil
+	loadShedding *loadshedding.Limiter
+	// cancelled when the server shuts down, see untilShutdown
+	shuttingDown context.Context
 
 	clickhouse EventBuffer
 	validator  validation.OpenAPIValidator
@@ -38,6 +59,18 @@ type Config struct {
 	Clickhouse EventBuffer
 	Vault      *vault.Service
 	AuthToken  string
+	Caches     map[string]cache.Inspector
+	Analytics  clickhouse.Querier
+	// Security relevant actions are logged here, defaults to Logger
+	Audit *logging.Logger
+	// Dependencies that must be healthy before the agent receives traffic, by name
+	ReadinessChecks map[string]func(ctx context.Context) error
+	// Reports on the buckets held by this node, usually the unwrapped Ratelimit service
+	RatelimitStats ratelimit.Inspector
+	// Addresses or CIDR ranges whose forwarding headers are trusted to report the source ip
+	TrustedProxies []string
+	// Rejects ratelimit requests beyond our capacity, nothing is shed if nil
+	LoadShedding *loadshedding.Limiter
 }
 
 func New(config Config) (*Server, error) {
@@ -61,9 +94,17 @@ func New(config Config) (*Server, error) {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 20 * time.Second,
 	}
+	shuttingDown, cancelShuttingDown := context.WithCancel(context.Background())
+	srv.RegisterOnShutdown(cancelShuttingDown)
+
+	audit := config.Logger.With().Bool("audit", true).Logger()
+	if config.Audit != nil {
+		audit = *config.Audit
+	}
 
 	s := &Server{
 		logger:      config.Logger,
+		audit:       audit,
 		metrics:     config.Metrics,
 		ratelimit:   config.Ratelimit,
 		vault:       config.Vault,
@@ -72,6 +113,14 @@ func New(config Config) (*Server, error) {
 		srv:         srv,
 		clickhouse:  config.Clickhouse,
 		authToken:   config.AuthToken,
+		caches:      config.Caches,
+		analytics:   config.Analytics,
+		deadLetters: map[string]events.DeadLetterInspector{},
+
+		readinessChecks: config.ReadinessChecks,
+		ratelimitStats:  config.RatelimitStats,
+		loadShedding:    config.LoadShedding,
+		shuttingDown:    shuttingDown,
 	}
 	// validationMiddleware, err := s.createOpenApiValidationMiddleware("./pkg/openapi/openapi.json")
 	// if err != nil {
@@ -89,7 +138,12 @@ func New(config Config) (*Server, error) {
 	}
 	s.validator = v
 
-	s.srv.Handler = withMetrics(withTracing(withRequestId(s.mux)))
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	s.srv.Handler = withMetrics(withTracing(withRequestId(withSourceIp(s.mux, trustedProxies))))
 
 	return s, nil
 }
@@ -99,8 +153,43 @@ func (s *Server) WithEventRouter(svc *eventrouter.Service) {
 	defer s.Unlock()
 
 	pattern, handlerFunc := svc.CreateHandler()
-
 	s.mux.HandleFunc(pattern, handlerFunc)
+
+	pattern, handlerFunc = svc.CreateStreamHandler()
+	s.mux.HandleFunc(pattern, s.untilShutdown(handlerFunc))
+}
+
+// untilShutdown cancels the request context when the server shuts down.
+// Shutdown waits for in-flight requests without cancelling them, so long lived
+// requests like streams would otherwise hold it up until its deadline.
+func (s *Server) untilShutdown(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		stop := context.AfterFunc(s.shuttingDown, cancel)
+		defer stop()
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// WithDeadLetters makes the dead letter queue available to the events routes.
+// It must be called before Listen.
+func (s *Server) WithDeadLetters(name string, q events.DeadLetterInspector) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.deadLetters[name] = q
+}
+
+// WithBuffer allows flushing the buffer through the events routes. Buffers are
+// flushed in the order they were added, so add those that write into other
+// buffers first. It must be called before Listen.
+func (s *Server) WithBuffer(name string, flush func(ctx context.Context) error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.buffers = append(s.buffers, routes.Buffer{Name: name, Flush: flush})
 }
 
 // Calling this function multiple times will have no effect.
@@ -118,12 +207,17 @@ func (s *Server) Listen(addr string) error {
 	s.srv.Addr = addr
 
 	s.logger.Info().Str("addr", addr).Msg("listening")
-	return s.srv.ListenAndServe()
+	err := s.srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-func (s *Server) Shutdown() error {
+// Shutdown stops accepting new connections and waits for in-flight requests
+// until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
 	s.Lock()
 	defer s.Unlock()
-	return s.srv.Close()
-
+	return s.srv.Shutdown(ctx)
 }
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+	"github.com/unkeyed/unkey/apps/agent/pkg/port"
+)
+
+func TestShutdownCancelsStreams(t *testing.T) {
+	s, err := New(Config{
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	s.mux.HandleFunc("GET /stream", s.untilShutdown(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		flushErr := http.NewResponseController(w).Flush()
+		if flushErr != nil {
+			t.Error(flushErr)
+		}
+		close(started)
+		<-r.Context().Done()
+	}))
+
+	addr := fmt.Sprintf("localhost:%d", port.New().Get())
+	go func() {
+		listenErr := s.Listen(addr)
+		if listenErr != nil {
+			t.Error(listenErr)
+		}
+	}()
+
+	var res *http.Response
+	require.Eventually(t, func() bool {
+		res, err = http.Get(fmt.Sprintf("http://%s/stream", addr))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer res.Body.Close()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, s.Shutdown(ctx))
+	require.Less(t, time.Since(start), time.Second)
+}
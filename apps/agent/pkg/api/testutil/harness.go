@@ -2,6 +2,7 @@ package testutil
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,13 +12,17 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/routes"
 	"github.com/unkeyed/unkey/apps/agent/pkg/api/validation"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
 	"github.com/unkeyed/unkey/apps/agent/pkg/cluster"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/membership"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
 	"github.com/unkeyed/unkey/apps/agent/pkg/port"
 	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
 	"github.com/unkeyed/unkey/apps/agent/services/ratelimit"
+	"github.com/unkeyed/unkey/apps/agent/services/vault"
 )
 
 type Harness struct {
@@ -26,7 +31,16 @@ type Harness struct {
 	logger  logging.Logger
 	metrics metrics.Metrics
 
-	ratelimit ratelimit.Service
+	ratelimit   ratelimit.Service
+	vault       *vault.Service
+	caches      map[string]cache.Inspector
+	analytics   clickhouse.Querier
+	deadLetters map[string]events.DeadLetterInspector
+
+	readinessChecks map[string]func(ctx context.Context) error
+	buffers         []routes.Buffer
+	// the unwrapped ratelimit service
+	ratelimitStats ratelimit.Inspector
 
 	mux *http.ServeMux
 }
@@ -41,10 +55,15 @@ func NewHarness(t *testing.T) *Harness {
 	rpcAddr := fmt.Sprintf("localhost:%d", p.Get())
 
 	h := Harness{
-		t:       t,
-		logger:  logging.NewNoopLogger(),
-		metrics: metrics.NewNoop(),
-		mux:     mux,
+		t:           t,
+		logger:      logging.NewNoopLogger(),
+		metrics:     metrics.NewNoop(),
+		caches:      map[string]cache.Inspector{},
+		analytics:   clickhouse.NewNoop(),
+		deadLetters: map[string]events.DeadLetterInspector{},
+		mux:         mux,
+
+		readinessChecks: map[string]func(ctx context.Context) error{},
 	}
 
 	memb, err := membership.New(membership.Config{
@@ -69,10 +88,41 @@ func NewHarness(t *testing.T) *Harness {
 	})
 	require.NoError(t, err)
 	h.ratelimit = rl
+	h.ratelimitStats = rl
 
 	return &h
 }
 
+// RegisterCache makes the cache available to routes under the given resource name.
+func (h *Harness) RegisterCache(resource string, c cache.Inspector) {
+	h.caches[resource] = c
+}
+
+// RegisterDeadLetters makes the dead letter queue available to routes under the given name.
+func (h *Harness) RegisterDeadLetters(name string, q events.DeadLetterInspector) {
+	h.deadLetters[name] = q
+}
+
+// RegisterReadinessCheck adds a dependency that is reported by the readiness route.
+func (h *Harness) RegisterReadinessCheck(name string, check func(ctx context.Context) error) {
+	h.readinessChecks[name] = check
+}
+
+// RegisterBuffer adds a buffer that is flushed by the events routes.
+func (h *Harness) RegisterBuffer(name string, flush func(ctx context.Context) error) {
+	h.buffers = append(h.buffers, routes.Buffer{Name: name, Flush: flush})
+}
+
+// SetVault makes the vault service available to routes.
+func (h *Harness) SetVault(v *vault.Service) {
+	h.vault = v
+}
+
+// SetAnalytics replaces the noop analytics querier used by routes.
+func (h *Harness) SetAnalytics(q clickhouse.Querier) {
+	h.analytics = q
+}
+
 func (h *Harness) Register(route *routes.Route) {
 
 	route.Register(h.mux)
@@ -85,11 +135,18 @@ func (h *Harness) SetupRoute(constructor func(svc routes.Services) *routes.Route
 	require.NoError(h.t, err)
 	route := constructor(routes.Services{
 		Logger:           h.logger,
+		Audit:            h.logger,
 		Metrics:          h.metrics,
 		Ratelimit:        h.ratelimit,
-		Vault:            nil,
+		RatelimitStats:   h.ratelimitStats,
+		Vault:            h.vault,
 		OpenApiValidator: validator,
 		Sender:           routes.NewJsonSender(h.logger),
+		Caches:           h.caches,
+		Analytics:        h.analytics,
+		DeadLetters:      h.deadLetters,
+		ReadinessChecks:  h.readinessChecks,
+		Buffers:          h.buffers,
 	})
 	h.Register(route)
 	return route
@@ -134,8 +191,11 @@ func CallRoute[Req any, Res any](t *testing.T, route *routes.Route, headers http
 	require.NoError(t, err)
 
 	var res Res
-	err = json.NewDecoder(rr.Body).Decode(&res)
-	require.NoError(t, err)
+	// Responses without content, such as 204, have no body to decode
+	if rr.Body.Len() > 0 {
+		err = json.NewDecoder(rr.Body).Decode(&res)
+		require.NoError(t, err)
+	}
 
 	return TestResponse[Res]{
 		Status:  rr.Code,
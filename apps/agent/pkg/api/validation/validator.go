@@ -11,7 +11,7 @@ import (
 	"github.com/Southclaws/fault/fmsg"
 	"github.com/pb33f/libopenapi"
 	validator "github.com/pb33f/libopenapi-validator"
-	"github.com/unkeyed/unkey/apps/agent/pkg/api/ctxutil"
+	apiErrors "github.com/unkeyed/unkey/apps/agent/pkg/api/errors"
 	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
 	"github.com/unkeyed/unkey/apps/agent/pkg/util"
 )
@@ -53,31 +53,16 @@ func (v *Validator) Body(r *http.Request, dest any) (openapi.ValidationError, bo
 	bodyBytes, err := io.ReadAll(r.Body)
 	r.Body.Close()
 	if err != nil {
-		return openapi.ValidationError{
-			Title:  "Bad Request",
-			Detail: "Failed to read request body",
-			Errors: []openapi.ValidationErrorDetail{{
-				Location: "body",
-				Message:  err.Error(),
-			}},
-			Instance:  "https://errors.unkey.com/todo",
-			Status:    http.StatusBadRequest,
-			RequestId: ctxutil.GetRequestId(r.Context()),
-		}, false
+		return apiErrors.NewValidationError(r.Context(), "Failed to read request body", []openapi.ValidationErrorDetail{{
+			Location: "body",
+			Message:  err.Error(),
+		}}), false
 	}
 	r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 
 	valid, errors := v.validator.ValidateHttpRequest(r)
 	if !valid {
-		valErr := openapi.ValidationError{
-			Title:     "Bad Request",
-			Detail:    "One or more fields failed validation",
-			Instance:  "https://errors.unkey.com/todo",
-			Status:    http.StatusBadRequest,
-			RequestId: ctxutil.GetRequestId(r.Context()),
-			Type:      "TODO docs link",
-			Errors:    []openapi.ValidationErrorDetail{},
-		}
+		valErr := apiErrors.NewValidationError(r.Context(), "One or more fields failed validation", nil)
 		for _, e := range errors {
 			fmt.Printf("valerr: %+v\n", e)
 
@@ -94,19 +79,11 @@ func (v *Validator) Body(r *http.Request, dest any) (openapi.ValidationError, bo
 	err = json.Unmarshal(bodyBytes, dest)
 
 	if err != nil {
-		return openapi.ValidationError{
-			Title:  "Bad Request",
-			Detail: "Failed to parse request body as JSON",
-			Errors: []openapi.ValidationErrorDetail{{
-				Location: "body",
-				Message:  err.Error(),
-				Fix:      util.Pointer("Ensure the request body is valid JSON"),
-			}},
-			Instance:  "https://errors.unkey.com/todo",
-			Status:    http.StatusBadRequest,
-			RequestId: ctxutil.GetRequestId(r.Context()),
-			Type:      "TODO docs link",
-		}, false
+		return apiErrors.NewValidationError(r.Context(), "Failed to parse request body as JSON", []openapi.ValidationErrorDetail{{
+			Location: "body",
+			Message:  err.Error(),
+			Fix:      util.Pointer("Ensure the request body is valid JSON"),
+		}}), false
 	}
 	fmt.Printf("body %+v\n", dest)
 
@@ -7,7 +7,7 @@ import (
 
 var (
 	// droppedMessages tracks the number of messages dropped due to a full buffer
-	// for each BatchProcessor instance. The "name" label identifies the specific
+	// or because they arrived after Close, for each BatchProcessor instance. The "name" label identifies the specific
 	// BatchProcessor.
 	droppedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "agent",
@@ -2,6 +2,7 @@ package batch
 
 import (
 	"context"
+	"sync"
 	"time"
 )
 
@@ -9,9 +10,17 @@ type BatchProcessor[T any] struct {
 	name   string
 	drop   bool
 	buffer chan T
-	batch  []T
 	config Config[T]
 	flush  func(ctx context.Context, batch []T)
+	// done when all consumers have flushed their last batch
+	consumers sync.WaitGroup
+	// one per consumer, see Flush
+	flushRequests []chan chan struct{}
+
+	// guards buffer against sends after Close, handlers may still be running
+	// when the server did not shut down in time
+	mu     sync.RWMutex
+	closed bool
 }
 
 type Config[T any] struct {
@@ -36,24 +45,29 @@ func New[T any](config Config[T]) *BatchProcessor[T] {
 		name:   config.Name,
 		drop:   config.Drop,
 		buffer: make(chan T, config.BufferSize),
-		batch:  make([]T, 0, config.BatchSize),
 		flush:  config.Flush,
 		config: config,
 	}
 
+	bp.consumers.Add(bp.config.Consumers)
 	for _ = range bp.config.Consumers {
-		go bp.process()
+		flushRequests := make(chan chan struct{})
+		bp.flushRequests = append(bp.flushRequests, flushRequests)
+		go bp.process(flushRequests)
 	}
 
 	return bp
 }
 
-func (bp *BatchProcessor[T]) process() {
+// process runs a single consumer, each consumer collects its own batch
+func (bp *BatchProcessor[T]) process(flushRequests <-chan chan struct{}) {
+	defer bp.consumers.Done()
+	batch := make([]T, 0, bp.config.BatchSize)
 	t := time.NewTimer(bp.config.FlushInterval)
 	flushAndReset := func() {
-		if len(bp.batch) > 0 {
-			bp.flush(context.Background(), bp.batch)
-			bp.batch = bp.batch[:0]
+		if len(batch) > 0 {
+			bp.flush(context.Background(), batch)
+			batch = batch[:0]
 		}
 		t.Reset(bp.config.FlushInterval)
 	}
@@ -62,20 +76,39 @@ func (bp *BatchProcessor[T]) process() {
 		case e, ok := <-bp.buffer:
 			if !ok {
 				// channel closed
-				if len(bp.batch) > 0 {
-					bp.flush(context.Background(), bp.batch)
-					bp.batch = bp.batch[:0]
+				if len(batch) > 0 {
+					bp.flush(context.Background(), batch)
 				}
 				t.Stop()
 				return
 			}
-			bp.batch = append(bp.batch, e)
-			if len(bp.batch) >= int(bp.config.BatchSize) {
+			batch = append(batch, e)
+			if len(batch) >= int(bp.config.BatchSize) {
 				flushAndReset()
 
 			}
 		case <-t.C:
 			flushAndReset()
+		case done := <-flushRequests:
+			// Take whatever is buffered right now, other consumers may be
+			// draining the same channel concurrently
+		drain:
+			for {
+				select {
+				case e, ok := <-bp.buffer:
+					if !ok {
+						break drain
+					}
+					batch = append(batch, e)
+					if len(batch) >= int(bp.config.BatchSize) {
+						flushAndReset()
+					}
+				default:
+					break drain
+				}
+			}
+			flushAndReset()
+			close(done)
 		}
 	}
 }
@@ -84,7 +117,15 @@ func (bp *BatchProcessor[T]) Size() int {
 	return len(bp.buffer)
 }
 
+// Buffer adds an item to the next batch. Items buffered after Close are
+// dropped.
 func (bp *BatchProcessor[T]) Buffer(t T) {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+	if bp.closed {
+		droppedMessages.WithLabelValues(bp.name).Inc()
+		return
+	}
 	if bp.drop {
 
 		select {
@@ -97,6 +138,39 @@ func (bp *BatchProcessor[T]) Buffer(t T) {
 	}
 }
 
+// Flush flushes everything buffered so far without waiting for the flush
+// interval. It blocks until every consumer has flushed or ctx is done.
+// After Close there is nothing left to flush and it returns immediately.
+func (bp *BatchProcessor[T]) Flush(ctx context.Context) error {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+	if bp.closed {
+		return nil
+	}
+	for _, requests := range bp.flushRequests {
+		done := make(chan struct{})
+		select {
+		case requests <- done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Close stops accepting new items and blocks until everything that was
+// buffered has been flushed.
 func (bp *BatchProcessor[T]) Close() {
-	close(bp.buffer)
+	bp.mu.Lock()
+	if !bp.closed {
+		bp.closed = true
+		close(bp.buffer)
+	}
+	bp.mu.Unlock()
+	bp.consumers.Wait()
 }
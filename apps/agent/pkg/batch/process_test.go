@@ -0,0 +1,131 @@
+package batch_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/batch"
+)
+
+func TestFlushesAllItemsWithMultipleConsumers(t *testing.T) {
+	flushed := atomic.Int64{}
+	bp := batch.New(batch.Config[int]{
+		BatchSize:     10,
+		BufferSize:    100,
+		FlushInterval: 10 * time.Millisecond,
+		Consumers:     4,
+		Flush: func(ctx context.Context, items []int) {
+			flushed.Add(int64(len(items)))
+		},
+	})
+
+	for i := 0; i < 1000; i++ {
+		bp.Buffer(i)
+	}
+	require.Eventually(t, func() bool {
+		return flushed.Load() == 1000
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDropDoesNotBlockWhenFlushIsStuck(t *testing.T) {
+	stuck := make(chan struct{})
+	defer close(stuck)
+	once := sync.Once{}
+	flushing := make(chan struct{})
+
+	bp := batch.New(batch.Config[int]{
+		Drop:          true,
+		BatchSize:     1,
+		BufferSize:    10,
+		FlushInterval: time.Second,
+		Flush: func(ctx context.Context, items []int) {
+			once.Do(func() { close(flushing) })
+			<-stuck
+		},
+	})
+
+	bp.Buffer(0)
+	<-flushing
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			bp.Buffer(i)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("buffering blocked while flush was stuck")
+	}
+}
+
+func TestCloseFlushesRemainingItems(t *testing.T) {
+	flushed := atomic.Int64{}
+	bp := batch.New(batch.Config[int]{
+		BatchSize:     1000,
+		BufferSize:    1000,
+		FlushInterval: time.Hour,
+		Consumers:     2,
+		Flush: func(ctx context.Context, items []int) {
+			time.Sleep(10 * time.Millisecond)
+			flushed.Add(int64(len(items)))
+		},
+	})
+
+	for i := 0; i < 100; i++ {
+		bp.Buffer(i)
+	}
+	bp.Close()
+	require.Equal(t, int64(100), flushed.Load())
+}
+
+func TestFlushDoesNotWaitForInterval(t *testing.T) {
+	flushed := atomic.Int64{}
+	bp := batch.New(batch.Config[int]{
+		BatchSize:     1000,
+		BufferSize:    1000,
+		FlushInterval: time.Hour,
+		Consumers:     3,
+		Flush: func(ctx context.Context, items []int) {
+			flushed.Add(int64(len(items)))
+		},
+	})
+	defer bp.Close()
+
+	for i := 0; i < 100; i++ {
+		bp.Buffer(i)
+	}
+	require.NoError(t, bp.Flush(context.Background()))
+	require.Equal(t, int64(100), flushed.Load())
+}
+
+func TestBufferAndFlushAfterClose(t *testing.T) {
+	flushed := atomic.Int64{}
+	bp := batch.New(batch.Config[int]{
+		BatchSize:     1000,
+		BufferSize:    1000,
+		FlushInterval: time.Hour,
+		Flush: func(ctx context.Context, items []int) {
+			flushed.Add(int64(len(items)))
+		},
+	})
+	bp.Buffer(1)
+	bp.Close()
+
+	// late handlers must not panic, their items are dropped
+	bp.Buffer(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, bp.Flush(ctx))
+	require.Equal(t, int64(1), flushed.Load())
+
+	bp.Close()
+}
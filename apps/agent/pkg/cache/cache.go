@@ -4,6 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/maypok86/otter"
@@ -19,15 +23,23 @@ import (
 )
 
 type cache[T any] struct {
-	otter             otter.Cache[string, swrEntry[T]]
-	fresh             time.Duration
-	stale             time.Duration
+	otter        otter.CacheWithVariableTTL[string, swrEntry[T]]
+	fresh        time.Duration
+	nullFresh    time.Duration
+	jitter       float64
+	stale        time.Duration
+	tombstoneTTL time.Duration
+	// Guards checking for a tombstone and writing an entry, sharded by key
+	locks             *[64]sync.Mutex
 	refreshFromOrigin func(ctx context.Context, identifier string) (data T, ok bool)
 	// If a key is stale, its identifier will be put into this channel and a goroutine refreshes it in the background
 	refreshC chan string
-	metrics  metrics.Metrics
-	logger   logging.Logger
-	resource string
+	// Identifiers that are currently queued or being refreshed, to avoid
+	// refreshing the same key multiple times concurrently
+	refreshing *sync.Map
+	metrics    metrics.Metrics
+	logger     logging.Logger
+	resource   string
 }
 
 type Config[T any] struct {
@@ -37,6 +49,7 @@ type Config[T any] struct {
 
 	// Subsequent requests that are not fresh but within the stale time will return cached data but also trigger
 	// fetching from the origin server
+	// After this time, the entry is discarded and will not be served anymore.
 	Stale time.Duration
 
 	// A handler that will be called to refetch data from the origin when necessary
@@ -46,8 +59,29 @@ type Config[T any] struct {
 	Metrics metrics.Metrics
 
 	// Start evicting the least recently used entry when the cache grows to MaxSize
+	// If a Weigher is set, MaxSize is the maximum total weight, rather than
+	// the number of entries.
 	MaxSize int
 
+	// How long a null entry, meaning the origin does not have the data, is considered fresh.
+	// This is usually shorter than Fresh, so newly created data becomes visible quickly.
+	// Defaults to Fresh.
+	NullFresh time.Duration
+
+	// Randomly shorten or extend the freshness of each entry by up to this factor,
+	// e.g. 0.1 for ±10%, so entries written together don't all expire together.
+	Jitter float64
+
+	// Optionally assign a weight to each entry, such as its approximate size in bytes,
+	// to bound the memory usage of the cache rather than just the number of entries.
+	// Defaults to 1 per entry.
+	Weigher func(key string, value T) uint32
+
+	// How long tombstones of deleted data are kept, to stop in-flight loads from
+	// writing the deleted data back into the cache. This must cover the slowest
+	// load from the origin and defaults to Stale.
+	TombstoneTTL time.Duration
+
 	Resource string
 }
 
@@ -58,19 +92,45 @@ func New[T any](config Config[T]) (*cache[T], error) {
 		return nil, fault.Wrap(err, fmsg.With("failed to create otter builder"))
 	}
 
+	weigher := config.Weigher
+	if weigher == nil {
+		weigher = func(key string, value T) uint32 {
+			return 1
+		}
+	}
+
 	otter, err := builder.CollectStats().Cost(func(key string, value swrEntry[T]) uint32 {
-		return 1
-	}).WithTTL(time.Hour).Build()
+		return weigher(key, value.Value)
+	}).WithVariableTTL().Build()
 	if err != nil {
 		return nil, fault.Wrap(err, fmsg.With("failed to create otter cache"))
 	}
 
+	nullFresh := config.NullFresh
+	if nullFresh <= 0 {
+		nullFresh = config.Fresh
+	}
+
+	if config.Metrics == nil {
+		config.Metrics = metrics.NewNoop()
+	}
+
+	tombstoneTTL := config.TombstoneTTL
+	if tombstoneTTL <= 0 {
+		tombstoneTTL = config.Stale
+	}
+
 	c := &cache[T]{
 		otter:             otter,
 		fresh:             config.Fresh,
+		nullFresh:         nullFresh,
+		jitter:            config.Jitter,
 		stale:             config.Stale,
+		tombstoneTTL:      tombstoneTTL,
+		locks:             &[64]sync.Mutex{},
 		refreshFromOrigin: config.RefreshFromOrigin,
 		refreshC:          make(chan string, 1000),
+		refreshing:        &sync.Map{},
 		logger:            config.Logger,
 		metrics:           config.Metrics,
 		resource:          config.Resource,
@@ -78,8 +138,16 @@ func New[T any](config Config[T]) (*cache[T], error) {
 
 	go c.runRefreshing()
 	repeat.Every(5*time.Second, func() {
+		stats := c.otter.Stats()
 		prometheus.CacheEntries.WithLabelValues(c.resource).Set(float64(c.otter.Size()))
-		prometheus.CacheRejected.WithLabelValues(c.resource).Set(float64(c.otter.Stats().EvictedCount()))
+		prometheus.CacheRejected.WithLabelValues(c.resource).Set(float64(stats.EvictedCount()))
+		c.metrics.Record(metrics.CacheSize{
+			Resource:      c.resource,
+			Entries:       c.otter.Size(),
+			Capacity:      c.otter.Capacity(),
+			Evicted:       stats.EvictedCount(),
+			EvictedWeight: stats.EvictedCost(),
+		})
 	})
 
 	return c, nil
@@ -97,13 +165,17 @@ func (c cache[T]) Get(ctx context.Context, key string) (value T, hit CacheHit) {
 
 	now := time.Now()
 
+	if e.reads != nil {
+		e.reads.Add(1)
+	}
+
 	if now.Before(e.Fresh) {
 
 		return e.Value, e.Hit
 
 	}
 	if now.Before(e.Stale) {
-		c.refreshC <- key
+		c.revalidate(key)
 
 		return e.Value, e.Hit
 	}
@@ -115,37 +187,108 @@ func (c cache[T]) Get(ctx context.Context, key string) (value T, hit CacheHit) {
 
 }
 
+func (c cache[T]) GetMany(ctx context.Context, keys []string) ([]T, []CacheHit) {
+	values := make([]T, len(keys))
+	hits := make([]CacheHit, len(keys))
+	for i, key := range keys {
+		values[i], hits[i] = c.Get(ctx, key)
+	}
+	return values, hits
+}
+
+func (c cache[T]) SetMany(ctx context.Context, values map[string]T) {
+	for key, value := range values {
+		c.set(ctx, key, c.fresh, value)
+	}
+}
+
 func (c cache[T]) SetNull(ctx context.Context, key string) {
-	c.set(ctx, key)
+	c.set(ctx, key, c.nullFresh)
 }
 
 func (c cache[T]) Set(ctx context.Context, key string, value T) {
-	c.set(ctx, key, value)
+	c.set(ctx, key, c.fresh, value)
 }
-func (c cache[T]) set(ctx context.Context, key string, value ...T) {
+
+func (c cache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	c.set(ctx, key, ttl, value)
+}
+
+// set stores the entry as fresh for the given duration, followed by the
+// configured stale period.
+// Nothing is written while the key has a tombstone.
+func (c cache[T]) set(ctx context.Context, key string, fresh time.Duration, value ...T) {
+	mu := c.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
 	now := time.Now()
 
+	if existing, ok := c.otter.Extension().GetQuietly(key); ok && existing.Tombstone && now.Before(existing.Stale) {
+		return
+	}
+
+	staleFor := c.stale - c.fresh
+	if staleFor < 0 {
+		staleFor = 0
+	}
+
+	fresh = jitter(fresh, c.jitter)
+
 	e := swrEntry[T]{
-		Value: value[0],
-		Fresh: now.Add(c.fresh),
-		Stale: now.Add(c.stale),
+		Fresh:   now.Add(fresh),
+		Stale:   now.Add(fresh + staleFor),
+		Written: now,
+		reads:   &atomic.Int64{},
 	}
 	if len(value) > 0 {
 		e.Value = value[0]
 		e.Hit = Hit
 	} else {
-		e.Hit = Miss
+		e.Hit = Null
 	}
-	c.otter.Set(key, e)
+	// Entries are never served after they become stale, so there is no point in
+	// keeping them around any longer
+	c.otter.Set(key, e, e.Stale.Sub(now))
 
 }
 
-func (c cache[T]) Remove(ctx context.Context, key string) {
+func (c cache[T]) Tombstone(ctx context.Context, key string) {
+	mu := c.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
 
-	c.otter.Delete(key)
+	now := time.Now()
+	c.otter.Set(key, swrEntry[T]{
+		Hit:       Null,
+		Tombstone: true,
+		Fresh:     now.Add(c.tombstoneTTL),
+		Stale:     now.Add(c.tombstoneTTL),
+		Written:   now,
+		reads:     &atomic.Int64{},
+	}, c.tombstoneTTL)
+}
+
+func (c cache[T]) lock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &c.locks[h.Sum32()%uint32(len(c.locks))]
+}
+
+func (c cache[T]) Remove(ctx context.Context, keys ...string) {
+
+	for _, key := range keys {
+		c.otter.Delete(key)
+	}
 
 }
 
+func (c cache[T]) RemoveByPrefix(ctx context.Context, prefix string) {
+	c.otter.DeleteByFunc(func(key string, _ swrEntry[T]) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
 func (c cache[T]) Dump(ctx context.Context) ([]byte, error) {
 	data := make(map[string]swrEntry[T])
 
@@ -167,12 +310,16 @@ func (c cache[T]) Restore(ctx context.Context, b []byte) error {
 	}
 	now := time.Now()
 	for key, entry := range data {
-		if now.Before(entry.Fresh) {
-			c.Set(ctx, key, entry.Value)
-		} else if now.Before(entry.Stale) {
-			c.refreshC <- key
+		// If the entry is older than stale, we don't restore it
+		if !now.Before(entry.Stale) {
+			continue
+		}
+		entry.reads = &atomic.Int64{}
+		// Entries keep their original lifetime, restoring must not make them fresh again
+		c.otter.Set(key, entry, entry.Stale.Sub(now))
+		if !now.Before(entry.Fresh) {
+			c.revalidate(key)
 		}
-		// If the entry is older than, we don't restore it
 	}
 	return nil
 }
@@ -181,21 +328,43 @@ func (c cache[T]) Clear(ctx context.Context) {
 	c.otter.Clear()
 }
 
+// revalidate queues the key to be refreshed from the origin in the background.
+// It never blocks the caller: if the key is already queued, or the queue is
+// full, the stale value is served as is.
+func (c cache[T]) revalidate(key string) {
+	if c.refreshFromOrigin == nil {
+		return
+	}
+	if _, alreadyRefreshing := c.refreshing.LoadOrStore(key, struct{}{}); alreadyRefreshing {
+		return
+	}
+	select {
+	case c.refreshC <- key:
+	default:
+		c.refreshing.Delete(key)
+		c.logger.Warn().Str("resource", c.resource).Str("cacheKey", key).Msg("refresh queue is full, dropping refresh")
+	}
+}
+
 func (c cache[T]) runRefreshing() {
 	for {
 		identifier := <-c.refreshC
-
-		ctx, span := tracing.Start(context.Background(), tracing.NewSpanName(fmt.Sprintf("cache.%s", c.resource), "refresh"))
-		span.SetAttributes(attribute.String("identifier", identifier))
-		t, ok := c.refreshFromOrigin(ctx, identifier)
-		if !ok {
-			span.AddEvent("identifier not found in origin")
-			c.logger.Warn().Str("identifier", identifier).Msg("origin couldn't find")
-			span.End()
-			continue
-		}
-		c.Set(ctx, identifier, t)
-		span.End()
+		c.refresh(identifier)
+		c.refreshing.Delete(identifier)
 	}
 
 }
+
+func (c cache[T]) refresh(identifier string) {
+	ctx, span := tracing.Start(context.Background(), tracing.NewSpanName(fmt.Sprintf("cache.%s", c.resource), "refresh"))
+	defer span.End()
+	span.SetAttributes(attribute.String("identifier", identifier))
+
+	t, ok := c.refreshFromOrigin(ctx, identifier)
+	if !ok {
+		span.AddEvent("identifier not found in origin")
+		c.logger.Warn().Str("identifier", identifier).Msg("origin couldn't find")
+		return
+	}
+	c.Set(ctx, identifier, t)
+}
@@ -2,12 +2,19 @@ package cache_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
+	vaultv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/vault/v1"
 	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
@@ -88,7 +95,6 @@ func TestRefresh(t *testing.T) {
 }
 
 func TestNull(t *testing.T) {
-	t.Skip()
 
 	c, err := cache.New[string](cache.Config[string]{
 		MaxSize: 10_000,
@@ -104,3 +110,426 @@ func TestNull(t *testing.T) {
 	require.Equal(t, cache.Null, hit)
 
 }
+
+func TestStaleWhileRevalidate(t *testing.T) {
+
+	refreshedFromOrigin := atomic.Int32{}
+	refreshed := make(chan struct{})
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+
+		Fresh: time.Second,
+		Stale: time.Minute,
+		RefreshFromOrigin: func(ctx context.Context, id string) (string, bool) {
+			refreshedFromOrigin.Add(1)
+			<-refreshed
+			return "new", true
+		},
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	c.Set(context.Background(), "key", "old")
+	time.Sleep(time.Second * 2)
+
+	// While the refresh is in flight, the stale value is served and no
+	// additional refreshes are triggered
+	for i := 0; i < 100; i++ {
+		value, hit := c.Get(context.Background(), "key")
+		require.Equal(t, cache.Hit, hit)
+		require.Equal(t, "old", value)
+	}
+	close(refreshed)
+
+	require.Eventually(t, func() bool {
+		value, _ := c.Get(context.Background(), "key")
+		return value == "new"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(1), refreshedFromOrigin.Load())
+}
+
+func TestWeigherBoundsSize(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 100,
+		Weigher: func(key string, value string) uint32 {
+			return uint32(len(value))
+		},
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		c.Set(context.Background(), fmt.Sprintf("key-%d", i), "0123456789")
+	}
+
+	// the condition runs on its own goroutine, so errors are returned instead of asserted there
+	var dumpErr error
+	require.Eventually(t, func() bool {
+		dump, err := c.Dump(context.Background())
+		if err != nil {
+			dumpErr = err
+			return true
+		}
+		entries := map[string]any{}
+		if err := json.Unmarshal(dump, &entries); err != nil {
+			dumpErr = err
+			return true
+		}
+		return len(entries) <= 10
+	}, 5*time.Second, 100*time.Millisecond)
+	require.NoError(t, dumpErr)
+}
+
+func TestProtoWeigher(t *testing.T) {
+	weigher := cache.ProtoWeigher[*vaultv1.DataEncryptionKey]()
+
+	small := &vaultv1.DataEncryptionKey{Id: "small", Key: make([]byte, 32)}
+	large := &vaultv1.DataEncryptionKey{Id: "large", Key: make([]byte, 1024)}
+
+	require.Equal(t, uint32(len("key")+proto.Size(small)), weigher("key", small))
+	require.Greater(t, weigher("key", large), weigher("key", small)+990)
+}
+
+func TestSetWithTTL(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+
+		Fresh: time.Minute,
+		Stale: time.Minute,
+		RefreshFromOrigin: func(ctx context.Context, id string) (string, bool) {
+			return "hello", true
+		},
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	c.SetWithTTL(context.Background(), "short", "value", time.Second)
+	c.Set(context.Background(), "long", "value")
+	time.Sleep(time.Second * 2)
+
+	_, hit := c.Get(context.Background(), "short")
+	require.Equal(t, cache.Miss, hit)
+	_, hit = c.Get(context.Background(), "long")
+	require.Equal(t, cache.Hit, hit)
+}
+
+func TestLoaderLoadsOnce(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	loader := cache.NewLoader[string](c)
+	loaded := atomic.Int32{}
+	load := func(ctx context.Context) (string, bool, error) {
+		loaded.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return "value", true, nil
+	}
+
+	type result struct {
+		value string
+		found bool
+		err   error
+	}
+	results := make(chan result, 100)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, found, err := loader.GetOrLoad(context.Background(), "key", load)
+			results <- result{value: value, found: found, err: err}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for r := range results {
+		require.NoError(t, r.err)
+		require.True(t, r.found)
+		require.Equal(t, "value", r.value)
+	}
+	require.Equal(t, int32(1), loaded.Load())
+}
+
+func TestNullExpiresIndependently(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize:   10_000,
+		Fresh:     time.Minute,
+		Stale:     time.Minute,
+		NullFresh: time.Second,
+		Logger:    logging.NewNoopLogger(),
+		Metrics:   metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	c.SetNull(context.Background(), "null")
+	c.Set(context.Background(), "key", "value")
+
+	_, hit := c.Get(context.Background(), "null")
+	require.Equal(t, cache.Null, hit)
+
+	time.Sleep(2 * time.Second)
+
+	_, hit = c.Get(context.Background(), "null")
+	require.Equal(t, cache.Miss, hit)
+	_, hit = c.Get(context.Background(), "key")
+	require.Equal(t, cache.Hit, hit)
+}
+
+func TestWarmup(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	keys := []string{}
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+	}
+	keys = append(keys, "missing")
+
+	err = cache.Warmup[string](context.Background(), c, keys, 10, func(ctx context.Context, key string) (string, bool, error) {
+		if key == "missing" {
+			return "", false, nil
+		}
+		return key, true, nil
+	})
+	require.NoError(t, err)
+
+	for _, key := range keys[:100] {
+		value, hit := c.Get(context.Background(), key)
+		require.Equal(t, cache.Hit, hit)
+		require.Equal(t, key, value)
+	}
+	_, hit := c.Get(context.Background(), "missing")
+	require.Equal(t, cache.Null, hit)
+}
+
+func TestRemoveByPrefix(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c.Set(ctx, "a-1", "value")
+	c.Set(ctx, "a-2", "value")
+	c.Set(ctx, "b-1", "value")
+	c.Set(ctx, "b-2", "value")
+
+	c.RemoveByPrefix(ctx, "a-")
+	_, hit := c.Get(ctx, "a-1")
+	require.Equal(t, cache.Miss, hit)
+	_, hit = c.Get(ctx, "a-2")
+	require.Equal(t, cache.Miss, hit)
+
+	c.Remove(ctx, "b-1", "b-2")
+	_, hit = c.Get(ctx, "b-1")
+	require.Equal(t, cache.Miss, hit)
+	_, hit = c.Get(ctx, "b-2")
+	require.Equal(t, cache.Miss, hit)
+}
+
+func TestPersist(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	newCache := func() cache.Cache[string] {
+		c, err := cache.New[string](cache.Config[string]{
+			MaxSize: 10_000,
+			Fresh:   time.Minute,
+			Stale:   time.Minute,
+			Logger:  logging.NewNoopLogger(),
+			Metrics: metrics.NewNoop(),
+		})
+		require.NoError(t, err)
+		return c
+	}
+
+	c := newCache()
+	stop, err := cache.Persist(ctx, c, cache.PersistenceConfig{Path: path, Interval: time.Hour, Plaintext: true, Logger: logging.NewNoopLogger()})
+	require.NoError(t, err)
+	c.Set(ctx, "key", "value")
+	c.SetWithTTL(ctx, "expired", "value", time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, stop())
+
+	restored := newCache()
+	stop, err = cache.Persist(ctx, restored, cache.PersistenceConfig{Path: path, Interval: time.Hour, Plaintext: true, Logger: logging.NewNoopLogger()})
+	require.NoError(t, err)
+	defer stop()
+
+	value, hit := restored.Get(ctx, "key")
+	require.Equal(t, cache.Hit, hit)
+	require.Equal(t, "value", value)
+	_, hit = restored.Get(ctx, "expired")
+	require.Equal(t, cache.Miss, hit)
+}
+
+func TestPersistSealsSnapshots(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	// secrets must never be written in plaintext by accident
+	_, err = cache.Persist(ctx, c, cache.PersistenceConfig{Path: path, Logger: logging.NewNoopLogger()})
+	require.Error(t, err)
+
+	reverse := func(b []byte) ([]byte, error) {
+		out := make([]byte, len(b))
+		for i := range b {
+			out[len(b)-1-i] = b[i]
+		}
+		return out, nil
+	}
+	stop, err := cache.Persist(ctx, c, cache.PersistenceConfig{Path: path, Interval: time.Hour, Seal: reverse, Open: reverse, Logger: logging.NewNoopLogger()})
+	require.NoError(t, err)
+	c.Set(ctx, "key", "secret")
+	require.NoError(t, stop())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "secret")
+
+	c.Clear(ctx)
+	stop, err = cache.Persist(ctx, c, cache.PersistenceConfig{Path: path, Interval: time.Hour, Seal: reverse, Open: reverse, Logger: logging.NewNoopLogger()})
+	require.NoError(t, err)
+	defer stop()
+	value, hit := c.Get(ctx, "key")
+	require.Equal(t, cache.Hit, hit)
+	require.Equal(t, "secret", value)
+}
+
+func TestInspect(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize:  10_000,
+		Fresh:    time.Minute,
+		Stale:    time.Minute,
+		Logger:   logging.NewNoopLogger(),
+		Metrics:  metrics.NewNoop(),
+		Resource: "test",
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	before := time.Now()
+	c.Set(ctx, "cold", "value")
+	c.Set(ctx, "warm", "value")
+	c.Set(ctx, "hot", "value")
+	for i := 0; i < 3; i++ {
+		c.Get(ctx, "hot")
+	}
+	c.Get(ctx, "warm")
+	c.Get(ctx, "missing")
+
+	summary := c.Inspect(ctx, 2)
+	require.Equal(t, "test", summary.Resource)
+	require.Equal(t, 3, summary.Entries)
+	require.InDelta(t, 0.8, summary.HitRatio, 0.001)
+	require.False(t, summary.Oldest.Before(before))
+	require.Equal(t, []cache.HotKey{
+		{Id: cache.HashKey("hot"), Reads: 3},
+		{Id: cache.HashKey("warm"), Reads: 1},
+	}, summary.Hottest)
+}
+
+func TestTombstoneBlocksInFlightLoad(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	loader := cache.NewLoader[string](c)
+
+	loadErrC := make(chan error, 1)
+	go func() {
+		_, _, loadErr := loader.GetOrLoad(ctx, "key", func(ctx context.Context) (string, bool, error) {
+			close(loading)
+			<-release
+			// The origin returned the key right before it was deleted
+			return "deleted", true, nil
+		})
+		loadErrC <- loadErr
+	}()
+
+	<-loading
+	c.Tombstone(ctx, "key")
+	close(release)
+	require.NoError(t, <-loadErrC)
+
+	_, hit := c.Get(ctx, "key")
+	require.Equal(t, cache.Null, hit)
+
+	c.Set(ctx, "key", "deleted")
+	_, hit = c.Get(ctx, "key")
+	require.Equal(t, cache.Null, hit)
+}
+
+func TestTombstoneExpires(t *testing.T) {
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize:      10_000,
+		Fresh:        time.Minute,
+		Stale:        time.Minute,
+		TombstoneTTL: 100 * time.Millisecond,
+		Logger:       logging.NewNoopLogger(),
+		Metrics:      metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c.Tombstone(ctx, "key")
+	c.Set(ctx, "key", "value")
+	_, hit := c.Get(ctx, "key")
+	require.Equal(t, cache.Null, hit)
+
+	time.Sleep(200 * time.Millisecond)
+
+	c.Set(ctx, "key", "value")
+	value, hit := c.Get(ctx, "key")
+	require.Equal(t, cache.Hit, hit)
+	require.Equal(t, "value", value)
+}
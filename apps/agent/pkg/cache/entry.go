@@ -2,6 +2,7 @@ package cache
 
 import (
 	"container/list"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +14,13 @@ type swrEntry[T any] struct {
 	Fresh time.Time `json:"fresh"`
 	// Before this time, the entry should be revalidated
 	// After this time, the entry must be discarded
-	Stale      time.Time     `json:"stale"`
+	Stale time.Time `json:"stale"`
+	// Tombstones mark deleted data, writes to the key are ignored until the tombstone expires
+	Tombstone bool `json:"tombstone,omitempty"`
+	// When the entry was written, to find the oldest entries when debugging
+	Written    time.Time     `json:"written"`
 	LruElement *list.Element `json:"-"`
+
+	// How often the entry has been read, shared between all copies of the entry
+	reads *atomic.Int64
 }
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// Summary describes the contents of a cache, to debug staleness issues in production.
+type Summary struct {
+	Resource string
+	Entries  int
+	// Ratio of hits to all lookups since the cache was created
+	HitRatio float64
+	// When the oldest entry was written, zero if the cache is empty
+	Oldest time.Time
+	// The most read entries, ordered by reads in descending order
+	Hottest []HotKey
+}
+
+type HotKey struct {
+	// The hashed key, so summaries can be shared without leaking identifiers
+	Id    string
+	Reads int64
+}
+
+// Inspector is implemented by caches that can report on their contents.
+type Inspector interface {
+	// Inspect summarizes the cache, including up to topN of the hottest keys.
+	Inspect(ctx context.Context, topN int) Summary
+
+	// Removes the keys from the cache.
+	Remove(ctx context.Context, keys ...string)
+
+	// Removes all keys with the given prefix, an empty prefix removes everything.
+	RemoveByPrefix(ctx context.Context, prefix string)
+
+	// Tombstone marks the key as deleted in the origin, so loads that are
+	// still in flight can't write it back.
+	Tombstone(ctx context.Context, key string)
+
+	// Clear removes all entries from the cache.
+	Clear(ctx context.Context)
+}
+
+// HashKey returns the id under which a key is reported in a Summary.
+func HashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:8])
+}
+
+func (c cache[T]) Inspect(ctx context.Context, topN int) Summary {
+	summary := Summary{
+		Resource: c.resource,
+		HitRatio: c.otter.Stats().Ratio(),
+		Hottest:  []HotKey{},
+	}
+
+	c.otter.Range(func(key string, entry swrEntry[T]) bool {
+		summary.Entries++
+		// Entries restored from older snapshots don't know when they were written
+		if !entry.Written.IsZero() && (summary.Oldest.IsZero() || entry.Written.Before(summary.Oldest)) {
+			summary.Oldest = entry.Written
+		}
+		var reads int64
+		if entry.reads != nil {
+			reads = entry.reads.Load()
+		}
+		summary.Hottest = append(summary.Hottest, HotKey{Id: HashKey(key), Reads: reads})
+		return true
+	})
+
+	sort.Slice(summary.Hottest, func(i, j int) bool {
+		return summary.Hottest[i].Reads > summary.Hottest[j].Reads
+	})
+	if topN < 0 {
+		topN = 0
+	}
+	if len(summary.Hottest) > topN {
+		summary.Hottest = summary.Hottest[:topN]
+	}
+	return summary
+}
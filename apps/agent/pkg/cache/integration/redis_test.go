@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+	"github.com/unkeyed/unkey/apps/agent/pkg/testutils/containers"
+)
+
+func TestRedisSharedAcrossNodes(t *testing.T) {
+	ctx := context.Background()
+	r := containers.NewRedis(t)
+	defer r.Stop()
+
+	newNode := func() cache.Cache[string] {
+		l1, err := cache.New[string](cache.Config[string]{
+			MaxSize: 10_000,
+			Fresh:   time.Minute,
+			Stale:   time.Minute * 5,
+			RefreshFromOrigin: func(ctx context.Context, id string) (string, bool) {
+				return "", false
+			},
+			Logger:  logging.NewNoopLogger(),
+			Metrics: metrics.NewNoop(),
+		})
+		require.NoError(t, err)
+		l2, err := cache.NewRedis[string](cache.RedisConfig{
+			Client:   r.Client,
+			TTL:      time.Minute,
+			Logger:   logging.NewNoopLogger(),
+			Resource: "test",
+		})
+		require.NoError(t, err)
+		return cache.NewTiered(l1, l2)
+	}
+
+	a := newNode()
+	b := newNode()
+
+	a.Set(ctx, "key", "value")
+	value, hit := b.Get(ctx, "key")
+	require.Equal(t, cache.Hit, hit)
+	require.Equal(t, "value", value)
+
+	a.SetNull(ctx, "null")
+	_, hit = b.Get(ctx, "null")
+	require.Equal(t, cache.Null, hit)
+
+	a.Clear(ctx)
+	_, hit = newNode().Get(ctx, "key")
+	require.Equal(t, cache.Miss, hit)
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 )
 
 type Cache[T any] interface {
@@ -9,14 +10,35 @@ type Cache[T any] interface {
 	// If the key is not found, found will be false.
 	Get(ctx context.Context, key string) (value T, hit CacheHit)
 
+	// GetMany returns the values for the given keys in a single operation.
+	// values and hits have the same length and order as keys.
+	GetMany(ctx context.Context, keys []string) (values []T, hits []CacheHit)
+
 	// Sets the value for the given key.
 	Set(ctx context.Context, key string, value T)
 
+	// SetMany sets all given values in a single operation.
+	SetMany(ctx context.Context, values map[string]T)
+
+	// SetWithTTL sets the value for the given key, overriding the default
+	// lifetime of the cache for this entry only.
+	SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration)
+
 	// Sets the given key to null, indicating that the value does not exist in the origin.
 	SetNull(ctx context.Context, key string)
 
-	// Removes the key from the cache.
-	Remove(ctx context.Context, key string)
+	// Tombstone marks the key as deleted in the origin. Get reports it as Null
+	// and, where supported, writes to the key are ignored until the tombstone
+	// expires, so loads that started before the deletion can't write the deleted
+	// data back.
+	Tombstone(ctx context.Context, key string)
+
+	// Removes the keys from the cache.
+	Remove(ctx context.Context, keys ...string)
+
+	// RemoveByPrefix removes all keys starting with the given prefix, for example
+	// all entries belonging to the same keyring.
+	RemoveByPrefix(ctx context.Context, prefix string)
 
 	// Dump returns a serialized representation of the cache.
 	Dump(ctx context.Context) ([]byte, error)
@@ -39,3 +61,16 @@ const (
 	// The entry did not exist in the origin
 
 )
+
+func (h CacheHit) String() string {
+	switch h {
+	case Null:
+		return "null"
+	case Hit:
+		return "hit"
+	case Miss:
+		return "miss"
+	default:
+		return "unknown"
+	}
+}
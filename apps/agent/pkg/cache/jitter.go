@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// jitter randomly shortens or extends d by up to factor, e.g. a factor of 0.1
+// returns a duration between 0.9*d and 1.1*d.
+//
+// Entries that are written at the same time, for example during a warmup,
+// would otherwise all expire at the same time and stampede the origin.
+func jitter(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 || d <= 0 {
+		return d
+	}
+	if factor > 1 {
+		factor = 1
+	}
+	return time.Duration(float64(d) * (1 + factor*(2*rand.Float64()-1)))
+}
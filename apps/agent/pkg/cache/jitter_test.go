@@ -0,0 +1,22 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJitterStaysWithinBounds(t *testing.T) {
+	d := time.Minute
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 1000; i++ {
+		j := jitter(d, 0.1)
+		require.GreaterOrEqual(t, j, 54*time.Second)
+		require.LessOrEqual(t, j, 66*time.Second)
+		seen[j] = true
+	}
+	require.Greater(t, len(seen), 1)
+
+	require.Equal(t, d, jitter(d, 0))
+}
@@ -0,0 +1,523 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+const (
+	// memcached rejects keys longer than this
+	memcachedMaxKeyLength = 250
+	// expiration times longer than 30 days are interpreted as unix timestamps
+	memcachedMaxRelativeExpiration = 30 * 24 * time.Hour
+
+	memcachedFlagValue     = 0
+	memcachedFlagNull      = 1
+	memcachedFlagTombstone = 2
+)
+
+type memcachedCache[T any] struct {
+	addr         string
+	pool         chan *memcachedConn
+	timeout      time.Duration
+	ttl          time.Duration
+	nullTTL      time.Duration
+	tombstoneTTL time.Duration
+	jitter       float64
+	serializer   Serializer[T]
+	prefix       string
+	// Holds the current generation of the resource, see generation
+	generationKey string
+	logger        logging.Logger
+}
+
+type MemcachedConfig[T any] struct {
+	// host:port of the memcached server
+	Addr string
+
+	// How long entries are kept in memcached
+	TTL time.Duration
+
+	// How long null entries are kept in memcached, defaults to TTL
+	NullTTL time.Duration
+
+	// How long tombstones are kept in memcached, defaults to TTL
+	TombstoneTTL time.Duration
+
+	// Randomly shorten or extend the ttl of each entry by up to this factor,
+	// e.g. 0.1 for ±10%, so entries written together don't all expire together.
+	Jitter float64
+
+	// How values are encoded, defaults to json
+	Serializer Serializer[T]
+
+	// Maximum number of idle connections kept open, defaults to 10
+	MaxIdleConns int
+
+	// Timeout for each operation, defaults to 1 second
+	Timeout time.Duration
+
+	Logger logging.Logger
+
+	// Keys are prefixed with the resource, so multiple caches can share the same memcached server
+	Resource string
+}
+
+// NewMemcached creates a cache backed by memcached, which can be shared across nodes.
+//
+// memcached can not list or selectively delete keys, therefore Dump is not
+// supported. Keys are namespaced by a generation of the resource instead, both
+// RemoveByPrefix and Clear start a new generation, which invalidates every entry
+// of this resource but leaves other resources sharing the server untouched.
+// Tombstones are reported as Null, but do not stop later writes to the key.
+func NewMemcached[T any](config MemcachedConfig[T]) (*memcachedCache[T], error) {
+	if config.Addr == "" {
+		return nil, fault.New("memcached address is required")
+	}
+	if config.Resource == "" {
+		return nil, fault.New("resource is required")
+	}
+	if config.TTL <= 0 {
+		return nil, fault.New("ttl must be greater than 0")
+	}
+	if config.NullTTL <= 0 {
+		config.NullTTL = config.TTL
+	}
+	if config.TombstoneTTL <= 0 {
+		config.TombstoneTTL = config.TTL
+	}
+	if config.Serializer == nil {
+		config.Serializer = NewJSONSerializer[T]()
+	}
+	if config.MaxIdleConns <= 0 {
+		config.MaxIdleConns = 10
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Second
+	}
+
+	// Every operation fails while the server is down, so warnings are sampled
+	logger := logging.Sampled(config.Logger.With().Str("resource", config.Resource).Logger(), 10, time.Second)
+
+	return &memcachedCache[T]{
+		addr:         config.Addr,
+		pool:         make(chan *memcachedConn, config.MaxIdleConns),
+		timeout:      config.Timeout,
+		ttl:          config.TTL,
+		nullTTL:      config.NullTTL,
+		tombstoneTTL: config.TombstoneTTL,
+		jitter:       config.Jitter,
+		serializer:   config.Serializer,
+		prefix:       fmt.Sprintf("cache:%s:", config.Resource),
+		// not a valid generation, so it never collides with a key
+		generationKey: fmt.Sprintf("cache:%s:generation", config.Resource),
+		logger:        logger,
+	}, nil
+}
+
+func (c *memcachedCache[T]) Get(ctx context.Context, key string) (value T, hit CacheHit) {
+	values, hits := c.GetMany(ctx, []string{key})
+	return values[0], hits[0]
+}
+
+func (c *memcachedCache[T]) GetMany(ctx context.Context, keys []string) ([]T, []CacheHit) {
+	values := make([]T, len(keys))
+	hits := make([]CacheHit, len(keys))
+	for i := range hits {
+		hits[i] = Miss
+	}
+	if len(keys) == 0 {
+		return values, hits
+	}
+
+	memcachedKeys := make([]string, len(keys))
+	var items map[string]memcachedItem
+	err := c.do(ctx, func(conn *memcachedConn) error {
+		generation, err := c.generation(conn)
+		if err != nil {
+			return err
+		}
+		for i, key := range keys {
+			memcachedKeys[i] = c.key(generation, key)
+		}
+		items, err = conn.get(memcachedKeys)
+		return err
+	})
+	if err != nil {
+		c.logger.Warn().Err(err).Int("keys", len(keys)).Msg("failed to get from memcached")
+		return values, hits
+	}
+
+	for i, memcachedKey := range memcachedKeys {
+		item, ok := items[memcachedKey]
+		if !ok {
+			continue
+		}
+		if item.flags == memcachedFlagNull || item.flags == memcachedFlagTombstone {
+			hits[i] = Null
+			continue
+		}
+		v, err := c.serializer.Unmarshal(item.data)
+		if err != nil {
+			c.logger.Warn().Err(err).Str("cacheKey", keys[i]).Msg("failed to unmarshal memcached entry")
+			continue
+		}
+		values[i], hits[i] = v, Hit
+	}
+	return values, hits
+}
+
+func (c *memcachedCache[T]) Set(ctx context.Context, key string, value T) {
+	c.SetWithTTL(ctx, key, value, c.ttl)
+}
+
+func (c *memcachedCache[T]) SetMany(ctx context.Context, values map[string]T) {
+	for key, value := range values {
+		c.SetWithTTL(ctx, key, value, c.ttl)
+	}
+}
+
+func (c *memcachedCache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	b, err := c.serializer.Marshal(value)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("cacheKey", key).Msg("failed to marshal memcached entry")
+		return
+	}
+	c.set(ctx, key, memcachedFlagValue, b, ttl)
+}
+
+func (c *memcachedCache[T]) SetNull(ctx context.Context, key string) {
+	c.set(ctx, key, memcachedFlagNull, []byte{}, c.nullTTL)
+}
+
+func (c *memcachedCache[T]) Tombstone(ctx context.Context, key string) {
+	c.set(ctx, key, memcachedFlagTombstone, []byte{}, c.tombstoneTTL)
+}
+
+func (c *memcachedCache[T]) set(ctx context.Context, key string, flags int, data []byte, ttl time.Duration) {
+	err := c.do(ctx, func(conn *memcachedConn) error {
+		generation, err := c.generation(conn)
+		if err != nil {
+			return err
+		}
+		return conn.set(c.key(generation, key), flags, expiration(jitter(ttl, c.jitter)), data)
+	})
+	if err != nil {
+		c.logger.Warn().Err(err).Str("cacheKey", key).Msg("failed to set in memcached")
+	}
+}
+
+func (c *memcachedCache[T]) Remove(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	err := c.do(ctx, func(conn *memcachedConn) error {
+		generation, err := c.generation(conn)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			err = conn.delete(c.key(generation, key))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Warn().Err(err).Strs("cacheKeys", keys).Msg("failed to remove from memcached")
+	}
+}
+
+// RemoveByPrefix removes all entries of this resource, because memcached can
+// not list keys. Removing too much is safe, serving entries that should have
+// been removed is not.
+func (c *memcachedCache[T]) RemoveByPrefix(ctx context.Context, prefix string) {
+	c.logger.Warn().Str("prefix", prefix).Msg("memcached does not support removing by prefix, removing all entries of the resource")
+	c.Clear(ctx)
+}
+
+func (c *memcachedCache[T]) Dump(ctx context.Context) ([]byte, error) {
+	return nil, fault.New("memcached does not support listing keys")
+}
+
+func (c *memcachedCache[T]) Restore(ctx context.Context, b []byte) error {
+	data := make(map[string]storedEntry[T])
+	err := json.Unmarshal(b, &data)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal cache data: %w", err)
+	}
+	for key, entry := range data {
+		if entry.Hit == Null {
+			c.SetNull(ctx, key)
+		} else {
+			c.Set(ctx, key, entry.Value)
+		}
+	}
+	return nil
+}
+
+// Clear removes all entries of this resource by starting a new generation.
+// The old entries are never read again and expire on their own.
+func (c *memcachedCache[T]) Clear(ctx context.Context) {
+	err := c.do(ctx, func(conn *memcachedConn) error {
+		// Without a generation, the next operation starts a new one anyway
+		_, err := conn.incr(c.generationKey)
+		return err
+	})
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("failed to clear memcached")
+	}
+}
+
+// generation returns the current generation of the resource, every key is
+// namespaced by it.
+// If memcached evicted the generation, a new one is started from the current
+// time, which is always ahead of every generation used before, so old entries
+// can't become visible again.
+func (c *memcachedCache[T]) generation(conn *memcachedConn) (string, error) {
+	items, err := conn.get([]string{c.generationKey})
+	if err != nil {
+		return "", err
+	}
+	if item, ok := items[c.generationKey]; ok {
+		return string(item.data), nil
+	}
+
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	stored, err := conn.add(c.generationKey, generation)
+	if err != nil {
+		return "", err
+	}
+	if stored {
+		return generation, nil
+	}
+	// Another node started one first
+	items, err = conn.get([]string{c.generationKey})
+	if err != nil {
+		return "", err
+	}
+	item, ok := items[c.generationKey]
+	if !ok {
+		return "", fault.New("memcached generation disappeared")
+	}
+	return string(item.data), nil
+}
+
+// key returns a valid memcached key. Keys that are too long or contain
+// whitespace or control characters are hashed.
+func (c *memcachedCache[T]) key(generation string, key string) string {
+	prefix := c.prefix + generation + ":"
+	k := prefix + key
+	if len(k) <= memcachedMaxKeyLength && !strings.ContainsFunc(k, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return k
+	}
+	h := sha256.Sum256([]byte(key))
+	return prefix + "sha256:" + hex.EncodeToString(h[:])
+}
+
+// expiration converts a ttl to memcached's expiration time in seconds.
+func expiration(ttl time.Duration) int {
+	if ttl > memcachedMaxRelativeExpiration {
+		ttl = memcachedMaxRelativeExpiration
+	}
+	// 0 means never expire, so we round up
+	return int(math.Ceil(ttl.Seconds()))
+}
+
+// do runs fn on a pooled connection.
+// Connections are closed instead of returned to the pool after an error,
+// because we don't know what state the protocol is in.
+func (c *memcachedCache[T]) do(ctx context.Context, fn func(conn *memcachedConn) error) error {
+	var conn *memcachedConn
+	select {
+	case conn = <-c.pool:
+	default:
+		dialer := net.Dialer{Timeout: c.timeout}
+		nc, err := dialer.DialContext(ctx, "tcp", c.addr)
+		if err != nil {
+			return fault.Wrap(err, fmsg.With("failed to connect to memcached"))
+		}
+		conn = &memcachedConn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	err := conn.nc.SetDeadline(deadline)
+	if err == nil {
+		err = fn(conn)
+	}
+	if err != nil {
+		conn.nc.Close()
+		return err
+	}
+
+	select {
+	case c.pool <- conn:
+	default:
+		conn.nc.Close()
+	}
+	return nil
+}
+
+type memcachedItem struct {
+	flags int
+	data  []byte
+}
+
+// memcachedConn implements the subset of memcached's text protocol we need.
+// See https://github.com/memcached/memcached/blob/master/doc/protocol.txt
+type memcachedConn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+func (c *memcachedConn) command(format string, args ...any) error {
+	_, err := fmt.Fprintf(c.rw, format, args...)
+	if err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+func (c *memcachedConn) readLine() (string, error) {
+	line, err := c.rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// expect reads a single line response and returns an error if it's not one of the expected ones
+func (c *memcachedConn) expect(expected ...string) error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	for _, e := range expected {
+		if line == e {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected memcached response: %s", line)
+}
+
+func (c *memcachedConn) get(keys []string) (map[string]memcachedItem, error) {
+	err := c.command("get %s\r\n", strings.Join(keys, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]memcachedItem)
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END" {
+			return items, nil
+		}
+
+		// VALUE <key> <flags> <bytes>
+		parts := strings.Fields(line)
+		if len(parts) < 4 || parts[0] != "VALUE" {
+			return nil, fmt.Errorf("unexpected memcached response: %s", line)
+		}
+		flags, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid flags in memcached response: %s", line)
+		}
+		size, err := strconv.Atoi(parts[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid size in memcached response: %s", line)
+		}
+
+		// data is followed by \r\n
+		data := make([]byte, size+2)
+		_, err = io.ReadFull(c.rw, data)
+		if err != nil {
+			return nil, err
+		}
+		items[parts[1]] = memcachedItem{flags: flags, data: data[:size]}
+	}
+}
+
+func (c *memcachedConn) set(key string, flags int, exptime int, data []byte) error {
+	_, err := fmt.Fprintf(c.rw, "set %s %d %d %d\r\n", key, flags, exptime, len(data))
+	if err != nil {
+		return err
+	}
+	_, err = c.rw.Write(data)
+	if err != nil {
+		return err
+	}
+	err = c.command("\r\n")
+	if err != nil {
+		return err
+	}
+	return c.expect("STORED")
+}
+
+func (c *memcachedConn) delete(key string) error {
+	err := c.command("delete %s\r\n", key)
+	if err != nil {
+		return err
+	}
+	return c.expect("DELETED", "NOT_FOUND")
+}
+
+// add stores the value only if the key does not exist yet, it never expires.
+func (c *memcachedConn) add(key string, value string) (bool, error) {
+	err := c.command("add %s 0 0 %d\r\n%s\r\n", key, len(value), value)
+	if err != nil {
+		return false, err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return false, err
+	}
+	switch line {
+	case "STORED":
+		return true, nil
+	case "NOT_STORED":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected memcached response: %s", line)
+	}
+}
+
+// incr increments a numeric value by one, it returns false if the key does not exist.
+func (c *memcachedConn) incr(key string) (bool, error) {
+	err := c.command("incr %s 1\r\n", key)
+	if err != nil {
+		return false, err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return false, err
+	}
+	if line == "NOT_FOUND" {
+		return false, nil
+	}
+	_, err = strconv.ParseUint(line, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("unexpected memcached response: %s", line)
+	}
+	return true, nil
+}
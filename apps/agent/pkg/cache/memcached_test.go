@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	vaultv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/vault/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeMemcached implements just enough of the text protocol to test the client.
+// Expiration is ignored.
+type fakeMemcached struct {
+	mu    sync.Mutex
+	items map[string]memcachedItem
+}
+
+func startFakeMemcached(t *testing.T) (string, *fakeMemcached) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeMemcached{items: map[string]memcachedItem{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return ln.Addr().String(), f
+}
+
+func (f *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		f.mu.Lock()
+		switch parts[0] {
+		case "get":
+			for _, key := range parts[1:] {
+				if item, ok := f.items[key]; ok {
+					fmt.Fprintf(rw, "VALUE %s %d %d\r\n%s\r\n", key, item.flags, len(item.data), item.data)
+				}
+			}
+			fmt.Fprint(rw, "END\r\n")
+		case "set":
+			flags, _ := strconv.Atoi(parts[2])
+			size, _ := strconv.Atoi(parts[4])
+			data := make([]byte, size+2)
+			_, err = io.ReadFull(rw, data)
+			if err == nil {
+				f.items[parts[1]] = memcachedItem{flags: flags, data: data[:size]}
+				fmt.Fprint(rw, "STORED\r\n")
+			}
+		case "delete":
+			if _, ok := f.items[parts[1]]; ok {
+				delete(f.items, parts[1])
+				fmt.Fprint(rw, "DELETED\r\n")
+			} else {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			}
+		case "add":
+			size, _ := strconv.Atoi(parts[4])
+			data := make([]byte, size+2)
+			_, err = io.ReadFull(rw, data)
+			if err == nil {
+				if _, ok := f.items[parts[1]]; ok {
+					fmt.Fprint(rw, "NOT_STORED\r\n")
+				} else {
+					f.items[parts[1]] = memcachedItem{data: data[:size]}
+					fmt.Fprint(rw, "STORED\r\n")
+				}
+			}
+		case "incr":
+			item, ok := f.items[parts[1]]
+			if !ok {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+				break
+			}
+			n, _ := strconv.ParseUint(string(item.data), 10, 64)
+			item.data = []byte(strconv.FormatUint(n+1, 10))
+			f.items[parts[1]] = item
+			fmt.Fprintf(rw, "%s\r\n", item.data)
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		f.mu.Unlock()
+		if err != nil || rw.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeMemcached) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := []string{}
+	for key := range f.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestMemcachedJSON(t *testing.T) {
+	ctx := context.Background()
+	addr, _ := startFakeMemcached(t)
+
+	c, err := NewMemcached(MemcachedConfig[map[string]string]{
+		Addr:     addr,
+		TTL:      time.Minute,
+		Logger:   logging.NewNoopLogger(),
+		Resource: "test",
+	})
+	require.NoError(t, err)
+
+	c.Set(ctx, "key", map[string]string{"hello": "world"})
+	c.SetNull(ctx, "null")
+
+	value, hit := c.Get(ctx, "key")
+	require.Equal(t, Hit, hit)
+	require.Equal(t, "world", value["hello"])
+
+	_, hit = c.Get(ctx, "null")
+	require.Equal(t, Null, hit)
+
+	values, hits := c.GetMany(ctx, []string{"null", "missing", "key"})
+	require.Equal(t, []CacheHit{Null, Miss, Hit}, hits)
+	require.Equal(t, "world", values[2]["hello"])
+
+	c.Remove(ctx, "key", "missing")
+	_, hit = c.Get(ctx, "key")
+	require.Equal(t, Miss, hit)
+
+	c.Tombstone(ctx, "deleted")
+	_, hit = c.Get(ctx, "deleted")
+	require.Equal(t, Null, hit)
+
+	c.Clear(ctx)
+	_, hit = c.Get(ctx, "null")
+	require.Equal(t, Miss, hit)
+}
+
+func TestMemcachedProto(t *testing.T) {
+	ctx := context.Background()
+	addr, _ := startFakeMemcached(t)
+
+	c, err := NewMemcached(MemcachedConfig[*vaultv1.DataEncryptionKey]{
+		Addr:       addr,
+		TTL:        time.Minute,
+		Serializer: NewProtoSerializer(func() *vaultv1.DataEncryptionKey { return &vaultv1.DataEncryptionKey{} }),
+		Logger:     logging.NewNoopLogger(),
+		Resource:   "dek",
+	})
+	require.NoError(t, err)
+
+	dek := &vaultv1.DataEncryptionKey{Id: "dek_1", CreatedAt: 1, Key: []byte("secret")}
+	c.Set(ctx, "dek_1", dek)
+
+	value, hit := c.Get(ctx, "dek_1")
+	require.Equal(t, Hit, hit)
+	require.True(t, proto.Equal(dek, value))
+}
+
+func TestMemcachedHashesInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	addr, server := startFakeMemcached(t)
+
+	c, err := NewMemcached(MemcachedConfig[string]{
+		Addr:     addr,
+		TTL:      time.Minute,
+		Logger:   logging.NewNoopLogger(),
+		Resource: "test",
+	})
+	require.NoError(t, err)
+
+	for _, key := range []string{"with space", "with\nnewline", strings.Repeat("a", 300)} {
+		c.Set(ctx, key, key)
+		value, hit := c.Get(ctx, key)
+		require.Equal(t, Hit, hit)
+		require.Equal(t, key, value)
+	}
+
+	for _, key := range server.keys() {
+		if key == c.generationKey {
+			continue
+		}
+		require.LessOrEqual(t, len(key), memcachedMaxKeyLength)
+		require.NotContains(t, key, " ")
+		require.Contains(t, key, ":sha256:")
+	}
+}
+
+func TestMemcachedClearOnlyRemovesItsResource(t *testing.T) {
+	ctx := context.Background()
+	addr, server := startFakeMemcached(t)
+
+	newCache := func(resource string) *memcachedCache[string] {
+		c, err := NewMemcached(MemcachedConfig[string]{
+			Addr:     addr,
+			TTL:      time.Minute,
+			Logger:   logging.NewNoopLogger(),
+			Resource: resource,
+		})
+		require.NoError(t, err)
+		return c
+	}
+	a, b := newCache("a"), newCache("b")
+	a.Set(ctx, "key", "a")
+	b.Set(ctx, "key", "b")
+
+	a.RemoveByPrefix(ctx, "k")
+	_, hit := a.Get(ctx, "key")
+	require.Equal(t, Miss, hit)
+	value, hit := b.Get(ctx, "key")
+	require.Equal(t, Hit, hit)
+	require.Equal(t, "b", value)
+
+	// another node sharing the resource sees the removal too
+	_, hit = newCache("a").Get(ctx, "key")
+	require.Equal(t, Miss, hit)
+
+	// an evicted generation must not bring back removed entries
+	a.Set(ctx, "key", "a")
+	a.Clear(ctx)
+	server.mu.Lock()
+	delete(server.items, a.generationKey)
+	server.mu.Unlock()
+	_, hit = a.Get(ctx, "key")
+	require.Equal(t, Miss, hit)
+}
+
+func TestMemcachedUnavailableIsMiss(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewMemcached(MemcachedConfig[string]{
+		// Nothing is listening on this port
+		Addr:     "127.0.0.1:1",
+		TTL:      time.Minute,
+		Timeout:  100 * time.Millisecond,
+		Logger:   logging.NewNoopLogger(),
+		Resource: "test",
+	})
+	require.NoError(t, err)
+
+	c.Set(ctx, "key", "value")
+	_, hit := c.Get(ctx, "key")
+	require.Equal(t, Miss, hit)
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/membership"
+)
+
+type invalidationMiddleware[T any] struct {
+	next       cache.Cache[T]
+	membership membership.Membership
+	eventType  string
+	registry   *events.Registry
+	logger     logging.Logger
+}
+
+// invalidationVersion is the current version of the invalidation payload
+const invalidationVersion = 1
+
+// serf rejects user events above 512 bytes, counting the event type, the
+// payload and its own encoding of the message.
+const (
+	gossipSizeLimit    = 512
+	gossipSizeOverhead = 64
+)
+
+type invalidation struct {
+	// The node that issued the invalidation, so it doesn't apply it twice
+	NodeId     string   `json:"nodeId"`
+	Keys       []string `json:"keys,omitempty"`
+	Tombstones []string `json:"tombstones,omitempty"`
+	Prefixes   []string `json:"prefixes,omitempty"`
+	Clear      bool     `json:"clear,omitempty"`
+}
+
+// WithInvalidation broadcasts removals to all other nodes in the cluster, so
+// they evict the same keys from their local cache.
+//
+// Only Remove, Tombstone, RemoveByPrefix and Clear are broadcasted. Callers must remove a key after
+// updating or deleting it in the origin, which bounds the staleness on other
+// nodes to the gossip propagation delay rather than the cache's ttl.
+func WithInvalidation[T any](c cache.Cache[T], m membership.Membership, resource string, logger logging.Logger) cache.Cache[T] {
+	mw := &invalidationMiddleware[T]{
+		next:       c,
+		membership: m,
+		eventType:  fmt.Sprintf("cache.invalidate.%s", resource),
+		registry:   events.NewRegistry(),
+		logger:     logger.With().Str("resource", resource).Logger(),
+	}
+	events.Register(mw.registry, mw.eventType, invalidationVersion, func(inv invalidation) error {
+		if inv.NodeId == "" {
+			return fault.New("invalidation requires a node id")
+		}
+		return nil
+	})
+
+	go mw.consume(m.SubscribeGossipEvents())
+
+	return mw
+}
+
+func (mw *invalidationMiddleware[T]) consume(events <-chan membership.GossipEvent) {
+	for e := range events {
+		if e.Event != mw.eventType {
+			continue
+		}
+
+		ctx, inv, err := mw.decode(e.Payload)
+		if err != nil {
+			mw.logger.Warn().Err(err).Msg("failed to decode invalidation")
+			continue
+		}
+		if inv.NodeId == mw.membership.NodeId() {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		if inv.Clear {
+			mw.next.Clear(ctx)
+		}
+		if len(inv.Keys) > 0 {
+			mw.next.Remove(ctx, inv.Keys...)
+		}
+		for _, key := range inv.Tombstones {
+			mw.next.Tombstone(ctx, key)
+		}
+		for _, prefix := range inv.Prefixes {
+			mw.next.RemoveByPrefix(ctx, prefix)
+		}
+		cancel()
+	}
+}
+
+// decode unwraps an invalidation. Nodes running older versions broadcast the
+// bare payload without an envelope.
+func (mw *invalidationMiddleware[T]) decode(b []byte) (context.Context, invalidation, error) {
+	ctx := context.Background()
+	e := events.Envelope{}
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return ctx, invalidation{}, err
+	}
+	if e.Type == "" {
+		inv := invalidation{}
+		err = json.Unmarshal(b, &inv)
+		return ctx, inv, err
+	}
+	return events.Unwrap[invalidation](ctx, mw.registry, e)
+}
+
+// broadcast sends the invalidation to all other nodes. Invalidations that
+// exceed the gossip size limit are split into one broadcast per key or prefix,
+// a single key or prefix that doesn't fit is an error.
+func (mw *invalidationMiddleware[T]) broadcast(ctx context.Context, inv invalidation) error {
+	inv.NodeId = mw.membership.NodeId()
+	e, err := mw.registry.Wrap(ctx, mw.eventType, invalidationVersion, "", inv)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to wrap invalidation"))
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to marshal invalidation"))
+	}
+
+	if len(mw.eventType)+len(b)+gossipSizeOverhead <= gossipSizeLimit {
+		return mw.membership.Broadcast(mw.eventType, b)
+	}
+	if len(inv.Keys)+len(inv.Tombstones)+len(inv.Prefixes) <= 1 {
+		return fault.New(fmt.Sprintf("invalidation of %d bytes exceeds the gossip size limit of %d bytes", len(b), gossipSizeLimit))
+	}
+
+	errs := []error{}
+	for _, key := range inv.Keys {
+		errs = append(errs, mw.broadcast(ctx, invalidation{Keys: []string{key}}))
+	}
+	for _, key := range inv.Tombstones {
+		errs = append(errs, mw.broadcast(ctx, invalidation{Tombstones: []string{key}}))
+	}
+	for _, prefix := range inv.Prefixes {
+		errs = append(errs, mw.broadcast(ctx, invalidation{Prefixes: []string{prefix}}))
+	}
+	return errors.Join(errs...)
+}
+
+func (mw *invalidationMiddleware[T]) Get(ctx context.Context, key string) (T, cache.CacheHit) {
+	return mw.next.Get(ctx, key)
+}
+func (mw *invalidationMiddleware[T]) GetMany(ctx context.Context, keys []string) ([]T, []cache.CacheHit) {
+	return mw.next.GetMany(ctx, keys)
+}
+func (mw *invalidationMiddleware[T]) SetMany(ctx context.Context, values map[string]T) {
+	mw.next.SetMany(ctx, values)
+}
+func (mw *invalidationMiddleware[T]) Set(ctx context.Context, key string, value T) {
+	mw.next.Set(ctx, key, value)
+}
+func (mw *invalidationMiddleware[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	mw.next.SetWithTTL(ctx, key, value, ttl)
+}
+func (mw *invalidationMiddleware[T]) SetNull(ctx context.Context, key string) {
+	mw.next.SetNull(ctx, key)
+}
+func (mw *invalidationMiddleware[T]) Remove(ctx context.Context, keys ...string) {
+	mw.next.Remove(ctx, keys...)
+	err := mw.broadcast(ctx, invalidation{Keys: keys})
+	if err != nil {
+		mw.logger.Error().Err(err).Strs("cacheKeys", keys).Msg("failed to broadcast invalidation")
+	}
+}
+func (mw *invalidationMiddleware[T]) Tombstone(ctx context.Context, key string) {
+	mw.next.Tombstone(ctx, key)
+	err := mw.broadcast(ctx, invalidation{Tombstones: []string{key}})
+	if err != nil {
+		mw.logger.Error().Err(err).Str("cacheKey", key).Msg("failed to broadcast invalidation")
+	}
+}
+func (mw *invalidationMiddleware[T]) RemoveByPrefix(ctx context.Context, prefix string) {
+	mw.next.RemoveByPrefix(ctx, prefix)
+	err := mw.broadcast(ctx, invalidation{Prefixes: []string{prefix}})
+	if err != nil {
+		mw.logger.Error().Err(err).Str("prefix", prefix).Msg("failed to broadcast invalidation")
+	}
+}
+
+func (mw *invalidationMiddleware[T]) Dump(ctx context.Context) ([]byte, error) {
+	return mw.next.Dump(ctx)
+}
+
+func (mw *invalidationMiddleware[T]) Restore(ctx context.Context, data []byte) error {
+	return mw.next.Restore(ctx, data)
+}
+
+func (mw *invalidationMiddleware[T]) Clear(ctx context.Context) {
+	mw.next.Clear(ctx)
+	err := mw.broadcast(ctx, invalidation{Clear: true})
+	if err != nil {
+		mw.logger.Error().Err(err).Msg("failed to broadcast invalidation")
+	}
+}
@@ -0,0 +1,147 @@
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache/middleware"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/membership"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+)
+
+// fakeMembership delivers broadcasts to all fakes sharing the same topic
+type fakeMembership struct {
+	membership.Membership
+	nodeId string
+	topic  events.Topic[membership.GossipEvent]
+}
+
+func (m *fakeMembership) NodeId() string {
+	return m.nodeId
+}
+
+func (m *fakeMembership) Broadcast(eventType string, payload []byte) error {
+	// like serf's default UserEventSizeLimit
+	if len(eventType)+len(payload) > 512 {
+		return fmt.Errorf("user event exceeds configured limit of 512 bytes")
+	}
+	m.topic.Publish(context.Background(), membership.GossipEvent{Event: eventType, Payload: payload})
+	return nil
+}
+
+func (m *fakeMembership) SubscribeGossipEvents() <-chan membership.GossipEvent {
+	return m.topic.SubscribeChannel(m.nodeId)
+}
+
+func TestInvalidationRemovesOnAllNodes(t *testing.T) {
+	ctx := context.Background()
+	topic := events.NewTopic[membership.GossipEvent](100)
+
+	newNode := func(nodeId string) cache.Cache[string] {
+		c, err := cache.New[string](cache.Config[string]{
+			MaxSize: 10_000,
+			Fresh:   time.Minute,
+			Stale:   time.Minute,
+			Logger:  logging.NewNoopLogger(),
+			Metrics: metrics.NewNoop(),
+		})
+		require.NoError(t, err)
+		return middleware.WithInvalidation[string](c, &fakeMembership{nodeId: nodeId, topic: topic}, "test", logging.NewNoopLogger())
+	}
+
+	a := newNode("a")
+	b := newNode("b")
+
+	a.Set(ctx, "key", "value")
+	b.Set(ctx, "key", "value")
+
+	a.Remove(ctx, "key")
+	require.Eventually(t, func() bool {
+		_, hit := b.Get(ctx, "key")
+		return hit == cache.Miss
+	}, 5*time.Second, 10*time.Millisecond)
+
+	b.Set(ctx, "other", "value")
+	a.Clear(ctx)
+	require.Eventually(t, func() bool {
+		_, hit := b.Get(ctx, "other")
+		return hit == cache.Miss
+	}, 5*time.Second, 10*time.Millisecond)
+	b.Set(ctx, "deleted", "value")
+	a.Tombstone(ctx, "deleted")
+	require.Eventually(t, func() bool {
+		_, hit := b.Get(ctx, "deleted")
+		return hit == cache.Null
+	}, 5*time.Second, 10*time.Millisecond)
+	b.Set(ctx, "deleted", "value")
+	_, hit := b.Get(ctx, "deleted")
+	require.Equal(t, cache.Null, hit)
+}
+
+func TestInvalidationAcceptsPayloadsWithoutEnvelope(t *testing.T) {
+	ctx := context.Background()
+	topic := events.NewTopic[membership.GossipEvent](100)
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+	b := middleware.WithInvalidation[string](c, &fakeMembership{nodeId: "b", topic: topic}, "test", logging.NewNoopLogger())
+	b.Set(ctx, "key", "value")
+
+	// nodes running an older version broadcast the bare invalidation
+	topic.Publish(ctx, membership.GossipEvent{
+		Event:   "cache.invalidate.test",
+		Payload: []byte(`{"nodeId":"a","keys":["key"]}`),
+	})
+	require.Eventually(t, func() bool {
+		_, hit := b.Get(ctx, "key")
+		return hit == cache.Miss
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestInvalidationSplitsLargeRemovals(t *testing.T) {
+	ctx := context.Background()
+	topic := events.NewTopic[membership.GossipEvent](100)
+
+	newNode := func(nodeId string) cache.Cache[string] {
+		c, err := cache.New[string](cache.Config[string]{
+			MaxSize: 10_000,
+			Fresh:   time.Minute,
+			Stale:   time.Minute,
+			Logger:  logging.NewNoopLogger(),
+			Metrics: metrics.NewNoop(),
+		})
+		require.NoError(t, err)
+		return middleware.WithInvalidation[string](c, &fakeMembership{nodeId: nodeId, topic: topic}, "test", logging.NewNoopLogger())
+	}
+
+	a := newNode("a")
+	b := newNode("b")
+
+	// Together far beyond what fits into a single gossip message
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("keyring_%d_%s", i, strings.Repeat("x", 64))
+		b.Set(ctx, keys[i], "value")
+	}
+
+	a.Remove(ctx, keys...)
+	for _, key := range keys {
+		require.Eventually(t, func() bool {
+			_, hit := b.Get(ctx, key)
+			return hit == cache.Miss
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+}
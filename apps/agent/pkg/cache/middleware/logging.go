@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+type loggingMiddleware[T any] struct {
+	next   cache.Cache[T]
+	logger logging.Logger
+}
+
+// WithLogging logs cache operations with their latency at debug level.
+// Failed dumps and restores are logged as warnings.
+//
+// At most 100 lines per second are written, the rest are dropped.
+func WithLogging[T any](c cache.Cache[T], logger logging.Logger, resource string) cache.Cache[T] {
+	return &loggingMiddleware[T]{next: c, logger: logging.Sampled(logger.With().Str("resource", resource).Logger(), 100, time.Second)}
+}
+
+func (mw *loggingMiddleware[T]) Get(ctx context.Context, key string) (T, cache.CacheHit) {
+	start := time.Now()
+	value, hit := mw.next.Get(ctx, key)
+	mw.logger.Debug().Str("cacheKey", key).Str("hit", hit.String()).Dur("latency", time.Since(start)).Msg("cache.Get")
+	return value, hit
+}
+func (mw *loggingMiddleware[T]) GetMany(ctx context.Context, keys []string) ([]T, []cache.CacheHit) {
+	start := time.Now()
+	values, hits := mw.next.GetMany(ctx, keys)
+	misses := 0
+	for _, hit := range hits {
+		if hit == cache.Miss {
+			misses++
+		}
+	}
+	mw.logger.Debug().Int("keys", len(keys)).Int("misses", misses).Dur("latency", time.Since(start)).Msg("cache.GetMany")
+	return values, hits
+}
+func (mw *loggingMiddleware[T]) Set(ctx context.Context, key string, value T) {
+	start := time.Now()
+	mw.next.Set(ctx, key, value)
+	mw.logger.Debug().Str("cacheKey", key).Dur("latency", time.Since(start)).Msg("cache.Set")
+}
+func (mw *loggingMiddleware[T]) SetMany(ctx context.Context, values map[string]T) {
+	start := time.Now()
+	mw.next.SetMany(ctx, values)
+	mw.logger.Debug().Int("keys", len(values)).Dur("latency", time.Since(start)).Msg("cache.SetMany")
+}
+func (mw *loggingMiddleware[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	start := time.Now()
+	mw.next.SetWithTTL(ctx, key, value, ttl)
+	mw.logger.Debug().Str("cacheKey", key).Dur("ttl", ttl).Dur("latency", time.Since(start)).Msg("cache.SetWithTTL")
+}
+func (mw *loggingMiddleware[T]) SetNull(ctx context.Context, key string) {
+	start := time.Now()
+	mw.next.SetNull(ctx, key)
+	mw.logger.Debug().Str("cacheKey", key).Dur("latency", time.Since(start)).Msg("cache.SetNull")
+}
+func (mw *loggingMiddleware[T]) Tombstone(ctx context.Context, key string) {
+	start := time.Now()
+	mw.next.Tombstone(ctx, key)
+	mw.logger.Debug().Str("cacheKey", key).Dur("latency", time.Since(start)).Msg("cache.Tombstone")
+}
+func (mw *loggingMiddleware[T]) Remove(ctx context.Context, keys ...string) {
+	start := time.Now()
+	mw.next.Remove(ctx, keys...)
+	mw.logger.Debug().Strs("cacheKeys", keys).Dur("latency", time.Since(start)).Msg("cache.Remove")
+}
+func (mw *loggingMiddleware[T]) RemoveByPrefix(ctx context.Context, prefix string) {
+	start := time.Now()
+	mw.next.RemoveByPrefix(ctx, prefix)
+	mw.logger.Debug().Str("prefix", prefix).Dur("latency", time.Since(start)).Msg("cache.RemoveByPrefix")
+}
+
+func (mw *loggingMiddleware[T]) Dump(ctx context.Context) ([]byte, error) {
+	start := time.Now()
+	b, err := mw.next.Dump(ctx)
+	if err != nil {
+		mw.logger.Warn().Err(err).Msg("failed to dump cache")
+		return nil, err
+	}
+	mw.logger.Debug().Int("bytes", len(b)).Dur("latency", time.Since(start)).Msg("cache.Dump")
+	return b, nil
+}
+
+func (mw *loggingMiddleware[T]) Restore(ctx context.Context, data []byte) error {
+	start := time.Now()
+	err := mw.next.Restore(ctx, data)
+	if err != nil {
+		mw.logger.Warn().Err(err).Msg("failed to restore cache")
+		return err
+	}
+	mw.logger.Debug().Int("bytes", len(data)).Dur("latency", time.Since(start)).Msg("cache.Restore")
+	return nil
+}
+
+func (mw *loggingMiddleware[T]) Clear(ctx context.Context) {
+	start := time.Now()
+	mw.next.Clear(ctx)
+	mw.logger.Debug().Dur("latency", time.Since(start)).Msg("cache.Clear")
+}
@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache/middleware"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+func TestLoggingLogsOperations(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 100,
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+	})
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	logged := middleware.WithLogging[string](c, zerolog.New(buf).Level(zerolog.DebugLevel), "test")
+
+	logged.Set(ctx, "key", "value")
+	logged.Get(ctx, "key")
+	logged.Get(ctx, "missing")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+
+	entries := make([]map[string]any, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &entries[i]))
+		require.Equal(t, "test", entries[i]["resource"])
+		require.Contains(t, entries[i], "latency")
+	}
+	require.Equal(t, "cache.Set", entries[0]["message"])
+	require.Equal(t, "hit", entries[1]["hit"])
+	require.Equal(t, "miss", entries[2]["hit"])
+}
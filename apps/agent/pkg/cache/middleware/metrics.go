@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
 	"github.com/unkeyed/unkey/apps/agent/pkg/prometheus"
+	"github.com/unkeyed/unkey/apps/agent/pkg/repeat"
 )
 
 type metricsMiddleware[T any] struct {
@@ -14,44 +16,119 @@ type metricsMiddleware[T any] struct {
 	metrics  metrics.Metrics
 	resource string
 	tier     string
+
+	// Reads are aggregated and flushed to metrics periodically, recording every
+	// single read would be far too much data
+	hits              atomic.Int64
+	misses            atomic.Int64
+	nulls             atomic.Int64
+	readLatencyMicros atomic.Int64
 }
 
-func WithMetrics[T any](c cache.Cache[T], m metrics.Metrics, resource string, tier string) cache.Cache[T] {
-	return &metricsMiddleware[T]{next: c, metrics: m, resource: resource, tier: tier}
+// WithMetrics reports hits, misses and latency to prometheus on every read and
+// flushes aggregated usage to m every 10 seconds.
+//
+// The returned function stops flushing, after flushing one last time.
+func WithMetrics[T any](c cache.Cache[T], m metrics.Metrics, resource string, tier string) (cache.Cache[T], func()) {
+	if m == nil {
+		m = metrics.NewNoop()
+	}
+	mw := &metricsMiddleware[T]{next: c, metrics: m, resource: resource, tier: tier}
+	stop := repeat.Every(10*time.Second, mw.flush)
+	return mw, func() {
+		stop()
+		mw.flush()
+	}
+}
+
+func (mw *metricsMiddleware[T]) flush() {
+	usage := metrics.CacheUsage{
+		Resource:          mw.resource,
+		Tier:              mw.tier,
+		Hits:              mw.hits.Swap(0),
+		Misses:            mw.misses.Swap(0),
+		Nulls:             mw.nulls.Swap(0),
+		ReadLatencyMicros: mw.readLatencyMicros.Swap(0),
+	}
+	if usage.Hits+usage.Misses+usage.Nulls == 0 {
+		return
+	}
+	mw.metrics.Record(usage)
 }
 
 func (mw *metricsMiddleware[T]) Get(ctx context.Context, key string) (T, cache.CacheHit) {
 	start := time.Now()
 	value, hit := mw.next.Get(ctx, key)
+	mw.observe(key, hit, time.Since(start))
+
+	return value, hit
+}
+
+func (mw *metricsMiddleware[T]) GetMany(ctx context.Context, keys []string) ([]T, []cache.CacheHit) {
+	start := time.Now()
+	values, hits := mw.next.GetMany(ctx, keys)
+	if len(keys) == 0 {
+		return values, hits
+	}
 
+	// We can't know the latency of individual keys, so we attribute an equal share to each
+	latency := time.Since(start) / time.Duration(len(keys))
+	for i, key := range keys {
+		mw.observe(key, hits[i], latency)
+	}
+
+	return values, hits
+}
+
+func (mw *metricsMiddleware[T]) observe(key string, hit cache.CacheHit, latency time.Duration) {
 	labels := map[string]string{
-		"key":      key,
+		"key":      prometheus.CacheKeyLabel.Value(key),
 		"resource": mw.resource,
 		"tier":     mw.tier,
 	}
 
+	switch hit {
+	case cache.Miss:
+		mw.misses.Add(1)
+	case cache.Null:
+		mw.nulls.Add(1)
+	default:
+		mw.hits.Add(1)
+	}
 	if hit == cache.Miss {
 		prometheus.CacheMisses.With(labels).Inc()
 	} else {
 		prometheus.CacheHits.With(labels).Inc()
 	}
-	prometheus.CacheLatency.With(labels).Observe(time.Since(start).Seconds())
-
-	return value, hit
+	mw.readLatencyMicros.Add(latency.Microseconds())
+	prometheus.CacheLatency.With(labels).Observe(latency.Seconds())
+}
+func (mw *metricsMiddleware[T]) SetMany(ctx context.Context, values map[string]T) {
+	mw.next.SetMany(ctx, values)
 }
 func (mw *metricsMiddleware[T]) Set(ctx context.Context, key string, value T) {
 	mw.next.Set(ctx, key, value)
 
+}
+func (mw *metricsMiddleware[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	mw.next.SetWithTTL(ctx, key, value, ttl)
+
 }
 func (mw *metricsMiddleware[T]) SetNull(ctx context.Context, key string) {
 	mw.next.SetNull(ctx, key)
 
 }
-func (mw *metricsMiddleware[T]) Remove(ctx context.Context, key string) {
+func (mw *metricsMiddleware[T]) Tombstone(ctx context.Context, key string) {
+	mw.next.Tombstone(ctx, key)
+}
+func (mw *metricsMiddleware[T]) Remove(ctx context.Context, keys ...string) {
 
-	mw.next.Remove(ctx, key)
+	mw.next.Remove(ctx, keys...)
 
 }
+func (mw *metricsMiddleware[T]) RemoveByPrefix(ctx context.Context, prefix string) {
+	mw.next.RemoveByPrefix(ctx, prefix)
+}
 
 func (mw *metricsMiddleware[T]) Dump(ctx context.Context) ([]byte, error) {
 	return mw.next.Dump(ctx)
@@ -0,0 +1,55 @@
+package middleware_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache/middleware"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+)
+
+type recordingMetrics struct {
+	metrics.Metrics
+	mu       sync.Mutex
+	recorded []metrics.Metric
+}
+
+func (m *recordingMetrics) Record(metric metrics.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recorded = append(m.recorded, metric)
+}
+
+func TestMetricsFlushesUsageWhenStopped(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 100,
+		Fresh:   time.Minute,
+		Stale:   time.Minute,
+		Logger:  logging.NewNoopLogger(),
+	})
+	require.NoError(t, err)
+
+	m := &recordingMetrics{}
+	measured, stop := middleware.WithMetrics[string](c, m, "test", "memory")
+	measured.Set(ctx, "a", "value")
+	measured.SetNull(ctx, "b")
+	measured.GetMany(ctx, []string{"a", "b", "c"})
+
+	stop()
+	// stopping twice is fine and flushes nothing new
+	stop()
+
+	require.Len(t, m.recorded, 1)
+	usage := m.recorded[0].(metrics.CacheUsage)
+	require.Equal(t, "test", usage.Resource)
+	require.Equal(t, int64(1), usage.Hits)
+	require.Equal(t, int64(1), usage.Misses)
+	require.Equal(t, int64(1), usage.Nulls)
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"time"
 
 	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
@@ -27,6 +28,31 @@ func (mw *tracingMiddleware[T]) Get(ctx context.Context, key string) (T, cache.C
 	)
 	return value, hit
 }
+func (mw *tracingMiddleware[T]) GetMany(ctx context.Context, keys []string) ([]T, []cache.CacheHit) {
+	ctx, span := tracing.Start(ctx, "cache.GetMany")
+	defer span.End()
+	span.SetAttributes(attribute.StringSlice("keys", keys))
+
+	values, hits := mw.next.GetMany(ctx, keys)
+	misses := 0
+	for _, hit := range hits {
+		if hit == cache.Miss {
+			misses++
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("misses", misses),
+	)
+	return values, hits
+}
+func (mw *tracingMiddleware[T]) SetMany(ctx context.Context, values map[string]T) {
+	ctx, span := tracing.Start(ctx, "cache.SetMany")
+	defer span.End()
+	span.SetAttributes(attribute.Int("keys", len(values)))
+
+	mw.next.SetMany(ctx, values)
+
+}
 func (mw *tracingMiddleware[T]) Set(ctx context.Context, key string, value T) {
 	ctx, span := tracing.Start(ctx, "cache.Set")
 	defer span.End()
@@ -34,6 +60,14 @@ func (mw *tracingMiddleware[T]) Set(ctx context.Context, key string, value T) {
 
 	mw.next.Set(ctx, key, value)
 
+}
+func (mw *tracingMiddleware[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	ctx, span := tracing.Start(ctx, "cache.SetWithTTL")
+	defer span.End()
+	span.SetAttributes(attribute.String("key", key), attribute.Int64("ttl", ttl.Milliseconds()))
+
+	mw.next.SetWithTTL(ctx, key, value, ttl)
+
 }
 func (mw *tracingMiddleware[T]) SetNull(ctx context.Context, key string) {
 	ctx, span := tracing.Start(ctx, "cache.SetNull")
@@ -43,12 +77,28 @@ func (mw *tracingMiddleware[T]) SetNull(ctx context.Context, key string) {
 	mw.next.SetNull(ctx, key)
 
 }
-func (mw *tracingMiddleware[T]) Remove(ctx context.Context, key string) {
-	ctx, span := tracing.Start(ctx, "cache.Remove")
+func (mw *tracingMiddleware[T]) Tombstone(ctx context.Context, key string) {
+	ctx, span := tracing.Start(ctx, "cache.Tombstone")
 	defer span.End()
 	span.SetAttributes(attribute.String("key", key))
 
-	mw.next.Remove(ctx, key)
+	mw.next.Tombstone(ctx, key)
+
+}
+func (mw *tracingMiddleware[T]) Remove(ctx context.Context, keys ...string) {
+	ctx, span := tracing.Start(ctx, "cache.Remove")
+	defer span.End()
+	span.SetAttributes(attribute.StringSlice("keys", keys))
+
+	mw.next.Remove(ctx, keys...)
+
+}
+func (mw *tracingMiddleware[T]) RemoveByPrefix(ctx context.Context, prefix string) {
+	ctx, span := tracing.Start(ctx, "cache.RemoveByPrefix")
+	defer span.End()
+	span.SetAttributes(attribute.String("prefix", prefix))
+
+	mw.next.RemoveByPrefix(ctx, prefix)
 
 }
 
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 )
 
 type noopCache[T any] struct{}
@@ -10,10 +11,24 @@ func (c *noopCache[T]) Get(ctx context.Context, key string) (value T, hit CacheH
 	var t T
 	return t, Miss
 }
-func (c *noopCache[T]) Set(ctx context.Context, key string, value T) {}
-func (c *noopCache[T]) SetNull(ctx context.Context, key string)      {}
+func (c *noopCache[T]) GetMany(ctx context.Context, keys []string) ([]T, []CacheHit) {
+	hits := make([]CacheHit, len(keys))
+	for i := range hits {
+		hits[i] = Miss
+	}
+	return make([]T, len(keys)), hits
+}
+func (c *noopCache[T]) Set(ctx context.Context, key string, value T)     {}
+func (c *noopCache[T]) SetMany(ctx context.Context, values map[string]T) {}
+func (c *noopCache[T]) SetNull(ctx context.Context, key string)          {}
+
+func (c *noopCache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {}
+
+func (c *noopCache[T]) Tombstone(ctx context.Context, key string) {}
+
+func (c *noopCache[T]) Remove(ctx context.Context, keys ...string) {}
 
-func (c *noopCache[T]) Remove(ctx context.Context, key string) {}
+func (c *noopCache[T]) RemoveByPrefix(ctx context.Context, prefix string) {}
 
 func (c *noopCache[T]) Dump(ctx context.Context) ([]byte, error) {
 	return []byte{}, nil
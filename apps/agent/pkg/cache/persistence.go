@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/repeat"
+)
+
+type PersistenceConfig struct {
+	// The file to store snapshots in, it will be created if it doesn't exist
+	Path string
+
+	// How often to write a snapshot to disk, defaults to 1 minute
+	Interval time.Duration
+
+	// Snapshots are readable by anyone with access to the file, caches holding
+	// secrets must encrypt them. Seal encrypts a snapshot before it is written,
+	// Open decrypts it again when it is restored.
+	Seal func(snapshot []byte) ([]byte, error)
+	Open func(sealed []byte) ([]byte, error)
+	// Write snapshots without encryption. Only set this for caches without
+	// secrets, Persist refuses to run if neither this nor Seal and Open are set.
+	Plaintext bool
+
+	Logger logging.Logger
+}
+
+// Persist restores the cache from the snapshot on disk, if there is one, and
+// periodically writes new snapshots, so a restarted node can serve from a warm
+// cache immediately.
+// Expired entries are discarded when the snapshot is restored.
+//
+// Snapshots must be encrypted with Seal and Open unless the config explicitly
+// allows plaintext, see PersistenceConfig.
+//
+// The returned function stops the periodic snapshots and writes a final one.
+// Call it during shutdown.
+func Persist[T any](ctx context.Context, c Cache[T], config PersistenceConfig) (func() error, error) {
+	if (config.Seal == nil) != (config.Open == nil) {
+		return nil, fault.New("seal and open must be set together")
+	}
+	if config.Seal != nil && config.Plaintext {
+		return nil, fault.New("sealed snapshots can not be plaintext")
+	}
+	if config.Seal == nil && !config.Plaintext {
+		return nil, fault.New("refusing to write unencrypted cache snapshots, set seal and open or allow plaintext")
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+
+	b, err := os.ReadFile(config.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fault.Wrap(err, fmsg.With("failed to read cache snapshot"))
+	}
+	if len(b) > 0 && config.Open != nil {
+		b, err = config.Open(b)
+		if err != nil {
+			// Sealed with a key we no longer have, or tampered with
+			config.Logger.Warn().Err(err).Str("path", config.Path).Msg("failed to open cache snapshot")
+			b = nil
+		}
+	}
+	if len(b) > 0 {
+		err = c.Restore(ctx, b)
+		if err != nil {
+			// A corrupt snapshot should not prevent the node from starting, it
+			// will be overwritten with the next snapshot
+			config.Logger.Warn().Err(err).Str("path", config.Path).Msg("failed to restore cache snapshot")
+		}
+	}
+
+	snapshot := func() error {
+		return writeSnapshot(context.Background(), c, config.Path, config.Seal)
+	}
+
+	stop := repeat.Every(config.Interval, func() {
+		err := snapshot()
+		if err != nil {
+			config.Logger.Warn().Err(err).Str("path", config.Path).Msg("failed to write cache snapshot")
+		}
+	})
+
+	return func() error {
+		stop()
+		return snapshot()
+	}, nil
+}
+
+// writeSnapshot writes to a temporary file first and then renames it, so a
+// crash during the write never leaves a partial snapshot behind.
+func writeSnapshot[T any](ctx context.Context, c Cache[T], path string, seal func([]byte) ([]byte, error)) error {
+	b, err := c.Dump(ctx)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to dump cache"))
+	}
+	if seal != nil {
+		b, err = seal(b)
+		if err != nil {
+			return fault.Wrap(err, fmsg.With("failed to seal snapshot"))
+		}
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to create temporary snapshot"))
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		return fault.Wrap(err, fmsg.With("failed to write snapshot"))
+	}
+	err = f.Close()
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to close snapshot"))
+	}
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to move snapshot into place"))
+	}
+	return nil
+}
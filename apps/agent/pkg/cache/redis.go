@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/redis/go-redis/v9"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+type redisCache[T any] struct {
+	client       redis.UniversalClient
+	ttl          time.Duration
+	nullTTL      time.Duration
+	tombstoneTTL time.Duration
+	jitter       float64
+	prefix       string
+	logger       logging.Logger
+	resource     string
+}
+
+type RedisConfig struct {
+	Client redis.UniversalClient
+
+	// How long entries are kept in redis
+	// There is no stale time, once an entry expires it is gone. Use a tiered
+	// cache with an in-memory L1 if you need stale-while-revalidate semantics.
+	TTL time.Duration
+
+	// How long null entries are kept in redis, defaults to TTL
+	NullTTL time.Duration
+
+	// How long tombstones are kept in redis, defaults to TTL
+	TombstoneTTL time.Duration
+
+	// Randomly shorten or extend the ttl of each entry by up to this factor,
+	// e.g. 0.1 for ±10%, so entries written together don't all expire together.
+	Jitter float64
+
+	Logger logging.Logger
+
+	// Keys are prefixed with the resource, so multiple caches can share the same redis instance
+	Resource string
+}
+
+// storedEntry is the representation of an entry in remote caches
+type storedEntry[T any] struct {
+	Value     T        `json:"value"`
+	Hit       CacheHit `json:"hit"`
+	Tombstone bool     `json:"tombstone,omitempty"`
+}
+
+// setUnlessTombstone writes an entry unless the key currently holds a tombstone.
+// Checking and writing must be atomic, otherwise a write could land right after a
+// tombstone and bring the deleted data back.
+var setUnlessTombstone = redis.NewScript(`
+local existing = redis.call("GET", KEYS[1])
+if existing and cjson.decode(existing).tombstone then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// NewRedis creates a cache backed by redis, which can be shared across nodes.
+func NewRedis[T any](config RedisConfig) (*redisCache[T], error) {
+	if config.Client == nil {
+		return nil, fault.New("redis client is required")
+	}
+	if config.Resource == "" {
+		return nil, fault.New("resource is required")
+	}
+	if config.TTL <= 0 {
+		return nil, fault.New("ttl must be greater than 0")
+	}
+
+	nullTTL := config.NullTTL
+	if nullTTL <= 0 {
+		nullTTL = config.TTL
+	}
+	tombstoneTTL := config.TombstoneTTL
+	if tombstoneTTL <= 0 {
+		tombstoneTTL = config.TTL
+	}
+
+	// Every operation fails while the server is down, so warnings are sampled
+	logger := logging.Sampled(config.Logger.With().Str("resource", config.Resource).Logger(), 10, time.Second)
+
+	return &redisCache[T]{
+		client:       config.Client,
+		ttl:          config.TTL,
+		nullTTL:      nullTTL,
+		tombstoneTTL: tombstoneTTL,
+		jitter:       config.Jitter,
+		prefix:       fmt.Sprintf("cache:%s:", config.Resource),
+		logger:       logger,
+		resource:     config.Resource,
+	}, nil
+}
+
+func (c *redisCache[T]) Get(ctx context.Context, key string) (value T, hit CacheHit) {
+	b, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.logger.Warn().Err(err).Str("cacheKey", key).Msg("failed to get from redis")
+		}
+		var t T
+		return t, Miss
+	}
+
+	e := storedEntry[T]{}
+	err = json.Unmarshal(b, &e)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("cacheKey", key).Msg("failed to unmarshal redis entry")
+		var t T
+		return t, Miss
+	}
+	return e.Value, e.Hit
+}
+
+func (c *redisCache[T]) GetMany(ctx context.Context, keys []string) ([]T, []CacheHit) {
+	values := make([]T, len(keys))
+	hits := make([]CacheHit, len(keys))
+	for i := range hits {
+		hits[i] = Miss
+	}
+	if len(keys) == 0 {
+		return values, hits
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	res, err := c.client.MGet(ctx, prefixed...).Result()
+	if err != nil {
+		c.logger.Warn().Err(err).Int("keys", len(keys)).Msg("failed to get many from redis")
+		return values, hits
+	}
+
+	for i, r := range res {
+		s, ok := r.(string)
+		if !ok {
+			// nil means the key does not exist
+			continue
+		}
+		e := storedEntry[T]{}
+		err = json.Unmarshal([]byte(s), &e)
+		if err != nil {
+			c.logger.Warn().Err(err).Str("cacheKey", keys[i]).Msg("failed to unmarshal redis entry")
+			continue
+		}
+		values[i], hits[i] = e.Value, e.Hit
+	}
+	return values, hits
+}
+
+func (c *redisCache[T]) SetMany(ctx context.Context, values map[string]T) {
+	if len(values) == 0 {
+		return
+	}
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			b, err := json.Marshal(storedEntry[T]{Value: value, Hit: Hit})
+			if err != nil {
+				c.logger.Warn().Err(err).Str("cacheKey", key).Msg("failed to marshal redis entry")
+				continue
+			}
+			setUnlessTombstone.Eval(ctx, pipe, []string{c.prefix + key}, b, jitter(c.ttl, c.jitter).Milliseconds())
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Warn().Err(err).Int("keys", len(values)).Msg("failed to set many in redis")
+	}
+}
+
+func (c *redisCache[T]) Set(ctx context.Context, key string, value T) {
+	c.set(ctx, key, storedEntry[T]{Value: value, Hit: Hit})
+}
+
+func (c *redisCache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	c.setWithTTL(ctx, key, storedEntry[T]{Value: value, Hit: Hit}, ttl)
+}
+
+func (c *redisCache[T]) SetNull(ctx context.Context, key string) {
+	c.setWithTTL(ctx, key, storedEntry[T]{Hit: Null}, c.nullTTL)
+}
+
+func (c *redisCache[T]) set(ctx context.Context, key string, e storedEntry[T]) {
+	c.setWithTTL(ctx, key, e, c.ttl)
+}
+
+func (c *redisCache[T]) setWithTTL(ctx context.Context, key string, e storedEntry[T], ttl time.Duration) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("cacheKey", key).Msg("failed to marshal redis entry")
+		return
+	}
+	if e.Tombstone {
+		err = c.client.Set(ctx, c.prefix+key, b, ttl).Err()
+	} else {
+		err = setUnlessTombstone.Run(ctx, c.client, []string{c.prefix + key}, b, jitter(ttl, c.jitter).Milliseconds()).Err()
+	}
+	if err != nil {
+		c.logger.Warn().Err(err).Str("cacheKey", key).Msg("failed to set in redis")
+	}
+}
+
+func (c *redisCache[T]) Tombstone(ctx context.Context, key string) {
+	c.setWithTTL(ctx, key, storedEntry[T]{Hit: Null, Tombstone: true}, c.tombstoneTTL)
+}
+
+func (c *redisCache[T]) Remove(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	err := c.client.Del(ctx, prefixed...).Err()
+	if err != nil {
+		c.logger.Warn().Err(err).Strs("cacheKeys", keys).Msg("failed to remove from redis")
+	}
+}
+
+func (c *redisCache[T]) RemoveByPrefix(ctx context.Context, prefix string) {
+	keys, err := c.keys(ctx, prefix)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("prefix", prefix).Msg("failed to remove from redis")
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+	err = c.client.Del(ctx, keys...).Err()
+	if err != nil {
+		c.logger.Warn().Err(err).Str("prefix", prefix).Msg("failed to remove from redis")
+	}
+}
+
+// keys returns all keys of this cache in redis starting with the given prefix,
+// including the cache's own prefix.
+func (c *redisCache[T]) keys(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	iter := c.client.Scan(ctx, 0, c.prefix+escapeGlob(prefix)+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fault.Wrap(err, fmsg.With("failed to scan redis keys"))
+	}
+	return keys, nil
+}
+
+func (c *redisCache[T]) Dump(ctx context.Context) ([]byte, error) {
+	keys, err := c.keys(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]json.RawMessage)
+	for _, key := range keys {
+		b, err := c.client.Get(ctx, key).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				// expired in the meantime
+				continue
+			}
+			return nil, fault.Wrap(err, fmsg.With("failed to get from redis"))
+		}
+		data[strings.TrimPrefix(key, c.prefix)] = b
+	}
+	return json.Marshal(data)
+}
+
+func (c *redisCache[T]) Restore(ctx context.Context, b []byte) error {
+	data := make(map[string]storedEntry[T])
+	err := json.Unmarshal(b, &data)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal cache data: %w", err)
+	}
+	for key, entry := range data {
+		c.set(ctx, key, entry)
+	}
+	return nil
+}
+
+func (c *redisCache[T]) Clear(ctx context.Context) {
+	keys, err := c.keys(ctx, "")
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("failed to clear redis cache")
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+	err = c.client.Del(ctx, keys...).Err()
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("failed to clear redis cache")
+	}
+}
+
+// escapeGlob escapes characters that have a special meaning in redis' MATCH patterns
+func escapeGlob(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+	return r.Replace(s)
+}
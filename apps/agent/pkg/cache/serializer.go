@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Serializer converts values to bytes and back, for caches that store data
+// outside of the process.
+type Serializer[T any] interface {
+	Marshal(value T) ([]byte, error)
+	Unmarshal(b []byte) (T, error)
+}
+
+type jsonSerializer[T any] struct{}
+
+// NewJSONSerializer serializes values using encoding/json.
+func NewJSONSerializer[T any]() Serializer[T] {
+	return jsonSerializer[T]{}
+}
+
+func (jsonSerializer[T]) Marshal(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonSerializer[T]) Unmarshal(b []byte) (T, error) {
+	var t T
+	err := json.Unmarshal(b, &t)
+	return t, err
+}
+
+type protoSerializer[T proto.Message] struct {
+	newMessage func() T
+}
+
+// NewProtoSerializer serializes protobuf messages in their binary format,
+// which is smaller and faster than json.
+// newMessage must return a new, empty message, e.g. `func() *vaultv1.DataEncryptionKey { return &vaultv1.DataEncryptionKey{} }`
+func NewProtoSerializer[T proto.Message](newMessage func() T) Serializer[T] {
+	return protoSerializer[T]{newMessage: newMessage}
+}
+
+func (s protoSerializer[T]) Marshal(value T) ([]byte, error) {
+	return proto.Marshal(value)
+}
+
+func (s protoSerializer[T]) Unmarshal(b []byte) (T, error) {
+	t := s.newMessage()
+	err := proto.Unmarshal(b, t)
+	return t, err
+}
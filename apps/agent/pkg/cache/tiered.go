@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+type tieredCache[T any] struct {
+	l1 Cache[T]
+	l2 Cache[T]
+}
+
+// NewTiered returns a cache that checks l1 first and falls back to l2.
+// Hits from l2 are back-filled into l1, so subsequent reads are served from l1.
+//
+// Typically l1 is a fast, in-memory cache local to this node and l2 is a
+// shared cache such as redis, so new nodes do not start cold.
+func NewTiered[T any](l1, l2 Cache[T]) Cache[T] {
+	return &tieredCache[T]{l1: l1, l2: l2}
+}
+
+func (c *tieredCache[T]) Get(ctx context.Context, key string) (value T, hit CacheHit) {
+	value, hit = c.l1.Get(ctx, key)
+	if hit != Miss {
+		return value, hit
+	}
+
+	value, hit = c.l2.Get(ctx, key)
+	switch hit {
+	case Hit:
+		c.l1.Set(ctx, key, value)
+	case Null:
+		c.l1.SetNull(ctx, key)
+	}
+	return value, hit
+}
+
+func (c *tieredCache[T]) GetMany(ctx context.Context, keys []string) ([]T, []CacheHit) {
+	values, hits := c.l1.GetMany(ctx, keys)
+
+	missing := []string{}
+	// index in keys for each missing key
+	missingIndex := []int{}
+	for i, hit := range hits {
+		if hit == Miss {
+			missing = append(missing, keys[i])
+			missingIndex = append(missingIndex, i)
+		}
+	}
+	if len(missing) == 0 {
+		return values, hits
+	}
+
+	l2Values, l2Hits := c.l2.GetMany(ctx, missing)
+	backfill := make(map[string]T)
+	for i, hit := range l2Hits {
+		j := missingIndex[i]
+		values[j], hits[j] = l2Values[i], hit
+		switch hit {
+		case Hit:
+			backfill[missing[i]] = l2Values[i]
+		case Null:
+			c.l1.SetNull(ctx, missing[i])
+		}
+	}
+	if len(backfill) > 0 {
+		c.l1.SetMany(ctx, backfill)
+	}
+	return values, hits
+}
+
+func (c *tieredCache[T]) SetMany(ctx context.Context, values map[string]T) {
+	c.l1.SetMany(ctx, values)
+	c.l2.SetMany(ctx, values)
+}
+
+func (c *tieredCache[T]) Set(ctx context.Context, key string, value T) {
+	c.l1.Set(ctx, key, value)
+	c.l2.Set(ctx, key, value)
+}
+
+func (c *tieredCache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	c.l1.SetWithTTL(ctx, key, value, ttl)
+	c.l2.SetWithTTL(ctx, key, value, ttl)
+}
+
+func (c *tieredCache[T]) SetNull(ctx context.Context, key string) {
+	c.l1.SetNull(ctx, key)
+	c.l2.SetNull(ctx, key)
+}
+
+func (c *tieredCache[T]) Tombstone(ctx context.Context, key string) {
+	c.l1.Tombstone(ctx, key)
+	c.l2.Tombstone(ctx, key)
+}
+
+func (c *tieredCache[T]) Remove(ctx context.Context, keys ...string) {
+	c.l1.Remove(ctx, keys...)
+	c.l2.Remove(ctx, keys...)
+}
+
+func (c *tieredCache[T]) RemoveByPrefix(ctx context.Context, prefix string) {
+	c.l1.RemoveByPrefix(ctx, prefix)
+	c.l2.RemoveByPrefix(ctx, prefix)
+}
+
+// Dump only dumps l1, l2 is expected to outlive this node.
+func (c *tieredCache[T]) Dump(ctx context.Context) ([]byte, error) {
+	return c.l1.Dump(ctx)
+}
+
+// Restore only restores l1, l2 is expected to outlive this node.
+func (c *tieredCache[T]) Restore(ctx context.Context, data []byte) error {
+	return c.l1.Restore(ctx, data)
+}
+
+func (c *tieredCache[T]) Clear(ctx context.Context) {
+	c.l1.Clear(ctx)
+	c.l2.Clear(ctx)
+}
@@ -0,0 +1,84 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+)
+
+func newMemoryCache(t *testing.T) cache.Cache[string] {
+	t.Helper()
+	c, err := cache.New[string](cache.Config[string]{
+		MaxSize: 10_000,
+		Fresh:   time.Minute,
+		Stale:   time.Minute * 5,
+		RefreshFromOrigin: func(ctx context.Context, id string) (string, bool) {
+			return "hello", true
+		},
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestTieredBackfillsL1(t *testing.T) {
+	ctx := context.Background()
+	l1 := newMemoryCache(t)
+	l2 := newMemoryCache(t)
+	c := cache.NewTiered(l1, l2)
+
+	l2.Set(ctx, "key", "value")
+
+	value, hit := c.Get(ctx, "key")
+	require.Equal(t, cache.Hit, hit)
+	require.Equal(t, "value", value)
+
+	value, hit = l1.Get(ctx, "key")
+	require.Equal(t, cache.Hit, hit)
+	require.Equal(t, "value", value)
+}
+
+func TestTieredWritesBothTiers(t *testing.T) {
+	ctx := context.Background()
+	l1 := newMemoryCache(t)
+	l2 := newMemoryCache(t)
+	c := cache.NewTiered(l1, l2)
+
+	c.Set(ctx, "key", "value")
+	_, hit := l1.Get(ctx, "key")
+	require.Equal(t, cache.Hit, hit)
+	_, hit = l2.Get(ctx, "key")
+	require.Equal(t, cache.Hit, hit)
+
+	c.Remove(ctx, "key")
+	_, hit = c.Get(ctx, "key")
+	require.Equal(t, cache.Miss, hit)
+	_, hit = l2.Get(ctx, "key")
+	require.Equal(t, cache.Miss, hit)
+}
+
+func TestTieredGetMany(t *testing.T) {
+	ctx := context.Background()
+	l1 := newMemoryCache(t)
+	l2 := newMemoryCache(t)
+	c := cache.NewTiered(l1, l2)
+
+	l1.Set(ctx, "a", "1")
+	l2.SetMany(ctx, map[string]string{"b": "2", "c": "3"})
+
+	values, hits := c.GetMany(ctx, []string{"a", "b", "missing", "c"})
+	require.Equal(t, []string{"1", "2", "", "3"}, values)
+	require.Equal(t, []cache.CacheHit{cache.Hit, cache.Hit, cache.Miss, cache.Hit}, hits)
+
+	// b and c were back-filled
+	values, hits = l1.GetMany(ctx, []string{"b", "c"})
+	require.Equal(t, []string{"2", "3"}, values)
+	require.Equal(t, []cache.CacheHit{cache.Hit, cache.Hit}, hits)
+}
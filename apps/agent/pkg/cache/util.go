@@ -2,32 +2,63 @@ package cache
 
 import (
 	"context"
+
+	"golang.org/x/sync/singleflight"
 )
 
-// withCache builds a pullthrough cache function to wrap a database call.
+type loadResult[T any] struct {
+	value T
+	found bool
+}
+
+// Loader is a pullthrough cache in front of an origin, such as the database.
+// Concurrent misses for the same key are deduplicated, so the origin is only
+// called once and all callers receive the same result. Create it once
+// alongside the cache and share it between all callers, or nothing is
+// deduplicated.
 // Example:
-// api, found, err := withCache(s.apiCache, s.db.FindApiByKeyAuthId)(ctx, key.KeyAuthId)
-func WithCache[T any](c Cache[T], loadFromDatabase func(ctx context.Context, identifier string) (T, bool, error)) func(ctx context.Context, identifier string) (T, bool, error) {
-	return func(ctx context.Context, identifier string) (T, bool, error) {
-		value, hit := c.Get(ctx, identifier)
+//
+//	s.apiLoader = cache.NewLoader(s.apiCache)
+//	api, found, err := s.apiLoader.GetOrLoad(ctx, keyAuthId, func(ctx context.Context) (Api, bool, error) {
+//		return s.db.FindApiByKeyAuthId(ctx, keyAuthId)
+//	})
+type Loader[T any] struct {
+	cache Cache[T]
+	group singleflight.Group
+}
 
-		if hit == Hit {
-			return value, true, nil
-		}
-		if hit == Null {
-			return value, false, nil
-		}
+func NewLoader[T any](c Cache[T]) *Loader[T] {
+	return &Loader[T]{cache: c}
+}
+
+// GetOrLoad returns the cached value for key, or calls load on a miss and
+// caches its result. Values load did not find are cached as null.
+func (l *Loader[T]) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) (T, bool, error)) (T, bool, error) {
+	value, hit := l.cache.Get(ctx, key)
+
+	if hit == Hit {
+		return value, true, nil
+	}
+	if hit == Null {
+		return value, false, nil
+	}
 
-		value, found, err := loadFromDatabase(ctx, identifier)
+	res, err, _ := l.group.Do(key, func() (any, error) {
+		value, found, err := load(ctx)
 		if err != nil {
-			return value, false, err
+			return nil, err
 		}
 		if found {
-			c.Set(ctx, identifier, value)
-			return value, true, nil
+			l.cache.Set(ctx, key, value)
 		} else {
-			c.SetNull(ctx, identifier)
-			return value, false, nil
+			l.cache.SetNull(ctx, key)
 		}
+		return loadResult[T]{value: value, found: found}, nil
+	})
+	if err != nil {
+		var t T
+		return t, false, err
 	}
+	loaded := res.(loadResult[T])
+	return loaded.value, loaded.found, nil
 }
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Warmup loads the given keys from the origin into the cache, so the first
+// requests after a restart don't all miss and hit the origin at once.
+//
+// Keys are loaded with up to `concurrency` parallel calls to load. Keys not
+// found in the origin are cached as null. Errors for individual keys do not
+// stop the warmup, they are joined and returned at the end.
+func Warmup[T any](ctx context.Context, c Cache[T], keys []string, concurrency int, load func(ctx context.Context, key string) (T, bool, error)) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	keysC := make(chan string)
+	errorsMu := sync.Mutex{}
+	errs := []error{}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keysC {
+				value, found, err := load(ctx, key)
+				if err != nil {
+					errorsMu.Lock()
+					errs = append(errs, err)
+					errorsMu.Unlock()
+					continue
+				}
+				if found {
+					c.Set(ctx, key, value)
+				} else {
+					c.SetNull(ctx, key)
+				}
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		select {
+		case keysC <- key:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(keysC)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,19 @@
+package cache
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoWeigher weighs protobuf messages by their encoded size in bytes plus
+// the length of their key, so MaxSize becomes an approximate memory budget.
+//
+// Weights are capped at math.MaxUint32.
+func ProtoWeigher[T proto.Message]() func(key string, value T) uint32 {
+	return func(key string, value T) uint32 {
+		size := uint64(len(key)) + uint64(proto.Size(value))
+		if size > 1<<32-1 {
+			return 1<<32 - 1
+		}
+		return uint32(size)
+	}
+}
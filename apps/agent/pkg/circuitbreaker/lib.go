@@ -130,10 +130,10 @@ func New[Res any](name string, applyConfigs ...applyConfig) *CB[Res] {
 	cb := &CB[Res]{
 		config:          cfg,
 		logger:          cfg.logger,
-		state:           Closed,
 		resetCountersAt: cfg.clock.Now().Add(cfg.cyclicPeriod),
 		resetStateAt:    cfg.clock.Now().Add(cfg.timeout),
 	}
+	cb.setState(Closed)
 
 	return cb
 }
@@ -177,7 +177,7 @@ func (cb *CB[Res]) preflight(ctx context.Context) error {
 		cb.resetCountersAt = now.Add(cb.config.cyclicPeriod)
 	}
 	if cb.state == Open && now.After(cb.resetStateAt) {
-		cb.state = HalfOpen
+		cb.setState(HalfOpen)
 		cb.resetStateAt = now.Add(cb.config.timeout)
 	}
 
@@ -215,13 +215,29 @@ func (cb *CB[Res]) postflight(ctx context.Context, err error) {
 
 	case Closed:
 		if cb.failures >= cb.config.tripThreshold {
-			cb.state = Open
+			cb.setState(Open)
 		}
 
 	case HalfOpen:
 		if cb.consecutiveSuccesses >= cb.config.maxRequests {
-			cb.state = Closed
+			cb.setState(Closed)
 		}
 	}
 
 }
+
+// setState transitions the circuit and reports it, must be called while
+// holding the lock
+func (cb *CB[Res]) setState(state State) {
+	if cb.state != "" && cb.state != state {
+		cb.logger.Info().Str("name", cb.config.name).Str("from", string(cb.state)).Str("to", string(state)).Msg("circuit breaker state changed")
+	}
+	cb.state = state
+	for _, s := range []State{Open, HalfOpen, Closed} {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		states.WithLabelValues(cb.config.name, string(s)).Set(value)
+	}
+}
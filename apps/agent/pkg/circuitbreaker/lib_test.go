@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"github.com/unkeyed/unkey/apps/agent/pkg/clock"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
@@ -91,3 +92,25 @@ func TestCircuitBreakerRecovers(t *testing.T) {
 	// Circuit should close
 	require.Equal(t, Closed, cb.state)
 }
+
+func TestCircuitBreakerStateMetric(t *testing.T) {
+
+	c := clock.NewTestClock()
+	cb := New[int]("test_state_metric", WithClock(c), WithTripThreshold(1), WithTimeout(20*time.Second))
+	require.Equal(t, 1.0, testutil.ToFloat64(states.WithLabelValues("test_state_metric", string(Closed))))
+
+	_, err := cb.Do(context.Background(), func(ctx context.Context) (int, error) {
+		return 0, errTestDownstream
+	})
+	require.ErrorIs(t, err, errTestDownstream)
+	require.Equal(t, 1.0, testutil.ToFloat64(states.WithLabelValues("test_state_metric", string(Open))))
+	require.Equal(t, 0.0, testutil.ToFloat64(states.WithLabelValues("test_state_metric", string(Closed))))
+
+	c.Tick(30 * time.Second)
+	_, err = cb.Do(context.Background(), func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1.0, testutil.ToFloat64(states.WithLabelValues("test_state_metric", string(HalfOpen))))
+	require.Equal(t, 0.0, testutil.ToFloat64(states.WithLabelValues("test_state_metric", string(Open))))
+}
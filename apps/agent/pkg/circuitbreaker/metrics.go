@@ -11,4 +11,11 @@ var (
 		Subsystem: "circuitbreaker",
 		Name:      "requests",
 	}, []string{"name", "state"})
+
+	states = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "agent",
+		Subsystem: "circuitbreaker",
+		Name:      "state",
+		Help:      "1 for the current state of each circuit breaker, 0 for the others",
+	}, []string{"name", "state"})
 )
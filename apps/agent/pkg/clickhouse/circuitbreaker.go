@@ -0,0 +1,91 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/circuitbreaker"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+// WithCircuitBreaker fails queries fast while clickhouse is unavailable,
+// instead of letting every caller wait for its own timeout.
+func WithCircuitBreaker(q Querier, logger logging.Logger) Querier {
+	return &circuitBreaker{
+		next: q,
+		cb: circuitbreaker.New[any](
+			"clickhouse.query",
+			circuitbreaker.WithLogger(logger),
+			circuitbreaker.WithCyclicPeriod(10*time.Second),
+			circuitbreaker.WithTimeout(30*time.Second),
+			circuitbreaker.WithMaxRequests(5),
+			circuitbreaker.WithTripThreshold(10),
+			// The caller giving up says nothing about clickhouse
+			circuitbreaker.WithIsDownstreamError(func(err error) bool {
+				return err != nil && !errors.Is(err, context.Canceled)
+			}),
+		),
+	}
+}
+
+type circuitBreaker struct {
+	next Querier
+	cb   circuitbreaker.CircuitBreaker[any]
+}
+
+var _ Querier = &circuitBreaker{}
+
+// do adapts a typed query to the untyped circuit breaker
+func do[T any](ctx context.Context, cb circuitbreaker.CircuitBreaker[any], fn func(context.Context) (T, error)) (T, error) {
+	res, err := cb.Do(ctx, func(ctx context.Context) (any, error) {
+		return fn(ctx)
+	})
+	if err != nil {
+		var t T
+		return t, err
+	}
+	return res.(T), nil
+}
+
+func (mw *circuitBreaker) GetKeyStats(ctx context.Context, req KeyStatsRequest) ([]StatsBucket, error) {
+	return do(ctx, mw.cb, func(ctx context.Context) ([]StatsBucket, error) {
+		return mw.next.GetKeyStats(ctx, req)
+	})
+}
+
+func (mw *circuitBreaker) GetApiStats(ctx context.Context, req ApiStatsRequest) (UsageStats, error) {
+	return do(ctx, mw.cb, func(ctx context.Context) (UsageStats, error) {
+		return mw.next.GetApiStats(ctx, req)
+	})
+}
+
+func (mw *circuitBreaker) GetWorkspaceStats(ctx context.Context, req WorkspaceStatsRequest) (UsageStats, error) {
+	return do(ctx, mw.cb, func(ctx context.Context) (UsageStats, error) {
+		return mw.next.GetWorkspaceStats(ctx, req)
+	})
+}
+
+func (mw *circuitBreaker) GetOwnerStats(ctx context.Context, req OwnerStatsRequest) (UsageStats, error) {
+	return do(ctx, mw.cb, func(ctx context.Context) (UsageStats, error) {
+		return mw.next.GetOwnerStats(ctx, req)
+	})
+}
+
+func (mw *circuitBreaker) GetMonthlyActiveKeys(ctx context.Context, req MonthlyActiveKeysRequest) ([]MonthlyActiveKeys, error) {
+	return do(ctx, mw.cb, func(ctx context.Context) ([]MonthlyActiveKeys, error) {
+		return mw.next.GetMonthlyActiveKeys(ctx, req)
+	})
+}
+
+func (mw *circuitBreaker) GetUsageRecords(ctx context.Context, req UsageRecordsRequest) ([]UsageRecord, error) {
+	return do(ctx, mw.cb, func(ctx context.Context) ([]UsageRecord, error) {
+		return mw.next.GetUsageRecords(ctx, req)
+	})
+}
+
+func (mw *circuitBreaker) GetLatencyStats(ctx context.Context, req LatencyStatsRequest) (LatencyStats, error) {
+	return do(ctx, mw.cb, func(ctx context.Context) (LatencyStats, error) {
+		return mw.next.GetLatencyStats(ctx, req)
+	})
+}
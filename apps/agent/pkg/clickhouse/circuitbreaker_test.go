@@ -0,0 +1,45 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/circuitbreaker"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+// unavailable fails every query
+type unavailable struct {
+	noop
+	calls int
+}
+
+func (u *unavailable) GetKeyStats(ctx context.Context, req KeyStatsRequest) ([]StatsBucket, error) {
+	u.calls++
+	return nil, errors.New("connection refused")
+}
+
+func TestCircuitBreakerFailsFast(t *testing.T) {
+	ctx := context.Background()
+	u := &unavailable{}
+	q := WithCircuitBreaker(u, logging.NewNoopLogger())
+
+	for range 10 {
+		_, err := q.GetKeyStats(ctx, KeyStatsRequest{})
+		require.ErrorContains(t, err, "connection refused")
+	}
+
+	_, err := q.GetKeyStats(ctx, KeyStatsRequest{})
+	require.ErrorIs(t, err, circuitbreaker.ErrTripped)
+	require.Equal(t, 10, u.calls)
+}
+
+func TestCircuitBreakerPassesResults(t *testing.T) {
+	q := WithCircuitBreaker(NewNoop(), logging.NewNoopLogger())
+
+	stats, err := q.GetApiStats(context.Background(), ApiStatsRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, stats.Outcomes)
+}
@@ -2,6 +2,7 @@ package clickhouse
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	ch "github.com/ClickHouse/clickhouse-go/v2"
@@ -10,20 +11,32 @@ import (
 	"github.com/unkeyed/unkey/apps/agent/pkg/batch"
 	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse/schema"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/prometheus"
+	"github.com/unkeyed/unkey/apps/agent/pkg/repeat"
 	"github.com/unkeyed/unkey/apps/agent/pkg/util"
 )
 
+var _ Bufferer = &Clickhouse{}
+var _ Querier = &Clickhouse{}
+
 type Clickhouse struct {
 	conn   ch.Conn
 	logger logging.Logger
 
 	requests         *batch.BatchProcessor[schema.ApiRequestV1]
 	keyVerifications *batch.BatchProcessor[schema.KeyVerificationRequestV1]
+
+	// stops replaying dead letters
+	stopReplaying []func()
 }
 
 type Config struct {
 	URL    string
 	Logger logging.Logger
+
+	// If set, rows that can not be inserted are written to this directory and
+	// replayed once clickhouse is available again.
+	DeadLetterDir string
 }
 
 func New(config Config) (*Clickhouse, error) {
@@ -53,37 +66,24 @@ func New(config Config) (*Clickhouse, error) {
 		return nil, fault.Wrap(err, fmsg.With("pinging clickhouse failed"))
 	}
 	c := &Clickhouse{
-		conn:   conn,
-		logger: config.Logger,
-
-		requests: batch.New[schema.ApiRequestV1](batch.Config[schema.ApiRequestV1]{
-			BatchSize:     1000,
-			BufferSize:    100000,
-			FlushInterval: time.Second,
-			Consumers:     4,
-			Flush: func(ctx context.Context, rows []schema.ApiRequestV1) {
-				table := "raw_api_requests_v1"
-				err := flush(ctx, conn, table, rows)
-				if err != nil {
-					config.Logger.Error().Err(err).Str("table", table).Msg("failed to flush batch")
-				}
-			},
-		}),
-		keyVerifications: batch.New[schema.KeyVerificationRequestV1](batch.Config[schema.KeyVerificationRequestV1]{
-			BatchSize:     1000,
-			BufferSize:    100000,
-			FlushInterval: time.Second,
-			Consumers:     4,
-			Flush: func(ctx context.Context, rows []schema.KeyVerificationRequestV1) {
-				table := "raw_key_verifications_v1"
-				err := flush(ctx, conn, table, rows)
-				if err != nil {
-					config.Logger.Error().Err(err).Str("table", table).Msg("failed to flush batch")
-				}
-			},
-		}),
+		conn:          conn,
+		logger:        config.Logger,
+		stopReplaying: []func(){},
 	}
 
+	var stop func()
+	c.requests, stop, err = newBuffer[schema.ApiRequestV1](conn, "raw_api_requests_v1", config.DeadLetterDir, config.Logger)
+	if err != nil {
+		return nil, err
+	}
+	c.stopReplaying = append(c.stopReplaying, stop)
+
+	c.keyVerifications, stop, err = newBuffer[schema.KeyVerificationRequestV1](conn, "raw_key_verifications_v1", config.DeadLetterDir, config.Logger)
+	if err != nil {
+		return nil, err
+	}
+	c.stopReplaying = append(c.stopReplaying, stop)
+
 	// err = c.conn.Ping(context.Background())
 	// if err != nil {
 	// 	return nil, fault.Wrap(err, fmsg.With("pinging clickhouse failed"))
@@ -91,11 +91,96 @@ func New(config Config) (*Clickhouse, error) {
 	return c, nil
 }
 
+// queryContext passes the deadline of ctx on to clickhouse as the
+// max_execution_time of a query, so the server gives up on it as well,
+// instead of only us.
+func queryContext(ctx context.Context) context.Context {
+	return ch.Context(ctx)
+}
+
+// Ping checks that clickhouse is reachable.
+func (c *Clickhouse) Ping(ctx context.Context) error {
+	return c.conn.Ping(ctx)
+}
+
+// Flush inserts all buffered rows without waiting for the flush interval.
+func (c *Clickhouse) Flush(ctx context.Context) error {
+	err := c.requests.Flush(ctx)
+	if err != nil {
+		return err
+	}
+	return c.keyVerifications.Flush(ctx)
+}
+
 func (c *Clickhouse) Shutdown(ctx context.Context) error {
+	for _, stop := range c.stopReplaying {
+		stop()
+	}
 	c.requests.Close()
+	c.keyVerifications.Close()
 	return c.conn.Close()
 }
 
+// newBuffer batches rows for the table and inserts them in the background.
+//
+// Buffering never blocks the caller: when clickhouse is slow or down, the buffer
+// fills up and new rows are dropped, rather than slowing down requests.
+// Failed inserts are retried with exponential backoff. If deadLetterDir is set,
+// rows are spilled to disk after the last retry and replayed every 30 seconds.
+//
+// The returned function stops replaying.
+func newBuffer[T any](conn ch.Conn, table string, deadLetterDir string, logger logging.Logger) (*batch.BatchProcessor[T], func(), error) {
+	var dlq *DeadLetterQueue[T]
+	stop := func() {}
+	if deadLetterDir != "" {
+		var err error
+		dlq, err = NewDeadLetterQueue[T](deadLetterDir, table, DefaultDeadLetterMaxBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		stop = repeat.Every(30*time.Second, func() {
+			replayed, replayErr := dlq.Replay(1000, func(rows []T) error {
+				return flush(context.Background(), conn, table, rows)
+			})
+			if replayed > 0 {
+				logger.Info().Str("table", table).Int("rows", replayed).Msg("replayed dead letters")
+			}
+			if replayErr != nil {
+				logger.Warn().Err(replayErr).Str("table", table).Msg("failed to replay dead letters")
+			}
+		})
+	}
+
+	return batch.New[T](batch.Config[T]{
+		Name:          table,
+		Drop:          true,
+		BatchSize:     1000,
+		BufferSize:    100000,
+		FlushInterval: time.Second,
+		Consumers:     4,
+		Flush: func(ctx context.Context, rows []T) {
+			err := util.Retry(func() error {
+				return flush(ctx, conn, table, rows)
+			}, 5, func(n int) time.Duration {
+				return 100 * time.Millisecond << n
+			})
+			if err == nil {
+				return
+			}
+			if dlq != nil {
+				dlqErr := dlq.Write(rows)
+				if dlqErr == nil {
+					logger.Warn().Err(err).Str("table", table).Int("rows", len(rows)).Msg("failed to flush batch, wrote rows to dead letter queue")
+					return
+				}
+				err = errors.Join(err, dlqErr)
+			}
+			prometheus.ClickhouseFailedRows.WithLabelValues(table).Add(float64(len(rows)))
+			logger.Error().Err(err).Str("table", table).Int("rows", len(rows)).Msg("failed to flush batch")
+		},
+	}), stop, nil
+}
+
 func (c *Clickhouse) BufferApiRequest(req schema.ApiRequestV1) {
 	c.requests.Buffer(req)
 }
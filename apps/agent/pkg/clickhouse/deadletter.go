@@ -0,0 +1,268 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+)
+
+// DefaultDeadLetterMaxBytes caps the files of a single dead letter queue.
+const DefaultDeadLetterMaxBytes = 1 << 30
+
+// ErrDeadLetterQueueFull is returned by Write once the queue reached its size limit.
+var ErrDeadLetterQueueFull = errors.New("dead letter queue is full")
+
+// DeadLetterQueue spills rows that could not be inserted to a local file, so
+// they can be replayed once clickhouse recovers, rather than being lost. The
+// eventrouter spills batches for tinybird the same way.
+//
+// Rows are stored as json lines in <dir>/<table>.jsonl. Replaying moves that
+// file to <dir>/<table>.replaying, so rows can be written while replaying, and
+// records its progress in <dir>/<table>.offset. Rows are replayed at least
+// once: if the process stops between an insert and recording the progress,
+// that batch is inserted again.
+type DeadLetterQueue[T any] struct {
+	mu sync.Mutex
+	// only one replay at a time
+	replayMu  sync.Mutex
+	path      string
+	replaying string
+	offset    string
+	maxBytes  int64
+}
+
+// NewDeadLetterQueue stores rows for the table in dir. Once its files take up
+// more than maxBytes, further writes are rejected until rows were replayed.
+func NewDeadLetterQueue[T any](dir string, table string, maxBytes int64) (*DeadLetterQueue[T], error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fault.Wrap(err, fmsg.With("failed to create dead letter directory"))
+	}
+	return &DeadLetterQueue[T]{
+		path:      filepath.Join(dir, fmt.Sprintf("%s.jsonl", table)),
+		replaying: filepath.Join(dir, fmt.Sprintf("%s.replaying", table)),
+		offset:    filepath.Join(dir, fmt.Sprintf("%s.offset", table)),
+		maxBytes:  maxBytes,
+	}, nil
+}
+
+// Write appends the rows to the queue.
+func (q *DeadLetterQueue[T]) Write(rows []T) error {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	for _, row := range rows {
+		err := enc.Encode(row)
+		if err != nil {
+			return fault.Wrap(err, fmsg.With("failed to encode row"))
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	size, err := q.size()
+	if err != nil {
+		return err
+	}
+	if size+int64(buf.Len()) > q.maxBytes {
+		return ErrDeadLetterQueueFull
+	}
+
+	f, err := os.OpenFile(q.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to open dead letter file"))
+	}
+	defer f.Close()
+
+	_, err = f.Write(buf.Bytes())
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to write dead letter file"))
+	}
+	return f.Sync()
+}
+
+// size must be called with the lock held
+func (q *DeadLetterQueue[T]) size() (int64, error) {
+	size := int64(0)
+	for _, path := range []string{q.path, q.replaying} {
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, fault.Wrap(err, fmsg.With("failed to stat dead letter file"))
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// Replay reads the queued rows and passes them to insert in batches of batchSize.
+// If insert fails, the remaining rows stay queued and the error is returned.
+//
+// Rows written while replaying are kept and replayed next time.
+func (q *DeadLetterQueue[T]) Replay(batchSize int, insert func(rows []T) error) (int, error) {
+	q.replayMu.Lock()
+	defer q.replayMu.Unlock()
+
+	f, offset, err := q.startReplay()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	replayed := 0
+	rows := []T{}
+	next := offset
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+		err := insert(rows)
+		if err != nil {
+			return err
+		}
+		replayed += len(rows)
+		rows = rows[:0]
+		offset = next
+		return q.saveOffset(offset)
+	}
+
+	for {
+		line, readErr := r.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return replayed, fault.Wrap(readErr, fmsg.With("failed to read dead letter file"))
+		}
+		if len(line) > 0 {
+			var row T
+			decodeErr := json.Unmarshal(line, &row)
+			if decodeErr != nil {
+				// Keep what we could read and move the rest out of the way,
+				// otherwise we'd never replay anything again
+				err = flush()
+				if err != nil {
+					return replayed, err
+				}
+				return replayed, errors.Join(
+					fault.Wrap(decodeErr, fmsg.With("failed to decode dead letter row")),
+					q.moveCorrupt(f),
+				)
+			}
+			next += int64(len(line))
+			rows = append(rows, row)
+			if len(rows) >= batchSize {
+				err = flush()
+				if err != nil {
+					return replayed, err
+				}
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	err = flush()
+	if err != nil {
+		return replayed, err
+	}
+	return replayed, q.finishReplay(f)
+}
+
+// startReplay opens the file being replayed, which is either left over from
+// an interrupted replay or the current queue, and seeks to where replaying
+// stopped last time.
+func (q *DeadLetterQueue[T]) startReplay() (*os.File, int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	_, err := os.Stat(q.replaying)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = os.Rename(q.path, q.replaying)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := int64(0)
+	b, err := os.ReadFile(q.offset)
+	if err == nil {
+		offset, err = strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+		if err != nil {
+			return nil, 0, fault.Wrap(err, fmsg.With("failed to parse dead letter offset"))
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, fault.Wrap(err, fmsg.With("failed to read dead letter offset"))
+	}
+
+	f, err := os.Open(q.replaying)
+	if err != nil {
+		return nil, 0, fault.Wrap(err, fmsg.With("failed to open dead letter file"))
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return nil, 0, fault.Wrap(err, fmsg.With("failed to seek dead letter file"))
+	}
+	return f, offset, nil
+}
+
+// saveOffset records that everything before offset was inserted.
+func (q *DeadLetterQueue[T]) saveOffset(offset int64) error {
+	tmp := q.offset + ".tmp"
+	err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)), 0o644)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to write dead letter offset"))
+	}
+	err = os.Rename(tmp, q.offset)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to write dead letter offset"))
+	}
+	return nil
+}
+
+func (q *DeadLetterQueue[T]) finishReplay(f *os.File) error {
+	f.Close()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	err := os.Remove(q.replaying)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to remove dead letter file"))
+	}
+	return q.removeOffset()
+}
+
+func (q *DeadLetterQueue[T]) moveCorrupt(f *os.File) error {
+	f.Close()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	err := os.Rename(q.replaying, q.path+".corrupt")
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to move corrupt dead letter file"))
+	}
+	return q.removeOffset()
+}
+
+// removeOffset must be called with the lock held
+func (q *DeadLetterQueue[T]) removeOffset() error {
+	err := os.Remove(q.offset)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fault.Wrap(err, fmsg.With("failed to remove dead letter offset"))
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package clickhouse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse/schema"
+)
+
+func TestDeadLetterQueueReplaysAfterFailure(t *testing.T) {
+	q, err := NewDeadLetterQueue[schema.KeyVerificationRequestV1](t.TempDir(), "raw_key_verifications_v1", DefaultDeadLetterMaxBytes)
+	require.NoError(t, err)
+
+	rows := []schema.KeyVerificationRequestV1{}
+	for i := 0; i < 5; i++ {
+		rows = append(rows, schema.KeyVerificationRequestV1{RequestID: string(rune('a' + i)), Outcome: "VALID"})
+	}
+	require.NoError(t, q.Write(rows[:3]))
+	require.NoError(t, q.Write(rows[3:]))
+
+	// The first batch succeeds, then clickhouse goes down again
+	calls := 0
+	replayed, err := q.Replay(2, func(batch []schema.KeyVerificationRequestV1) error {
+		calls++
+		if calls > 1 {
+			return errors.New("clickhouse is down")
+		}
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, 2, replayed)
+
+	inserted := []schema.KeyVerificationRequestV1{}
+	replayed, err = q.Replay(2, func(batch []schema.KeyVerificationRequestV1) error {
+		inserted = append(inserted, batch...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, replayed)
+	require.Equal(t, rows[2:], inserted)
+
+	// Nothing left
+	replayed, err = q.Replay(2, func(batch []schema.KeyVerificationRequestV1) error {
+		t.Fatal("nothing should be replayed")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, replayed)
+}
+
+func TestDeadLetterQueueMovesCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewDeadLetterQueue[schema.KeyVerificationRequestV1](dir, "raw_key_verifications_v1", DefaultDeadLetterMaxBytes)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "raw_key_verifications_v1.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"RequestID":"a"}`+"\n"+`{"Reque`), 0o644))
+
+	_, err = q.Replay(10, func(batch []schema.KeyVerificationRequestV1) error { return nil })
+	require.Error(t, err)
+	require.FileExists(t, path+".corrupt")
+	require.NoFileExists(t, path)
+}
+
+func TestDeadLetterQueueResumesInterruptedReplay(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewDeadLetterQueue[schema.KeyVerificationRequestV1](dir, "raw_key_verifications_v1", DefaultDeadLetterMaxBytes)
+	require.NoError(t, err)
+
+	rows := []schema.KeyVerificationRequestV1{}
+	for i := 0; i < 5; i++ {
+		rows = append(rows, schema.KeyVerificationRequestV1{RequestID: string(rune('a' + i)), Outcome: "VALID"})
+	}
+	require.NoError(t, q.Write(rows))
+
+	// The process stops while the second batch is inserted, a new row is
+	// written in the meantime
+	calls := 0
+	replayed, err := q.Replay(2, func(batch []schema.KeyVerificationRequestV1) error {
+		calls++
+		if calls > 1 {
+			require.NoError(t, q.Write([]schema.KeyVerificationRequestV1{{RequestID: "f"}}))
+			return errors.New("killed")
+		}
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, 2, replayed)
+
+	// After a restart, replaying picks up where it stopped and then
+	// continues with the rows written since
+	q, err = NewDeadLetterQueue[schema.KeyVerificationRequestV1](dir, "raw_key_verifications_v1", DefaultDeadLetterMaxBytes)
+	require.NoError(t, err)
+	inserted := []string{}
+	insert := func(batch []schema.KeyVerificationRequestV1) error {
+		for _, row := range batch {
+			inserted = append(inserted, row.RequestID)
+		}
+		return nil
+	}
+	replayed, err = q.Replay(2, insert)
+	require.NoError(t, err)
+	require.Equal(t, 3, replayed)
+	replayed, err = q.Replay(2, insert)
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+	require.Equal(t, []string{"c", "d", "e", "f"}, inserted)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestDeadLetterQueueRejectsWritesWhenFull(t *testing.T) {
+	q, err := NewDeadLetterQueue[schema.KeyVerificationRequestV1](t.TempDir(), "raw_key_verifications_v1", 200)
+	require.NoError(t, err)
+
+	row := schema.KeyVerificationRequestV1{RequestID: "a", Outcome: "VALID"}
+	err = nil
+	written := 0
+	for err == nil {
+		err = q.Write([]schema.KeyVerificationRequestV1{row})
+		if err == nil {
+			written++
+		}
+	}
+	require.ErrorIs(t, err, ErrDeadLetterQueueFull)
+	require.Greater(t, written, 0)
+
+	// Replaying makes room again
+	replayed, err := q.Replay(10, func(batch []schema.KeyVerificationRequestV1) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, written, replayed)
+	require.NoError(t, q.Write([]schema.KeyVerificationRequestV1{row}))
+}
@@ -0,0 +1,83 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/repeat"
+)
+
+type ExportConfig struct {
+	// The bucket and optional prefix to export to, e.g. https://my-bucket.s3.us-east-1.amazonaws.com/unkey
+	// Any s3 compatible storage works, including GCS via https://storage.googleapis.com/my-bucket
+	Url             string
+	AccessKeyId     string
+	AccessKeySecret string
+}
+
+// ExportKeyVerifications writes all verifications of the given UTC day to s3 as
+// parquet, one file per workspace:
+//
+//	<url>/date=2024-01-01/workspace_id=ws_123/key_verifications.parquet
+//
+// Exporting the same day again overwrites the files, so it is safe to retry.
+func (c *Clickhouse) ExportKeyVerifications(ctx context.Context, config ExportConfig, day time.Time) error {
+	query, args := exportQuery(config, day)
+	err := c.conn.Exec(queryContext(ctx), query, args...)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With(fmt.Sprintf("failed to export key verifications of %s", day.Format(time.DateOnly))))
+	}
+	return nil
+}
+
+// StartExport exports the previous day once per day, starting now.
+// Only one node in the cluster needs to run this.
+//
+// The returned function stops exporting.
+func (c *Clickhouse) StartExport(config ExportConfig) func() {
+	mu := sync.Mutex{}
+	var lastExported time.Time
+	export := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		yesterday := time.Now().UTC().Truncate(24 * time.Hour).Add(-24 * time.Hour)
+		if yesterday.Equal(lastExported) {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		err := c.ExportKeyVerifications(ctx, config, yesterday)
+		if err != nil {
+			// We try again next hour
+			c.logger.Error().Err(err).Msg("failed to export key verifications")
+			return
+		}
+		lastExported = yesterday
+		c.logger.Info().Str("date", yesterday.Format(time.DateOnly)).Msg("exported key verifications")
+	}
+
+	go export()
+	return repeat.Every(time.Hour, export)
+}
+
+func exportQuery(config ExportConfig, day time.Time) (string, []any) {
+	start := day.UTC().Truncate(24 * time.Hour)
+	end := start.Add(24 * time.Hour)
+
+	url := fmt.Sprintf("%s/date=%s/workspace_id={_partition_id}/key_verifications.parquet", strings.TrimSuffix(config.Url, "/"), start.Format(time.DateOnly))
+
+	query := `
+INSERT INTO FUNCTION s3(?, ?, ?, 'Parquet')
+PARTITION BY workspace_id
+SELECT * FROM default.raw_key_verifications_v1
+WHERE time >= ? AND time < ?
+SETTINGS s3_truncate_on_insert = 1
+`
+	return query, []any{url, config.AccessKeyId, config.AccessKeySecret, start.UnixMilli(), end.UnixMilli()}
+}
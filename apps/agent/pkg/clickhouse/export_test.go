@@ -0,0 +1,30 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportQuery(t *testing.T) {
+	day := time.Date(2024, 3, 14, 15, 9, 26, 0, time.UTC)
+
+	query, args := exportQuery(ExportConfig{
+		Url:             "https://bucket.s3.amazonaws.com/unkey/",
+		AccessKeyId:     "id",
+		AccessKeySecret: "secret",
+	}, day)
+
+	require.Contains(t, query, "'Parquet'")
+	require.Contains(t, query, "PARTITION BY workspace_id")
+
+	start := time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, []any{
+		"https://bucket.s3.amazonaws.com/unkey/date=2024-03-14/workspace_id={_partition_id}/key_verifications.parquet",
+		"id",
+		"secret",
+		start.UnixMilli(),
+		start.Add(24 * time.Hour).UnixMilli(),
+	}, args)
+}
@@ -1,6 +1,8 @@
 package clickhouse
 
 import (
+	"context"
+
 	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse/schema"
 )
 
@@ -8,3 +10,13 @@ type Bufferer interface {
 	BufferApiRequest(schema.ApiRequestV1)
 	BufferKeyVerification(schema.KeyVerificationRequestV1)
 }
+
+type Querier interface {
+	GetKeyStats(ctx context.Context, req KeyStatsRequest) ([]StatsBucket, error)
+	GetApiStats(ctx context.Context, req ApiStatsRequest) (UsageStats, error)
+	GetWorkspaceStats(ctx context.Context, req WorkspaceStatsRequest) (UsageStats, error)
+	GetOwnerStats(ctx context.Context, req OwnerStatsRequest) (UsageStats, error)
+	GetMonthlyActiveKeys(ctx context.Context, req MonthlyActiveKeysRequest) ([]MonthlyActiveKeys, error)
+	GetUsageRecords(ctx context.Context, req UsageRecordsRequest) ([]UsageRecord, error)
+	GetLatencyStats(ctx context.Context, req LatencyStatsRequest) (LatencyStats, error)
+}
@@ -1,12 +1,15 @@
 package clickhouse
 
 import (
+	"context"
+
 	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse/schema"
 )
 
 type noop struct{}
 
 var _ Bufferer = &noop{}
+var _ Querier = &noop{}
 
 func (n *noop) BufferApiRequest(schema.ApiRequestV1) {
 
@@ -15,6 +18,34 @@ func (n *noop) BufferKeyVerification(schema.KeyVerificationRequestV1) {
 
 }
 
+func (n *noop) GetKeyStats(ctx context.Context, req KeyStatsRequest) ([]StatsBucket, error) {
+	return []StatsBucket{}, nil
+}
+
+func (n *noop) GetApiStats(ctx context.Context, req ApiStatsRequest) (UsageStats, error) {
+	return UsageStats{Outcomes: map[string]uint64{}}, nil
+}
+
+func (n *noop) GetWorkspaceStats(ctx context.Context, req WorkspaceStatsRequest) (UsageStats, error) {
+	return UsageStats{Outcomes: map[string]uint64{}}, nil
+}
+
+func (n *noop) GetOwnerStats(ctx context.Context, req OwnerStatsRequest) (UsageStats, error) {
+	return UsageStats{Outcomes: map[string]uint64{}}, nil
+}
+
+func (n *noop) GetMonthlyActiveKeys(ctx context.Context, req MonthlyActiveKeysRequest) ([]MonthlyActiveKeys, error) {
+	return []MonthlyActiveKeys{}, nil
+}
+
+func (n *noop) GetUsageRecords(ctx context.Context, req UsageRecordsRequest) ([]UsageRecord, error) {
+	return []UsageRecord{}, nil
+}
+
+func (n *noop) GetLatencyStats(ctx context.Context, req LatencyStatsRequest) (LatencyStats, error) {
+	return LatencyStats{}, nil
+}
+
 func NewNoop() *noop {
 	return &noop{}
 }
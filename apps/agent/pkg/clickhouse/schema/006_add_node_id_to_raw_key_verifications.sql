@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE default.raw_key_verifications_v1
+  -- The agent node that received the verification, empty for older rows
+  ADD COLUMN node_id String
+;
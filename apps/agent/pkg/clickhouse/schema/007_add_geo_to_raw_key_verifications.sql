@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE default.raw_key_verifications_v1
+  -- ISO 3166-1 alpha-2 country code of the source ip, empty if unknown
+  ADD COLUMN country LowCardinality(String),
+  -- Autonomous system number of the source ip, 0 if unknown
+  ADD COLUMN asn UInt32
+;
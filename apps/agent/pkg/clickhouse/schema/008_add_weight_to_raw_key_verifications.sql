@@ -0,0 +1,6 @@
+-- +goose up
+ALTER TABLE default.raw_key_verifications_v1
+  -- How many verifications this row represents, rows of hot keys are sampled
+  -- and must be summed rather than counted
+  ADD COLUMN weight UInt32 DEFAULT 1
+;
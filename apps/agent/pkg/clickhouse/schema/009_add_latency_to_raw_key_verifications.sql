@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE default.raw_key_verifications_v1
+  -- How long the verification took in milliseconds, 0 if unknown
+  ADD COLUMN latency Float64 DEFAULT 0
+;
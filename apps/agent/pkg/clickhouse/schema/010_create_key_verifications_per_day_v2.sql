@@ -0,0 +1,15 @@
+-- +goose up
+CREATE TABLE default.key_verifications_per_day_v2
+(
+  time          DateTime,
+  workspace_id  String,
+  key_space_id  String,
+  identity_id   String,
+  key_id        String,
+  outcome       LowCardinality(String),
+  -- The sum of the weights, sampled rows stand for multiple verifications
+  count         AggregateFunction(sum, UInt32)
+)
+ENGINE = AggregatingMergeTree()
+ORDER BY (workspace_id, key_space_id, time, identity_id, key_id)
+;
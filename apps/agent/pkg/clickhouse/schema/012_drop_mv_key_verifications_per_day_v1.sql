@@ -0,0 +1,6 @@
+-- +goose up
+-- Replaced by mv_key_verifications_per_day_v2, which sums the weights instead
+-- of counting sampled rows once. key_verifications_per_day_v1 keeps the
+-- history it already aggregated.
+DROP VIEW default.mv_key_verifications_per_day_v1
+;
@@ -23,4 +23,25 @@ type KeyVerificationRequestV1 struct {
 	Region      string `ch:"region"`
 	Outcome     string `ch:"outcome"`
 	IdentityID  string `ch:"identity_id"`
+	NodeID      string `ch:"node_id"`
+	// Resolved from the source ip, the ip itself is not stored
+	Country string `ch:"country"`
+	ASN     uint32 `ch:"asn"`
+	// How many verifications this row represents, greater than 1 if the
+	// verifications of this key were sampled
+	Weight uint32 `ch:"weight"`
+	// How long the verification took in milliseconds
+	Latency float64 `ch:"latency"`
 }
+
+// Outcomes of a key verification
+const (
+	OutcomeValid                   = "VALID"
+	OutcomeRateLimited             = "RATE_LIMITED"
+	OutcomeUsageExceeded           = "USAGE_EXCEEDED"
+	OutcomeExpired                 = "EXPIRED"
+	OutcomeDisabled                = "DISABLED"
+	OutcomeForbidden               = "FORBIDDEN"
+	OutcomeIpBlocked               = "IP_BLOCKED"
+	OutcomeInsufficientPermissions = "INSUFFICIENT_PERMISSIONS"
+)
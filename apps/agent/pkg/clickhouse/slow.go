@@ -0,0 +1,78 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/prometheus"
+)
+
+// WithSlowQueryLog logs and counts every query that takes longer than threshold.
+//
+// Parameters are hashed rather than logged, so identical slow queries can be
+// correlated without leaking workspace or key ids into the logs.
+func WithSlowQueryLog(q Querier, threshold time.Duration, logger logging.Logger) Querier {
+	return &slowQueryLog{next: q, threshold: threshold, logger: logger}
+}
+
+type slowQueryLog struct {
+	next      Querier
+	threshold time.Duration
+	logger    logging.Logger
+}
+
+var _ Querier = &slowQueryLog{}
+
+func (mw *slowQueryLog) observe(method string, req any, start time.Time) {
+	latency := time.Since(start)
+	if latency < mw.threshold {
+		return
+	}
+	prometheus.ClickhouseSlowQueries.WithLabelValues(method).Inc()
+
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", req)))
+	mw.logger.Warn().
+		Str("method", method).
+		Str("paramsHash", hex.EncodeToString(h[:8])).
+		Int64("latency", latency.Milliseconds()).
+		Msg("slow query")
+}
+
+func (mw *slowQueryLog) GetKeyStats(ctx context.Context, req KeyStatsRequest) ([]StatsBucket, error) {
+	defer mw.observe("GetKeyStats", req, time.Now())
+	return mw.next.GetKeyStats(ctx, req)
+}
+
+func (mw *slowQueryLog) GetApiStats(ctx context.Context, req ApiStatsRequest) (UsageStats, error) {
+	defer mw.observe("GetApiStats", req, time.Now())
+	return mw.next.GetApiStats(ctx, req)
+}
+
+func (mw *slowQueryLog) GetWorkspaceStats(ctx context.Context, req WorkspaceStatsRequest) (UsageStats, error) {
+	defer mw.observe("GetWorkspaceStats", req, time.Now())
+	return mw.next.GetWorkspaceStats(ctx, req)
+}
+
+func (mw *slowQueryLog) GetOwnerStats(ctx context.Context, req OwnerStatsRequest) (UsageStats, error) {
+	defer mw.observe("GetOwnerStats", req, time.Now())
+	return mw.next.GetOwnerStats(ctx, req)
+}
+
+func (mw *slowQueryLog) GetMonthlyActiveKeys(ctx context.Context, req MonthlyActiveKeysRequest) ([]MonthlyActiveKeys, error) {
+	defer mw.observe("GetMonthlyActiveKeys", req, time.Now())
+	return mw.next.GetMonthlyActiveKeys(ctx, req)
+}
+
+func (mw *slowQueryLog) GetUsageRecords(ctx context.Context, req UsageRecordsRequest) ([]UsageRecord, error) {
+	defer mw.observe("GetUsageRecords", req, time.Now())
+	return mw.next.GetUsageRecords(ctx, req)
+}
+
+func (mw *slowQueryLog) GetLatencyStats(ctx context.Context, req LatencyStatsRequest) (LatencyStats, error) {
+	defer mw.observe("GetLatencyStats", req, time.Now())
+	return mw.next.GetLatencyStats(ctx, req)
+}
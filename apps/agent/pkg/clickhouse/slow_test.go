@@ -0,0 +1,40 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := context.Background()
+
+	fast := WithSlowQueryLog(NewNoop(), time.Hour, zerolog.New(buf))
+	_, err := fast.GetKeyStats(ctx, KeyStatsRequest{KeyID: "key_1"})
+	require.NoError(t, err)
+	require.Empty(t, buf.String())
+
+	slow := WithSlowQueryLog(NewNoop(), 0, zerolog.New(buf))
+	_, err = slow.GetKeyStats(ctx, KeyStatsRequest{KeyID: "key_1"})
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `"method":"GetKeyStats"`)
+	require.Contains(t, buf.String(), `"paramsHash"`)
+	require.NotContains(t, buf.String(), "key_1")
+	first := buf.String()
+
+	_, err = slow.GetKeyStats(ctx, KeyStatsRequest{KeyID: "key_1"})
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	hash := func(line string) string {
+		i := strings.Index(line, `"paramsHash":"`)
+		return line[i : i+len(`"paramsHash":"`)+16]
+	}
+	require.Equal(t, hash(strings.TrimSpace(first)), hash(lines[1]))
+}
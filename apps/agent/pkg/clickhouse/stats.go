@@ -0,0 +1,414 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+)
+
+type Granularity string
+
+const (
+	GranularityMinute Granularity = "minute"
+	GranularityHour   Granularity = "hour"
+	GranularityDay    Granularity = "day"
+)
+
+// maxBuckets bounds the size of a single time series
+const maxBuckets = 1000
+
+func (g Granularity) duration() (time.Duration, error) {
+	switch g {
+	case GranularityMinute:
+		return time.Minute, nil
+	case GranularityHour:
+		return time.Hour, nil
+	case GranularityDay:
+		return 24 * time.Hour, nil
+	default:
+		return 0, fault.New(fmt.Sprintf("unknown granularity: %s", g))
+	}
+}
+
+type KeyStatsRequest struct {
+	WorkspaceID string
+	KeyID       string
+	// inclusive
+	Start time.Time
+	// exclusive
+	End         time.Time
+	Granularity Granularity
+}
+
+// StatsBucket counts verifications by outcome within a single interval
+type StatsBucket struct {
+	// The start of the interval
+	Time          time.Time
+	Verifications uint64
+	// Verifications by outcome, e.g. "VALID" or "RATE_LIMITED"
+	Outcomes map[string]uint64
+}
+
+// GetKeyStats returns a time series of verifications of a single key, ordered by time.
+// Intervals without verifications are omitted.
+func (c *Clickhouse) GetKeyStats(ctx context.Context, req KeyStatsRequest) ([]StatsBucket, error) {
+	query, args, err := statsQuery(req.Start, req.End, req.Granularity, map[string]string{
+		"workspace_id": req.WorkspaceID,
+		"key_id":       req.KeyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []statsRow{}
+	err = c.conn.Select(queryContext(ctx), &rows, query, args...)
+	if err != nil {
+		return nil, fault.Wrap(err, fmsg.With("failed to query key stats"))
+	}
+	return toBuckets(rows), nil
+}
+
+type statsRow struct {
+	Bucket  time.Time `ch:"bucket"`
+	Outcome string    `ch:"outcome"`
+	Count   uint64    `ch:"count"`
+}
+
+// toBuckets folds rows, ordered by bucket, into one bucket per interval
+func toBuckets(rows []statsRow) []StatsBucket {
+	buckets := []StatsBucket{}
+	for _, row := range rows {
+		if len(buckets) == 0 || !buckets[len(buckets)-1].Time.Equal(row.Bucket) {
+			buckets = append(buckets, StatsBucket{Time: row.Bucket, Outcomes: map[string]uint64{}})
+		}
+		b := &buckets[len(buckets)-1]
+		b.Outcomes[row.Outcome] += row.Count
+		b.Verifications += row.Count
+	}
+	return buckets
+}
+
+type ApiStatsRequest struct {
+	WorkspaceID string
+	KeySpaceID  string
+	// inclusive
+	Start time.Time
+	// exclusive
+	End time.Time
+}
+
+type WorkspaceStatsRequest struct {
+	WorkspaceID string
+	// inclusive
+	Start time.Time
+	// exclusive
+	End time.Time
+}
+
+type OwnerStatsRequest struct {
+	WorkspaceID string
+	// The owner of the keys, stored as identity_id
+	OwnerID string
+	// inclusive
+	Start time.Time
+	// exclusive
+	End time.Time
+}
+
+// UsageStats summarizes verifications over a time range
+type UsageStats struct {
+	Verifications uint64
+	// Distinct keys with at least one verification
+	ActiveKeys uint64
+	// Verifications by outcome, e.g. "VALID" or "RATE_LIMITED"
+	Outcomes map[string]uint64
+}
+
+// GetApiStats summarizes the verifications of all keys of an api.
+func (c *Clickhouse) GetApiStats(ctx context.Context, req ApiStatsRequest) (UsageStats, error) {
+	return c.usageStats(ctx, req.Start, req.End, map[string]string{
+		"workspace_id": req.WorkspaceID,
+		"key_space_id": req.KeySpaceID,
+	})
+}
+
+// GetWorkspaceStats summarizes the verifications of all keys in a workspace.
+func (c *Clickhouse) GetWorkspaceStats(ctx context.Context, req WorkspaceStatsRequest) (UsageStats, error) {
+	return c.usageStats(ctx, req.Start, req.End, map[string]string{
+		"workspace_id": req.WorkspaceID,
+	})
+}
+
+// GetOwnerStats summarizes the verifications of all keys of a single owner,
+// customers often issue multiple keys per end user.
+func (c *Clickhouse) GetOwnerStats(ctx context.Context, req OwnerStatsRequest) (UsageStats, error) {
+	filters, err := ownerStatsFilters(req)
+	if err != nil {
+		return UsageStats{}, err
+	}
+	return c.usageStats(ctx, req.Start, req.End, filters)
+}
+
+// ownerStatsFilters rejects an empty owner, which would otherwise match every
+// key without an identity.
+func ownerStatsFilters(req OwnerStatsRequest) (map[string]string, error) {
+	if req.OwnerID == "" {
+		return nil, fault.New("owner id is required")
+	}
+	return map[string]string{
+		"workspace_id": req.WorkspaceID,
+		"identity_id":  req.OwnerID,
+	}, nil
+}
+
+func (c *Clickhouse) usageStats(ctx context.Context, start, end time.Time, filters map[string]string) (UsageStats, error) {
+	outcomesQuery, activeKeysQuery, args, err := usageQueries(start, end, filters)
+	if err != nil {
+		return UsageStats{}, err
+	}
+
+	outcomes := []struct {
+		Outcome string `ch:"outcome"`
+		Count   uint64 `ch:"count"`
+	}{}
+	err = c.conn.Select(queryContext(ctx), &outcomes, outcomesQuery, args...)
+	if err != nil {
+		return UsageStats{}, fault.Wrap(err, fmsg.With("failed to query outcomes"))
+	}
+
+	stats := UsageStats{Outcomes: map[string]uint64{}}
+	for _, o := range outcomes {
+		stats.Outcomes[o.Outcome] = o.Count
+		stats.Verifications += o.Count
+	}
+
+	err = c.conn.QueryRow(queryContext(ctx), activeKeysQuery, args...).Scan(&stats.ActiveKeys)
+	if err != nil {
+		return UsageStats{}, fault.Wrap(err, fmsg.With("failed to query active keys"))
+	}
+	return stats, nil
+}
+
+// usageQueries builds the queries counting verifications by outcome and
+// distinct active keys. Both use the same args.
+func usageQueries(start, end time.Time, filters map[string]string) (string, string, []any, error) {
+	if !start.Before(end) {
+		return "", "", nil, fault.New("start must be before end")
+	}
+	where, args := whereClause(start, end, filters)
+
+	outcomesQuery := fmt.Sprintf(`
+SELECT outcome, sum(weight) AS count
+FROM default.raw_key_verifications_v1
+WHERE %s
+GROUP BY outcome
+`, where)
+
+	activeKeysQuery := fmt.Sprintf(`
+SELECT uniqExact(key_id)
+FROM default.raw_key_verifications_v1
+WHERE %s
+`, where)
+
+	return outcomesQuery, activeKeysQuery, args, nil
+}
+
+type MonthlyActiveKeysRequest struct {
+	// Any time within the calendar month, in UTC
+	Month time.Time
+	// Optionally only return a single workspace
+	WorkspaceID string
+}
+
+type MonthlyActiveKeys struct {
+	WorkspaceID string `ch:"workspace_id"`
+	// Distinct keys with at least one verification during the month
+	ActiveKeys uint64 `ch:"active_keys"`
+}
+
+// GetMonthlyActiveKeys returns the number of distinct keys verified per workspace
+// in a calendar month, this is what we bill for.
+func (c *Clickhouse) GetMonthlyActiveKeys(ctx context.Context, req MonthlyActiveKeysRequest) ([]MonthlyActiveKeys, error) {
+	query, args := monthlyActiveKeysQuery(req)
+
+	rows := []MonthlyActiveKeys{}
+	err := c.conn.Select(queryContext(ctx), &rows, query, args...)
+	if err != nil {
+		return nil, fault.Wrap(err, fmsg.With("failed to query monthly active keys"))
+	}
+	return rows, nil
+}
+
+func monthlyActiveKeysQuery(req MonthlyActiveKeysRequest) (string, []any) {
+	m := req.Month.UTC()
+	start := time.Date(m.Year(), m.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	filters := map[string]string{}
+	if req.WorkspaceID != "" {
+		filters["workspace_id"] = req.WorkspaceID
+	}
+	where, args := whereClause(start, end, filters)
+
+	query := fmt.Sprintf(`
+SELECT workspace_id, uniqExact(key_id) AS active_keys
+FROM default.raw_key_verifications_v1
+WHERE %s
+GROUP BY workspace_id
+ORDER BY workspace_id ASC
+`, where)
+	return query, args
+}
+
+// statsQuery builds a query counting verifications per interval and outcome,
+// filtered by the given columns.
+func statsQuery(start, end time.Time, granularity Granularity, filters map[string]string) (string, []any, error) {
+	d, err := granularity.duration()
+	if err != nil {
+		return "", nil, err
+	}
+	if !start.Before(end) {
+		return "", nil, fault.New("start must be before end")
+	}
+	if end.Sub(start)/d > maxBuckets {
+		return "", nil, fault.New(fmt.Sprintf("the time range must not contain more than %d intervals of %s", maxBuckets, granularity))
+	}
+
+	where, args := whereClause(start, end, filters)
+
+	query := fmt.Sprintf(`
+SELECT
+  toStartOfInterval(fromUnixTimestamp64Milli(time), INTERVAL 1 %s) AS bucket,
+  outcome,
+  sum(weight) AS count
+FROM default.raw_key_verifications_v1
+WHERE %s
+GROUP BY bucket, outcome
+ORDER BY bucket ASC
+`, strings.ToUpper(string(granularity)), where)
+
+	return query, args, nil
+}
+
+// whereClause filters verifications by time range and the given columns.
+// Column names must never come from user input, only their values are bound
+// as parameters.
+func whereClause(start, end time.Time, filters map[string]string) (string, []any) {
+	conditions := []string{"time >= ?", "time < ?"}
+	args := []any{start.UnixMilli(), end.UnixMilli()}
+
+	// sorted for deterministic queries
+	columns := make([]string, 0, len(filters))
+	for column := range filters {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	for _, column := range columns {
+		conditions = append(conditions, fmt.Sprintf("%s = ?", column))
+		args = append(args, filters[column])
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+type UsageRecordsRequest struct {
+	Start time.Time
+	End   time.Time
+}
+
+// UsageRecord is the usage of a single identity, verifications of keys without
+// an identity are reported with an empty IdentityID.
+type UsageRecord struct {
+	WorkspaceID   string `ch:"workspace_id"`
+	IdentityID    string `ch:"identity_id"`
+	Verifications uint64 `ch:"verifications"`
+}
+
+// GetUsageRecords returns the number of verifications per workspace and identity
+// within the time range, so customers can bill their own users.
+func (c *Clickhouse) GetUsageRecords(ctx context.Context, req UsageRecordsRequest) ([]UsageRecord, error) {
+	query, args, err := usageRecordsQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []UsageRecord{}
+	err = c.conn.Select(queryContext(ctx), &rows, query, args...)
+	if err != nil {
+		return nil, fault.Wrap(err, fmsg.With("failed to query usage records"))
+	}
+	return rows, nil
+}
+
+func usageRecordsQuery(req UsageRecordsRequest) (string, []any, error) {
+	if !req.Start.Before(req.End) {
+		return "", nil, fault.New("start must be before end")
+	}
+	where, args := whereClause(req.Start, req.End, nil)
+
+	query := fmt.Sprintf(`
+SELECT workspace_id, identity_id, sum(weight) AS verifications
+FROM default.raw_key_verifications_v1
+WHERE %s
+GROUP BY workspace_id, identity_id
+ORDER BY workspace_id ASC, identity_id ASC
+`, where)
+	return query, args, nil
+}
+
+type LatencyStatsRequest struct {
+	WorkspaceID string
+	KeySpaceID  string
+	// inclusive
+	Start time.Time
+	// exclusive
+	End time.Time
+}
+
+// LatencyStats are percentiles of the verification latency in milliseconds.
+// Verifications without a recorded latency are ignored.
+type LatencyStats struct {
+	P50 float64
+	P95 float64
+	P99 float64
+}
+
+// GetLatencyStats returns latency percentiles of all verifications of an api.
+func (c *Clickhouse) GetLatencyStats(ctx context.Context, req LatencyStatsRequest) (LatencyStats, error) {
+	query, args, err := latencyStatsQuery(req)
+	if err != nil {
+		return LatencyStats{}, err
+	}
+
+	quantiles := []float64{}
+	err = c.conn.QueryRow(queryContext(ctx), query, args...).Scan(&quantiles)
+	if err != nil {
+		return LatencyStats{}, fault.Wrap(err, fmsg.With("failed to query latency stats"))
+	}
+	if len(quantiles) != 3 {
+		return LatencyStats{}, fault.New(fmt.Sprintf("expected 3 quantiles, got %d", len(quantiles)))
+	}
+	return LatencyStats{P50: quantiles[0], P95: quantiles[1], P99: quantiles[2]}, nil
+}
+
+func latencyStatsQuery(req LatencyStatsRequest) (string, []any, error) {
+	if !req.Start.Before(req.End) {
+		return "", nil, fault.New("start must be before end")
+	}
+	where, args := whereClause(req.Start, req.End, map[string]string{
+		"workspace_id": req.WorkspaceID,
+		"key_space_id": req.KeySpaceID,
+	})
+
+	// Weighted by the sample weight, so hot keys are not underrepresented
+	query := fmt.Sprintf(`
+SELECT quantilesTDigestWeighted(0.5, 0.95, 0.99)(latency, weight)
+FROM default.raw_key_verifications_v1
+WHERE %s AND latency > 0
+`, where)
+	return query, args, nil
+}
@@ -0,0 +1,148 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsQuery(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	query, args, err := statsQuery(start, end, GranularityHour, map[string]string{
+		"workspace_id": "ws_1",
+		"key_id":       "key_1",
+	})
+	require.NoError(t, err)
+	require.Contains(t, query, "INTERVAL 1 HOUR")
+	require.Contains(t, query, "sum(weight)")
+	require.Contains(t, query, "WHERE time >= ? AND time < ? AND key_id = ? AND workspace_id = ?")
+	require.Equal(t, []any{start.UnixMilli(), end.UnixMilli(), "key_1", "ws_1"}, args)
+}
+
+func TestStatsQueryValidatesRange(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _, err := statsQuery(start, start, GranularityDay, nil)
+	require.Error(t, err)
+
+	_, _, err = statsQuery(start, start.Add(time.Hour), Granularity("week"), nil)
+	require.Error(t, err)
+
+	// Half a day in minutes is within the limit, a day is not
+	_, _, err = statsQuery(start, start.Add(12*time.Hour), GranularityMinute, nil)
+	require.NoError(t, err)
+	_, _, err = statsQuery(start, start.Add(24*time.Hour), GranularityMinute, nil)
+	require.Error(t, err)
+}
+
+func TestUsageQueries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(30 * 24 * time.Hour)
+
+	outcomesQuery, activeKeysQuery, args, err := usageQueries(start, end, map[string]string{
+		"workspace_id": "ws_1",
+		"key_space_id": "ks_1",
+	})
+	require.NoError(t, err)
+	require.Contains(t, outcomesQuery, "GROUP BY outcome")
+	// sampled rows stand for multiple verifications
+	require.Contains(t, outcomesQuery, "sum(weight)")
+	require.Contains(t, activeKeysQuery, "uniqExact(key_id)")
+	for _, query := range []string{outcomesQuery, activeKeysQuery} {
+		require.Contains(t, query, "WHERE time >= ? AND time < ? AND key_space_id = ? AND workspace_id = ?")
+	}
+	require.Equal(t, []any{start.UnixMilli(), end.UnixMilli(), "ks_1", "ws_1"}, args)
+
+	_, _, _, err = usageQueries(end, start, nil)
+	require.Error(t, err)
+}
+
+func TestOwnerStatsQueries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(30 * 24 * time.Hour)
+
+	filters, err := ownerStatsFilters(OwnerStatsRequest{
+		WorkspaceID: "ws_1",
+		OwnerID:     "owner_1",
+		Start:       start,
+		End:         end,
+	})
+	require.NoError(t, err)
+	outcomesQuery, activeKeysQuery, args, err := usageQueries(start, end, filters)
+	require.NoError(t, err)
+	// the owner is stored as identity_id
+	for _, query := range []string{outcomesQuery, activeKeysQuery} {
+		require.Contains(t, query, "WHERE time >= ? AND time < ? AND identity_id = ? AND workspace_id = ?")
+	}
+	require.Equal(t, []any{start.UnixMilli(), end.UnixMilli(), "owner_1", "ws_1"}, args)
+
+	_, err = ownerStatsFilters(OwnerStatsRequest{WorkspaceID: "ws_1", Start: start, End: end})
+	require.Error(t, err)
+}
+
+func TestToBuckets(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	buckets := toBuckets([]statsRow{
+		{Bucket: t0, Outcome: "VALID", Count: 10},
+		{Bucket: t0, Outcome: "RATE_LIMITED", Count: 2},
+		{Bucket: t1, Outcome: "EXPIRED", Count: 1},
+	})
+	require.Equal(t, []StatsBucket{
+		{Time: t0, Verifications: 12, Outcomes: map[string]uint64{"VALID": 10, "RATE_LIMITED": 2}},
+		{Time: t1, Verifications: 1, Outcomes: map[string]uint64{"EXPIRED": 1}},
+	}, buckets)
+}
+
+func TestMonthlyActiveKeysQuery(t *testing.T) {
+	query, args := monthlyActiveKeysQuery(MonthlyActiveKeysRequest{
+		Month:       time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC),
+		WorkspaceID: "ws_1",
+	})
+	require.Contains(t, query, "GROUP BY workspace_id")
+	require.Equal(t, []any{
+		time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC).UnixMilli(),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli(),
+		"ws_1",
+	}, args)
+
+	_, args = monthlyActiveKeysQuery(MonthlyActiveKeysRequest{Month: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)})
+	require.Len(t, args, 2)
+}
+
+func TestUsageRecordsQuery(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	query, args, err := usageRecordsQuery(UsageRecordsRequest{Start: start, End: end})
+	require.NoError(t, err)
+	require.Contains(t, query, "GROUP BY workspace_id, identity_id")
+	require.Contains(t, query, "sum(weight)")
+	require.Equal(t, []any{start.UnixMilli(), end.UnixMilli()}, args)
+
+	_, _, err = usageRecordsQuery(UsageRecordsRequest{Start: end, End: start})
+	require.Error(t, err)
+}
+
+func TestLatencyStatsQuery(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	query, args, err := latencyStatsQuery(LatencyStatsRequest{
+		WorkspaceID: "ws_1",
+		KeySpaceID:  "ks_1",
+		Start:       start,
+		End:         end,
+	})
+	require.NoError(t, err)
+	require.Contains(t, query, "quantilesTDigestWeighted(0.5, 0.95, 0.99)(latency, weight)")
+	require.Contains(t, query, "WHERE time >= ? AND time < ? AND key_space_id = ? AND workspace_id = ? AND latency > 0")
+	require.Equal(t, []any{start.UnixMilli(), end.UnixMilli(), "ks_1", "ws_1"}, args)
+
+	_, _, err = latencyStatsQuery(LatencyStatsRequest{Start: end, End: start})
+	require.Error(t, err)
+}
@@ -10,6 +10,22 @@ type Agent struct {
 			Dataset string `json:"dataset" minLength:"1" description:"The dataset to send logs to"`
 			Token   string `json:"token" minLength:"1" description:"The token to use for authentication"`
 		} `json:"axiom,omitempty" description:"Send logs to axiom"`
+		Http *struct {
+			Url     string            `json:"url" minLength:"1" description:"The ingest endpoint, e.g. https://api.axiom.co/v1/datasets/<dataset>/ingest"`
+			Headers map[string]string `json:"headers,omitempty" description:"Headers sent with every request, e.g. for authentication"`
+		} `json:"http,omitempty" description:"Send batches of newline delimited json logs to an http endpoint"`
+		Audit *struct {
+			Path string `json:"path" minLength:"1" description:"The file to append audit entries to, it is created if it does not exist"`
+			Key  string `json:"key" minLength:"1" description:"Secret to sign the hash chain with, it is needed to verify the audit log"`
+		} `json:"audit,omitempty" description:"Write security relevant actions to a separate, hash chained file"`
+		File *struct {
+			Path       string `json:"path" minLength:"1" description:"The file to write logs to, rotated files are written next to it"`
+			MaxSizeMb  int    `json:"maxSizeMb,omitempty" min:"1" description:"Rotate when the file grows beyond this many megabytes"`
+			MaxAge     int    `json:"maxAge,omitempty" min:"1" description:"Rotate when the file is older than this many seconds"`
+			MaxBackups int    `json:"maxBackups,omitempty" min:"1" description:"How many rotated files to keep, defaults to all"`
+			Compress   bool   `json:"compress,omitempty" description:"Compress rotated files with gzip"`
+		} `json:"file,omitempty" description:"Write logs to a file"`
+		KeyPrefixes []string `json:"keyPrefixes,omitempty" description:"Words starting with any of these prefixes are redacted from logs, e.g. sk_"`
 	} `json:"logging,omitempty"`
 
 	Tracing *struct {
@@ -17,6 +33,19 @@ type Agent struct {
 			Dataset string `json:"dataset" minLength:"1" description:"The dataset to send traces to"`
 			Token   string `json:"token" minLength:"1" description:"The token to use for authentication"`
 		} `json:"axiom,omitempty" description:"Send traces to axiom"`
+		Otlp *struct {
+			Endpoint string            `json:"endpoint" minLength:"1" description:"The host and port of the collector's OTLP/gRPC receiver, e.g. localhost:4317"`
+			Insecure bool              `json:"insecure,omitempty" description:"Connect without TLS"`
+			Headers  map[string]string `json:"headers,omitempty" description:"Headers sent with every export, e.g. for authentication"`
+		} `json:"otlp,omitempty" description:"Send traces to an opentelemetry collector"`
+		Zipkin *struct {
+			Url string `json:"url" minLength:"1" description:"The span collection endpoint, e.g. http://localhost:9411/api/v2/spans"`
+		} `json:"zipkin,omitempty" description:"Send traces to zipkin"`
+		Jaeger *struct {
+			Endpoint string `json:"endpoint" minLength:"1" description:"The host and port of jaeger's OTLP/gRPC receiver, e.g. localhost:4317"`
+			Insecure bool   `json:"insecure,omitempty" description:"Connect without TLS"`
+		} `json:"jaeger,omitempty" description:"Send traces to jaeger over OTLP"`
+		SampleRatio *float64 `json:"sampleRatio,omitempty" min:"0" max:"1" description:"The fraction of new traces to record, defaults to 1. Child spans follow their parent and requests with the Unkey-Debug-Trace header are always traced"`
 	} `json:"tracing,omitempty"`
 
 	Metrics *struct {
@@ -24,6 +53,11 @@ type Agent struct {
 			Dataset string `json:"dataset" minLength:"1" description:"The dataset to send metrics to"`
 			Token   string `json:"token" minLength:"1" description:"The token to use for authentication"`
 		} `json:"axiom,omitempty" description:"Send metrics to axiom"`
+		Otlp *struct {
+			Endpoint string            `json:"endpoint" minLength:"1" description:"The OTLP/HTTP endpoint of the collector, e.g. http://localhost:4318"`
+			Headers  map[string]string `json:"headers,omitempty" description:"Headers sent with every export, e.g. for authentication"`
+			Interval int               `json:"interval,omitempty" min:"1" description:"Interval in seconds to push metrics, defaults to 60"`
+		} `json:"otlp,omitempty" description:"Push metrics to an opentelemetry collector"`
 	} `json:"metrics,omitempty"`
 
 	Schema    string `json:"$schema,omitempty" description:"Make jsonschema happy"`
@@ -34,6 +68,8 @@ type Agent struct {
 		URL      string `json:"url" minLength:"1" description:"URL to send heartbeat to"`
 		Interval int    `json:"interval" min:"1" description:"Interval in seconds to send heartbeat"`
 	} `json:"heartbeat,omitempty" description:"Send heartbeat to a URL"`
+	ShutdownTimeout int      `json:"shutdownTimeout,omitempty" min:"1" description:"Seconds to wait for in-flight requests to finish when shutting down, defaults to 20. Buffered analytics are flushed afterwards"`
+	TrustedProxies  []string `json:"trustedProxies,omitempty" description:"Addresses or CIDR ranges of load balancers and proxies in front of the agent. The source ip of a request is only taken from the X-Forwarded-For and Forwarded headers as far as they were written by these"`
 
 	Services struct {
 		EventRouter *struct {
@@ -42,14 +78,41 @@ type Agent struct {
 				FlushInterval int    `json:"flushInterval" min:"1" description:"Interval in seconds to flush events"`
 				BufferSize    int    `json:"bufferSize" min:"1" description:"Size of the buffer"`
 				BatchSize     int    `json:"batchSize" min:"1" description:"Size of the batch"`
+				DeadLetterDir string `json:"deadLetterDir,omitempty" description:"Directory to store batches while tinybird is unavailable, they are replayed once it recovers"`
 			} `json:"tinybird,omitempty" description:"Send events to tinybird"`
+			Geo *struct {
+				CountryDatabase string `json:"countryDatabase,omitempty" description:"Path to a MaxMind country or city database"`
+				AsnDatabase     string `json:"asnDatabase,omitempty" description:"Path to a MaxMind ASN database"`
+			} `json:"geo,omitempty" description:"Resolve the source ip of key verifications to country and ASN before storing them in clickhouse"`
+			Sampling *struct {
+				Threshold      int                `json:"threshold" min:"0" description:"Verifications per second of a single key that are always recorded"`
+				Rate           float64            `json:"rate" min:"0" max:"1" description:"The fraction of verifications recorded beyond the threshold"`
+				WorkspaceRates map[string]float64 `json:"workspaceRates,omitempty" description:"Overrides the rate for individual workspaces, by workspace id"`
+			} `json:"sampling,omitempty" description:"Sample the key verifications of hot keys written to clickhouse, counts are extrapolated in queries"`
+			WorkspaceMetrics bool `json:"workspaceMetrics,omitempty" description:"Count verifications and denials per workspace in prometheus, the number of workspaces is bounded by prometheus.maxLabelValues"`
 		} `json:"eventRouter,omitempty" description:"Route events"`
+		Alerting *struct {
+			WebhookUrl string `json:"webhookUrl,omitempty" description:"Post fired alerts, exceeded ratelimits and exceeded usage limits to this url"`
+			Secret     string `json:"secret,omitempty" description:"The secret to sign alerts with, the signature is sent in the Unkey-Signature header"`
+			Rules      []struct {
+				Id               string  `json:"id" minLength:"1"`
+				WorkspaceId      string  `json:"workspaceId" minLength:"1"`
+				ApiId            string  `json:"apiId,omitempty" description:"Only evaluate verifications of this api"`
+				KeyId            string  `json:"keyId,omitempty" description:"Only evaluate verifications of this key"`
+				Window           int     `json:"window" min:"1" description:"Verifications are counted in consecutive windows of this many seconds"`
+				MaxVerifications int     `json:"maxVerifications,omitempty" description:"Fire when there are more verifications within a window"`
+				MaxErrorRate     float64 `json:"maxErrorRate,omitempty" description:"Fire when more than this fraction of verifications in a window were not valid"`
+				MinVerifications int     `json:"minVerifications,omitempty" description:"Only evaluate the error rate of windows with at least this many verifications"`
+			} `json:"rules"`
+		} `json:"alerting,omitempty" description:"Evaluate alerting rules against key verifications, requires the event router"`
 		Vault struct {
-			S3Bucket          string `json:"s3Bucket" minLength:"1" description:"The bucket to store secrets in"`
-			S3Url             string `json:"s3Url" minLength:"1" description:"The url to store secrets in"`
-			S3AccessKeyId     string `json:"s3AccessKeyId" minLength:"1" description:"The access key id to use for s3"`
-			S3AccessKeySecret string `json:"s3AccessKeySecret" minLength:"1" description:"The access key secret to use for s3"`
-			MasterKeys        string `json:"masterKeys" minLength:"1" description:"The master keys to use for encryption, comma separated"`
+			S3Bucket          string   `json:"s3Bucket" minLength:"1" description:"The bucket to store secrets in"`
+			S3Url             string   `json:"s3Url" minLength:"1" description:"The url to store secrets in"`
+			S3AccessKeyId     string   `json:"s3AccessKeyId" minLength:"1" description:"The access key id to use for s3"`
+			S3AccessKeySecret string   `json:"s3AccessKeySecret" minLength:"1" description:"The access key secret to use for s3"`
+			MasterKeys        string   `json:"masterKeys" minLength:"1" description:"The master keys to use for encryption, comma separated"`
+			WarmupKeyrings    []string `json:"warmupKeyrings,omitempty" description:"Keyrings whose latest key is loaded into the cache before serving requests"`
+			KeyCacheSnapshot  string   `json:"keyCacheSnapshot,omitempty" description:"Keep an encrypted snapshot of the key cache in this file, so restarted nodes start with a warm cache"`
 		} `json:"vault" description:"Store secrets"`
 	} `json:"services"`
 
@@ -69,8 +132,9 @@ type Agent struct {
 	} `json:"cluster,omitempty"`
 
 	Prometheus *struct {
-		Path string `json:"path" default:"/metrics" description:"The path where prometheus scrapes metrics"`
-		Port int    `json:"port" default:"2112" description:"The port where prometheus scrapes metrics"`
+		Path           string `json:"path" default:"/metrics" description:"The path where prometheus scrapes metrics"`
+		Port           int    `json:"port" default:"2112" description:"The port where prometheus scrapes metrics"`
+		MaxLabelValues int    `json:"maxLabelValues,omitempty" min:"1" description:"Distinct values kept per high cardinality label, such as cache keys, further values are hashed into overflow buckets. Defaults to 1000"`
 	} `json:"prometheus,omitempty"`
 	Pyroscope *struct {
 		Url      string `json:"url" minLength:"1"`
@@ -78,6 +142,24 @@ type Agent struct {
 		Password string `json:"password" minLength:"1"`
 	} `json:"pyroscope,omitempty"`
 	Clickhouse *struct {
-		Url string `json:"url" minLength:"1"`
+		Url           string `json:"url" minLength:"1"`
+		DeadLetterDir string `json:"deadLetterDir,omitempty" description:"Directory to store rows that could not be inserted, they are replayed once clickhouse is available again"`
+		Export        *struct {
+			Url             string `json:"url" minLength:"1" description:"The s3 compatible bucket and prefix to export to"`
+			AccessKeyId     string `json:"accessKeyId" minLength:"1" description:"The access key id to use for s3"`
+			AccessKeySecret string `json:"accessKeySecret" minLength:"1" description:"The access key secret to use for s3"`
+		} `json:"export,omitempty" description:"Export key verifications to s3 as parquet once per day, enable this on a single node only"`
+		UsageWebhook *struct {
+			Url             string `json:"url" minLength:"1" description:"The url to post usage records to"`
+			Secret          string `json:"secret" minLength:"1" description:"The secret to sign the payload with, the signature is sent in the Unkey-Signature header"`
+			Interval        int    `json:"interval,omitempty" min:"1" description:"The length of each reporting period in seconds, defaults to 1 hour"`
+			SettlementDelay int    `json:"settlementDelay,omitempty" min:"1" description:"How many seconds to wait after a period ended before reporting it, so late verifications are included, defaults to 10 minutes"`
+		} `json:"usageWebhook,omitempty" description:"Periodically report verifications per workspace and identity to a webhook for metered billing, enable this on a single node only"`
+		SlowQueryThreshold int `json:"slowQueryThreshold,omitempty" min:"1" description:"Queries taking longer than this many milliseconds are logged and counted, defaults to 1000"`
 	} `json:"clickhouse,omitempty"`
+	LoadShedding *struct {
+		TargetLatency int `json:"targetLatency,omitempty" min:"1" description:"Ratelimit requests taking longer than this many milliseconds lower the concurrency limit, defaults to 250"`
+		MinLimit      int `json:"minLimit,omitempty" min:"1" description:"The concurrency limit never drops below this, defaults to 10"`
+		MaxLimit      int `json:"maxLimit,omitempty" min:"1" description:"The concurrency limit never grows beyond this, defaults to 1000"`
+	} `json:"loadShedding,omitempty" description:"Reject ratelimit requests from the api with 429 while more are in flight than the agent can handle, the limit adapts to their latency"`
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/unkeyed/unkey/apps/agent/pkg/auth"
 	"github.com/unkeyed/unkey/apps/agent/pkg/cluster"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
 )
 
 type clusterServer struct {
@@ -28,7 +29,12 @@ func NewClusterServer(svc cluster.Cluster, logger logging.Logger) *clusterServer
 }
 
 func (s *clusterServer) CreateHandler() (string, http.Handler, error) {
-	otelInterceptor, err := otelconnect.NewInterceptor()
+	// Calls come from other nodes and the api, so we continue their traces
+	otelInterceptor, err := otelconnect.NewInterceptor(
+		otelconnect.WithTracerProvider(tracing.GetGlobalTraceProvider()),
+		otelconnect.WithPropagator(tracing.Propagator),
+		otelconnect.WithTrustRemote(),
+	)
 	if err != nil {
 		return "", nil, err
 	}
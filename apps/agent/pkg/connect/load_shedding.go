@@ -0,0 +1,28 @@
+package connect
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"connectrpc.com/connect"
+	"github.com/unkeyed/unkey/apps/agent/pkg/loadshedding"
+)
+
+// newLoadSheddingInterceptor admits calls to the given procedures only while
+// the limiter has capacity, other procedures pass through.
+func newLoadSheddingInterceptor(limiter *loadshedding.Limiter, procedures ...string) connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if !slices.Contains(procedures, req.Spec().Procedure) {
+				return next(ctx, req)
+			}
+			release, ok := limiter.Acquire()
+			if !ok {
+				return nil, connect.NewError(connect.CodeResourceExhausted, errors.New("the agent is overloaded, please retry later"))
+			}
+			defer release()
+			return next(ctx, req)
+		}
+	}
+}
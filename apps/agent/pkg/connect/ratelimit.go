@@ -10,6 +10,7 @@ import (
 	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
 	"github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1/ratelimitv1connect"
 	"github.com/unkeyed/unkey/apps/agent/pkg/auth"
+	"github.com/unkeyed/unkey/apps/agent/pkg/loadshedding"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
 )
@@ -27,6 +28,8 @@ type ratelimitServer struct {
 	svc       RatelimitService
 	logger    logging.Logger
 	authToken string
+	// may be nil
+	loadShedding *loadshedding.Limiter
 	ratelimitv1connect.UnimplementedRatelimitServiceHandler
 }
 
@@ -40,13 +43,34 @@ func NewRatelimitServer(svc RatelimitService, logger logging.Logger, authToken s
 
 }
 
+// WithLoadShedding rejects ratelimit requests from the api with
+// CodeResourceExhausted while the limiter is at capacity. Requests between
+// nodes are never shed, they keep the cluster consistent.
+func (s *ratelimitServer) WithLoadShedding(limiter *loadshedding.Limiter) *ratelimitServer {
+	s.loadShedding = limiter
+	return s
+}
+
 func (s *ratelimitServer) CreateHandler() (string, http.Handler, error) {
-	otelInterceptor, err := otelconnect.NewInterceptor()
+	// Calls come from other nodes and the api, so we continue their traces
+	otelInterceptor, err := otelconnect.NewInterceptor(
+		otelconnect.WithTracerProvider(tracing.GetGlobalTraceProvider()),
+		otelconnect.WithPropagator(tracing.Propagator),
+		otelconnect.WithTrustRemote(),
+	)
 	if err != nil {
 		return "", nil, err
 	}
 
-	path, handler := ratelimitv1connect.NewRatelimitServiceHandler(s, connect.WithInterceptors(otelInterceptor))
+	interceptors := []connect.Interceptor{otelInterceptor}
+	if s.loadShedding != nil {
+		interceptors = append(interceptors, newLoadSheddingInterceptor(s.loadShedding,
+			ratelimitv1connect.RatelimitServiceRatelimitProcedure,
+			ratelimitv1connect.RatelimitServiceMultiRatelimitProcedure,
+		))
+	}
+
+	path, handler := ratelimitv1connect.NewRatelimitServiceHandler(s, connect.WithInterceptors(interceptors...))
 	return path, handler, nil
 
 }
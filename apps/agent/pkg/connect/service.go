@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +14,8 @@ import (
 	"net/http/pprof"
 
 	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"connectrpc.com/grpcreflect"
 	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
@@ -31,6 +35,10 @@ type Server struct {
 	isListening bool
 	image       string
 	srv         *http.Server
+
+	// the fully qualified names of all added services, for health checks and reflection
+	services []string
+	health   *grpchealth.StaticChecker
 }
 
 type Config struct {
@@ -47,6 +55,7 @@ func New(cfg Config) (*Server, error) {
 		isListening: false,
 		mux:         http.NewServeMux(),
 		image:       cfg.Image,
+		health:      grpchealth.NewStaticChecker(),
 	}, nil
 }
 
@@ -59,9 +68,27 @@ func (s *Server) AddService(svc Service) error {
 
 	h := newHeaderMiddleware(handler)
 	s.mux.Handle(pattern, h)
+
+	// Patterns are the service name enclosed in slashes
+	name := strings.Trim(pattern, "/")
+	s.services = append(s.services, name)
+	s.health.SetStatus(name, grpchealth.StatusServing)
 	return nil
 }
 
+// registerHealthAndReflection serves the standard grpc health checking and
+// server reflection services, so load balancers and tools like grpcurl work
+// without copies of our proto files. Reflection only knows about services
+// added before this is called.
+func (s *Server) registerHealthAndReflection() {
+	s.mux.Handle(grpchealth.NewHandler(s.health))
+
+	reflector := grpcreflect.NewStaticReflector(s.services...)
+	s.mux.Handle(grpcreflect.NewHandlerV1(reflector))
+	// Many tools, grpcurl among them, still use the alpha version
+	s.mux.Handle(grpcreflect.NewHandlerV1Alpha(reflector))
+}
+
 func (s *Server) EnablePprof(expectedUsername string, expectedPassword string) {
 
 	var withBasicAuth = func(handler http.HandlerFunc) http.HandlerFunc {
@@ -110,6 +137,8 @@ func (s *Server) Listen(addr string) error {
 	s.isListening = true
 	s.Unlock()
 
+	s.registerHealthAndReflection()
+
 	s.mux.HandleFunc("/v1/liveness", func(w http.ResponseWriter, r *http.Request) {
 		b, err := json.Marshal(map[string]string{"status": "serving", "image": s.image})
 		if err != nil {
@@ -143,15 +172,19 @@ func (s *Server) Listen(addr string) error {
 	s.srv.WriteTimeout = 20 * time.Second
 
 	s.logger.Info().Str("addr", addr).Msg("listening")
-	return s.srv.ListenAndServe()
+	err := s.srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 
 }
 
-func (s *Server) Shutdown() error {
+// Shutdown stops accepting new connections and waits for in-flight requests
+// until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
 	s.Lock()
 	defer s.Unlock()
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 	return s.srv.Shutdown(ctx)
 
 }
@@ -0,0 +1,53 @@
+package connect
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/gen/proto/cluster/v1/clusterv1connect"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+)
+
+func TestHealthAndReflection(t *testing.T) {
+	logger := logging.NewNoopLogger()
+	s, err := New(Config{Logger: logger, Metrics: metrics.NewNoop()})
+	require.NoError(t, err)
+	require.NoError(t, s.AddService(NewClusterServer(nil, logger)))
+	s.registerHealthAndReflection()
+
+	srv := httptest.NewServer(s.mux)
+	defer srv.Close()
+
+	check := func(service string) (int, string) {
+		res, err := http.Post(srv.URL+"/grpc.health.v1.Health/Check", "application/json", strings.NewReader(`{"service":"`+service+`"}`))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(body)
+	}
+
+	status, body := check("")
+	require.Equal(t, 200, status)
+	require.JSONEq(t, `{"status":"SERVING_STATUS_SERVING"}`, body)
+
+	status, body = check(clusterv1connect.ClusterServiceName)
+	require.Equal(t, 200, status)
+	require.JSONEq(t, `{"status":"SERVING_STATUS_SERVING"}`, body)
+
+	status, _ = check("does.not.Exist")
+	require.Equal(t, 404, status)
+
+	for _, path := range []string{
+		"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+	} {
+		_, pattern := s.mux.Handler(httptest.NewRequest(http.MethodPost, path, nil))
+		require.NotEmpty(t, pattern, path)
+	}
+}
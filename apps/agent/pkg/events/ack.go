@@ -0,0 +1,254 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+type AckConfig[E any] struct {
+	// Identifies the subscriber in logs and dead letters
+	Id string
+	// Deliveries that are neither acked nor nacked within this time are
+	// delivered again. Defaults to 30s.
+	AckTimeout time.Duration
+	// Size of the delivery channel
+	BufferSize int
+
+	// How often an event is delivered before it is given up on, defaults to 5
+	MaxAttempts int
+	// How many events may be waiting for an ack at the same time, further
+	// events are not delivered until others are acked or given up on.
+	// Defaults to 1000.
+	MaxPending int
+
+	// Events that were never acked, or were still pending when the
+	// subscription stopped, are moved here. Without a dead letter queue they
+	// are dropped.
+	DeadLetters *DeadLetterQueue[E]
+
+	Logger logging.Logger
+}
+
+// Delivery is an event that must be acknowledged, otherwise it is delivered
+// again.
+type Delivery[E any] struct {
+	Event E
+	// 1 for the first delivery, incremented on every redelivery
+	Attempt int
+
+	id uint64
+	q  *ackQueue[E]
+}
+
+// Ack marks the event as handled, it will not be delivered again.
+func (d Delivery[E]) Ack() {
+	d.q.ack(d.id)
+}
+
+// Nack delivers the event again right away.
+func (d Delivery[E]) Nack() {
+	d.q.nack(d.id)
+}
+
+type pendingDelivery[E any] struct {
+	event    E
+	attempt  int
+	deadline time.Time
+}
+
+type ackQueue[E any] struct {
+	sync.Mutex
+	config  AckConfig[E]
+	nextId  uint64
+	pending map[uint64]*pendingDelivery[E]
+	// one slot per pending delivery, bounds the pending map
+	slots chan struct{}
+
+	out  chan Delivery[E]
+	wake chan struct{}
+	done chan struct{}
+}
+
+// SubscribeAcked delivers every event of the topic at least once, until the
+// returned function is called. Consumers must ack or nack each delivery.
+// Events that were delivered MaxAttempts times without an ack are moved to the
+// dead letter queue.
+//
+// Unacked events are kept in memory only, they are lost when the process
+// stops. The delivery channel is closed once the subscription is stopped.
+func SubscribeAcked[E any](t EventSubscriber[E], config AckConfig[E]) (<-chan Delivery[E], func()) {
+	if config.AckTimeout <= 0 {
+		config.AckTimeout = 30 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.MaxPending <= 0 {
+		config.MaxPending = 1000
+	}
+
+	q := &ackQueue[E]{
+		config:  config,
+		pending: map[uint64]*pendingDelivery[E]{},
+		slots:   make(chan struct{}, config.MaxPending),
+		out:     make(chan Delivery[E], config.BufferSize),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if config.DeadLetters != nil {
+		config.DeadLetters.register(config.Id, q.requeue)
+	}
+
+	sub := t.SubscribeChannel(config.Id)
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// keep draining after stopping, so publishers are never blocked
+		for e := range sub {
+			q.deliver(e)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		q.redeliverLoop()
+	}()
+
+	var once sync.Once
+	return q.out, func() {
+		once.Do(func() {
+			close(q.done)
+			t.Unsubscribe(sub)
+			wg.Wait()
+			close(q.out)
+			q.giveUpPending()
+		})
+	}
+}
+
+func (q *ackQueue[E]) deliver(e E) {
+	select {
+	case q.slots <- struct{}{}:
+	case <-q.done:
+		return
+	}
+
+	q.Lock()
+	q.nextId++
+	id := q.nextId
+	q.pending[id] = &pendingDelivery[E]{event: e, attempt: 1, deadline: time.Now().Add(q.config.AckTimeout)}
+	q.Unlock()
+
+	q.send(Delivery[E]{Event: e, Attempt: 1, id: id, q: q})
+}
+
+// requeue hands a dead letter to the consumer once more
+func (q *ackQueue[E]) requeue(_ context.Context, e E) error {
+	select {
+	case <-q.done:
+		return fmt.Errorf("subscriber %s is stopped", q.config.Id)
+	default:
+	}
+	q.deliver(e)
+	return nil
+}
+
+func (q *ackQueue[E]) send(d Delivery[E]) {
+	select {
+	case q.out <- d:
+	case <-q.done:
+	}
+}
+
+func (q *ackQueue[E]) ack(id uint64) {
+	q.Lock()
+	defer q.Unlock()
+	q.remove(id)
+}
+
+// remove must be called with the lock held
+func (q *ackQueue[E]) remove(id uint64) {
+	if _, ok := q.pending[id]; !ok {
+		return
+	}
+	delete(q.pending, id)
+	<-q.slots
+}
+
+func (q *ackQueue[E]) nack(id uint64) {
+	q.Lock()
+	p, ok := q.pending[id]
+	if ok {
+		p.deadline = time.Time{}
+	}
+	q.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *ackQueue[E]) redeliverLoop() {
+	ticker := time.NewTicker(max(q.config.AckTimeout/4, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+		for _, d := range q.expired(time.Now()) {
+			q.send(d)
+		}
+	}
+}
+
+// expired returns all deliveries past their deadline and extends it.
+// Deliveries without attempts left are given up on instead.
+func (q *ackQueue[E]) expired(now time.Time) []Delivery[E] {
+	q.Lock()
+	defer q.Unlock()
+	deliveries := []Delivery[E]{}
+	for id, p := range q.pending {
+		if now.Before(p.deadline) {
+			continue
+		}
+		if p.attempt >= q.config.MaxAttempts {
+			q.giveUp(p, fmt.Errorf("not acknowledged after %d attempts", p.attempt))
+			q.remove(id)
+			continue
+		}
+		p.attempt++
+		p.deadline = now.Add(q.config.AckTimeout)
+		deliveries = append(deliveries, Delivery[E]{Event: p.event, Attempt: p.attempt, id: id, q: q})
+	}
+	return deliveries
+}
+
+// giveUpPending moves everything still waiting for an ack to the dead letter
+// queue, once the subscription stopped.
+func (q *ackQueue[E]) giveUpPending() {
+	q.Lock()
+	defer q.Unlock()
+	for id, p := range q.pending {
+		q.giveUp(p, fmt.Errorf("subscription stopped before the event was acknowledged"))
+		q.remove(id)
+	}
+}
+
+// giveUp must be called with the lock held
+func (q *ackQueue[E]) giveUp(p *pendingDelivery[E], err error) {
+	if q.config.DeadLetters == nil {
+		q.config.Logger.Error().Err(err).Str("subscriber", q.config.Id).Int("attempts", p.attempt).Msg("dropping event")
+		return
+	}
+	q.config.DeadLetters.add(q.config.Id, p.event, err, p.attempt)
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+func TestSubscribeAckedRedeliversUnackedEvents(t *testing.T) {
+	topic := NewTopic[string]()
+	deliveries, stop := SubscribeAcked[string](topic, AckConfig[string]{Id: "test", AckTimeout: 20 * time.Millisecond, BufferSize: 10})
+	defer stop()
+
+	topic.Publish(context.Background(), "a")
+
+	first := <-deliveries
+	require.Equal(t, "a", first.Event)
+	require.Equal(t, 1, first.Attempt)
+
+	// not acked in time
+	second := <-deliveries
+	require.Equal(t, "a", second.Event)
+	require.Equal(t, 2, second.Attempt)
+	second.Ack()
+
+	select {
+	case d := <-deliveries:
+		t.Fatalf("acked event was delivered again: %+v", d)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSubscribeAckedNackRedeliversImmediately(t *testing.T) {
+	topic := NewTopic[string]()
+	deliveries, stop := SubscribeAcked[string](topic, AckConfig[string]{Id: "test", AckTimeout: time.Hour, BufferSize: 10})
+
+	topic.Publish(context.Background(), "a")
+	(<-deliveries).Nack()
+
+	select {
+	case d := <-deliveries:
+		require.Equal(t, 2, d.Attempt)
+		d.Ack()
+	case <-time.After(time.Second):
+		t.Fatal("nacked event was not delivered again")
+	}
+
+	stop()
+	_, open := <-deliveries
+	require.False(t, open)
+}
+
+func TestSubscribeAckedDeadLettersAfterMaxAttempts(t *testing.T) {
+	topic := NewTopic[string]()
+	dlq := NewDeadLetterQueue[string](10, logging.NewNoopLogger())
+	deliveries, stop := SubscribeAcked[string](topic, AckConfig[string]{
+		Id:          "test",
+		AckTimeout:  time.Hour,
+		BufferSize:  10,
+		MaxAttempts: 2,
+		DeadLetters: dlq,
+	})
+	defer stop()
+
+	topic.Publish(context.Background(), "a")
+	(<-deliveries).Nack()
+	(<-deliveries).Nack()
+
+	require.Eventually(t, func() bool { return len(dlq.List()) == 1 }, time.Second, 10*time.Millisecond)
+	letter := dlq.List()[0]
+	require.Equal(t, "a", letter.Event)
+	require.Equal(t, 2, letter.Attempts)
+
+	// requeued dead letters are delivered again
+	require.NoError(t, dlq.Requeue(context.Background(), letter.Id))
+	d := <-deliveries
+	require.Equal(t, "a", d.Event)
+	d.Ack()
+	require.Empty(t, dlq.List())
+}
+
+func TestSubscribeAckedBoundsPending(t *testing.T) {
+	topic := NewTopic[string](10)
+	deliveries, stop := SubscribeAcked[string](topic, AckConfig[string]{
+		Id:         "test",
+		AckTimeout: time.Hour,
+		BufferSize: 10,
+		MaxPending: 1,
+	})
+	defer stop()
+
+	topic.Publish(context.Background(), "a")
+	topic.Publish(context.Background(), "b")
+
+	first := <-deliveries
+	select {
+	case d := <-deliveries:
+		t.Fatalf("delivered %s while another event was pending", d.Event)
+	case <-time.After(50 * time.Millisecond):
+	}
+	first.Ack()
+	require.Equal(t, "b", (<-deliveries).Event)
+}
+
+func TestSubscribeAckedDeadLettersPendingOnStop(t *testing.T) {
+	topic := NewTopic[string]()
+	dlq := NewDeadLetterQueue[string](10, logging.NewNoopLogger())
+	deliveries, stop := SubscribeAcked[string](topic, AckConfig[string]{
+		Id:          "test",
+		AckTimeout:  time.Hour,
+		BufferSize:  10,
+		DeadLetters: dlq,
+	})
+
+	topic.Publish(context.Background(), "a")
+	<-deliveries
+	stop()
+
+	require.Len(t, dlq.List(), 1)
+	require.Equal(t, "a", dlq.List()[0].Event)
+}
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is an event a subscriber failed to handle
+type DeadLetter[E any] struct {
+	Id         string
+	Subscriber string
+	Event      E
+	// The last error returned by the subscriber
+	Error    string
+	Attempts int
+	Time     time.Time
+}
+
+// DeadLetterSummary is a dead letter of any event type, with the event encoded as json
+type DeadLetterSummary struct {
+	Id         string
+	Subscriber string
+	Event      json.RawMessage
+	Error      string
+	Attempts   int
+	Time       time.Time
+}
+
+// DeadLetterInspector lets operators look at failed events and retry them,
+// regardless of their type.
+type DeadLetterInspector interface {
+	Inspect() []DeadLetterSummary
+	// Requeue hands the event to its subscriber once more and removes it on
+	// success.
+	Requeue(ctx context.Context, id string) error
+}
+
+// DeadLetterQueue holds events that subscribers failed to handle, in memory.
+// When it is full, the oldest dead letter is dropped.
+type DeadLetterQueue[E any] struct {
+	sync.Mutex
+	maxSize  int
+	letters  []DeadLetter[E]
+	handlers map[string]func(ctx context.Context, event E) error
+	logger   logging.Logger
+}
+
+var _ DeadLetterInspector = &DeadLetterQueue[any]{}
+
+func NewDeadLetterQueue[E any](maxSize int, logger logging.Logger) *DeadLetterQueue[E] {
+	return &DeadLetterQueue[E]{
+		maxSize:  maxSize,
+		letters:  []DeadLetter[E]{},
+		handlers: map[string]func(ctx context.Context, event E) error{},
+		logger:   logger,
+	}
+}
+
+func (q *DeadLetterQueue[E]) register(subscriber string, handle func(ctx context.Context, event E) error) {
+	q.Lock()
+	defer q.Unlock()
+	q.handlers[subscriber] = handle
+}
+
+func (q *DeadLetterQueue[E]) add(subscriber string, e E, err error, attempts int) {
+	q.Lock()
+	defer q.Unlock()
+	if q.maxSize > 0 && len(q.letters) >= q.maxSize {
+		q.logger.Error().Str("subscriber", q.letters[0].Subscriber).Str("id", q.letters[0].Id).Msg("dead letter queue is full, dropping oldest")
+		q.letters = q.letters[1:]
+	}
+	q.letters = append(q.letters, DeadLetter[E]{
+		Id:         uid.New("dl"),
+		Subscriber: subscriber,
+		Event:      e,
+		Error:      err.Error(),
+		Attempts:   attempts,
+		Time:       time.Now(),
+	})
+}
+
+// List returns all dead letters, oldest first.
+func (q *DeadLetterQueue[E]) List() []DeadLetter[E] {
+	q.Lock()
+	defer q.Unlock()
+	letters := make([]DeadLetter[E], len(q.letters))
+	copy(letters, q.letters)
+	return letters
+}
+
+func (q *DeadLetterQueue[E]) Inspect() []DeadLetterSummary {
+	letters := q.List()
+	summaries := make([]DeadLetterSummary, len(letters))
+	for i, l := range letters {
+		b, err := json.Marshal(l.Event)
+		if err != nil {
+			q.logger.Warn().Err(err).Str("id", l.Id).Msg("failed to marshal dead letter")
+		}
+		summaries[i] = DeadLetterSummary{
+			Id:         l.Id,
+			Subscriber: l.Subscriber,
+			Event:      b,
+			Error:      l.Error,
+			Attempts:   l.Attempts,
+			Time:       l.Time,
+		}
+	}
+	return summaries
+}
+
+func (q *DeadLetterQueue[E]) Requeue(ctx context.Context, id string) error {
+	q.Lock()
+	i := q.index(id)
+	if i < 0 {
+		q.Unlock()
+		return ErrDeadLetterNotFound
+	}
+	l := q.letters[i]
+	handle := q.handlers[l.Subscriber]
+	q.Unlock()
+
+	if handle == nil {
+		return errors.New("subscriber " + l.Subscriber + " is not running")
+	}
+	err := handle(ctx, l.Event)
+
+	q.Lock()
+	defer q.Unlock()
+	// the queue may have changed in the meantime
+	i = q.index(id)
+	if i < 0 {
+		return err
+	}
+	if err != nil {
+		q.letters[i].Attempts++
+		q.letters[i].Error = err.Error()
+		return err
+	}
+	q.letters = append(q.letters[:i], q.letters[i+1:]...)
+	return nil
+}
+
+// Remove discards a dead letter without handling it.
+func (q *DeadLetterQueue[E]) Remove(id string) bool {
+	q.Lock()
+	defer q.Unlock()
+	i := q.index(id)
+	if i < 0 {
+		return false
+	}
+	q.letters = append(q.letters[:i], q.letters[i+1:]...)
+	return true
+}
+
+func (q *DeadLetterQueue[E]) index(id string) int {
+	for i, l := range q.letters {
+		if l.Id == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Envelope wraps events that leave the process, so consumers can tell what
+// they are looking at and how to decode it, even as event shapes change.
+type Envelope struct {
+	Type string `json:"type"`
+	// Bumped on every incompatible change of the payload
+	Version     int    `json:"version"`
+	WorkspaceId string `json:"workspaceId,omitempty"`
+	// W3C trace context of the publisher
+	TraceParent string          `json:"traceparent,omitempty"`
+	TraceState  string          `json:"tracestate,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// Registry knows every event type and version and how to validate its payload.
+type Registry struct {
+	sync.RWMutex
+	validators map[string]func(payload json.RawMessage) error
+}
+
+func NewRegistry() *Registry {
+	return &Registry{validators: map[string]func(payload json.RawMessage) error{}}
+}
+
+func registryKey(eventType string, version int) string {
+	return fmt.Sprintf("%s@v%d", eventType, version)
+}
+
+// Register adds a version of an event type. The payload must decode into P
+// and pass validate, which may be nil.
+// Registering the same type and version again replaces it.
+func Register[P any](r *Registry, eventType string, version int, validate func(P) error) {
+	r.Lock()
+	defer r.Unlock()
+	r.validators[registryKey(eventType, version)] = func(payload json.RawMessage) error {
+		var p P
+		err := json.Unmarshal(payload, &p)
+		if err != nil {
+			return fault.Wrap(err, fmsg.With(fmt.Sprintf("payload does not match %s v%d", eventType, version)))
+		}
+		if validate == nil {
+			return nil
+		}
+		return validate(p)
+	}
+}
+
+// Validate checks that the envelope's type and version are known and its
+// payload is valid.
+func (r *Registry) Validate(e Envelope) error {
+	r.RLock()
+	validate, ok := r.validators[registryKey(e.Type, e.Version)]
+	r.RUnlock()
+	if !ok {
+		return fault.New(fmt.Sprintf("unknown event %s v%d", e.Type, e.Version))
+	}
+	return validate(e.Payload)
+}
+
+// Wrap puts the payload in an envelope, carrying the trace context of ctx,
+// and validates it before it is published.
+func (r *Registry) Wrap(ctx context.Context, eventType string, version int, workspaceId string, payload any) (Envelope, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, fault.Wrap(err, fmsg.With("failed to marshal payload"))
+	}
+	carrier := propagation.MapCarrier{}
+	tracing.Propagator.Inject(ctx, carrier)
+
+	e := Envelope{
+		Type:        eventType,
+		Version:     version,
+		WorkspaceId: workspaceId,
+		TraceParent: carrier.Get("traceparent"),
+		TraceState:  carrier.Get("tracestate"),
+		Payload:     b,
+	}
+	err = r.Validate(e)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return e, nil
+}
+
+// Unwrap validates the envelope and decodes its payload. The returned context
+// continues the publisher's trace.
+func Unwrap[P any](ctx context.Context, r *Registry, e Envelope) (context.Context, P, error) {
+	var p P
+	err := r.Validate(e)
+	if err != nil {
+		return ctx, p, err
+	}
+	err = json.Unmarshal(e.Payload, &p)
+	if err != nil {
+		return ctx, p, fault.Wrap(err, fmsg.With("failed to unmarshal payload"))
+	}
+	ctx = tracing.Propagator.Extract(ctx, propagation.MapCarrier{
+		"traceparent": e.TraceParent,
+		"tracestate":  e.TraceState,
+	})
+	return ctx, p, nil
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type keyDeleted struct {
+	KeyId string `json:"keyId"`
+}
+
+func newRegistry() *Registry {
+	r := NewRegistry()
+	Register(r, "key.deleted", 1, func(e keyDeleted) error {
+		if e.KeyId == "" {
+			return errors.New("keyId is required")
+		}
+		return nil
+	})
+	return r
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	r := newRegistry()
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+	e, err := r.Wrap(ctx, "key.deleted", 1, "ws_1", keyDeleted{KeyId: "key_1"})
+	require.NoError(t, err)
+	require.Equal(t, "key.deleted", e.Type)
+	require.Equal(t, 1, e.Version)
+	require.Equal(t, "ws_1", e.WorkspaceId)
+	require.NotEmpty(t, e.TraceParent)
+
+	consumerCtx, payload, err := Unwrap[keyDeleted](context.Background(), r, e)
+	require.NoError(t, err)
+	require.Equal(t, keyDeleted{KeyId: "key_1"}, payload)
+	require.Equal(t, spanContext.TraceID(), trace.SpanContextFromContext(consumerCtx).TraceID())
+}
+
+func TestEnvelopeValidation(t *testing.T) {
+	r := newRegistry()
+
+	_, err := r.Wrap(context.Background(), "key.deleted", 1, "ws_1", keyDeleted{})
+	require.Error(t, err)
+
+	_, err = r.Wrap(context.Background(), "key.deleted", 2, "ws_1", keyDeleted{KeyId: "key_1"})
+	require.Error(t, err, "unregistered versions are rejected")
+
+	_, _, err = Unwrap[keyDeleted](context.Background(), r, Envelope{Type: "key.deleted", Version: 1, Payload: []byte(`{"keyId": 1}`)})
+	require.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+type HandlerConfig[E any] struct {
+	// Identifies the subscriber in logs and dead letters
+	Id     string
+	Handle func(ctx context.Context, event E) error
+
+	// How often an event is attempted before it is given up on, defaults to 3
+	MaxAttempts int
+	// How long to wait after the first failed attempt, doubled after every
+	// further attempt. Defaults to 100ms.
+	Backoff time.Duration
+
+	// Events that failed every attempt, or were still being retried when the
+	// handler stopped, are moved here. Without a dead letter queue they are
+	// dropped.
+	DeadLetters *DeadLetterQueue[E]
+
+	Logger logging.Logger
+}
+
+// Handle calls the handler for every event of the topic, one at a time, until
+// the returned function is called. Failed events are retried with backoff.
+func Handle[E any](t EventSubscriber[E], config HandlerConfig[E]) func() {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = 100 * time.Millisecond
+	}
+	if config.DeadLetters != nil {
+		config.DeadLetters.register(config.Id, config.Handle)
+	}
+
+	sub := t.SubscribeChannel(config.Id)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for e := range sub {
+			handle(ctx, config, e)
+		}
+	}()
+
+	return func() {
+		cancel()
+		t.Unsubscribe(sub)
+	}
+}
+
+func handle[E any](ctx context.Context, config HandlerConfig[E], e E) {
+	backoff := config.Backoff
+	var err error
+	attempts := 0
+retry:
+	for attempts < config.MaxAttempts {
+		attempts++
+		err = config.Handle(ctx, e)
+		if err == nil {
+			return
+		}
+		config.Logger.Warn().Err(err).Str("subscriber", config.Id).Int("attempt", attempts).Msg("failed to handle event")
+		if attempts == config.MaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			// Shutting down, keep the event rather than retrying it
+			break retry
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	if config.DeadLetters == nil {
+		config.Logger.Error().Err(err).Str("subscriber", config.Id).Int("attempts", attempts).Msg("dropping event")
+		return
+	}
+	config.DeadLetters.add(config.Id, e, err, attempts)
+}
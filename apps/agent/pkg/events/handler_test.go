@@ -0,0 +1,118 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+func TestHandleRetries(t *testing.T) {
+	topic := NewTopic[string]()
+	attempts := atomic.Int32{}
+	stop := Handle[string](topic, HandlerConfig[string]{
+		Id:          "test",
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		Logger:      logging.NewNoopLogger(),
+		Handle: func(ctx context.Context, event string) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("not yet")
+			}
+			return nil
+		},
+	})
+	defer stop()
+
+	topic.Publish(context.Background(), "a")
+	require.Eventually(t, func() bool { return attempts.Load() == 3 }, time.Second, time.Millisecond)
+}
+
+func TestHandleMovesToDeadLetters(t *testing.T) {
+	topic := NewTopic[string]()
+	dlq := NewDeadLetterQueue[string](10, logging.NewNoopLogger())
+	fail := atomic.Bool{}
+	fail.Store(true)
+	stop := Handle[string](topic, HandlerConfig[string]{
+		Id:          "test",
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+		DeadLetters: dlq,
+		Logger:      logging.NewNoopLogger(),
+		Handle: func(ctx context.Context, event string) error {
+			if fail.Load() {
+				return errors.New("unavailable")
+			}
+			return nil
+		},
+	})
+	defer stop()
+
+	topic.Publish(context.Background(), "a")
+	require.Eventually(t, func() bool { return len(dlq.List()) == 1 }, time.Second, time.Millisecond)
+
+	l := dlq.List()[0]
+	require.Equal(t, "test", l.Subscriber)
+	require.Equal(t, "a", l.Event)
+	require.Equal(t, "unavailable", l.Error)
+	require.Equal(t, 2, l.Attempts)
+
+	summaries := dlq.Inspect()
+	require.Len(t, summaries, 1)
+	require.JSONEq(t, `"a"`, string(summaries[0].Event))
+
+	// still failing, the dead letter is kept
+	require.Error(t, dlq.Requeue(context.Background(), l.Id))
+	require.Equal(t, 3, dlq.List()[0].Attempts)
+
+	fail.Store(false)
+	require.NoError(t, dlq.Requeue(context.Background(), l.Id))
+	require.Empty(t, dlq.List())
+
+	require.ErrorIs(t, dlq.Requeue(context.Background(), l.Id), ErrDeadLetterNotFound)
+}
+
+func TestHandleDeadLettersWhenStoppedDuringBackoff(t *testing.T) {
+	topic := NewTopic[string]()
+	dlq := NewDeadLetterQueue[string](10, logging.NewNoopLogger())
+	failed := make(chan struct{}, 1)
+	stop := Handle[string](topic, HandlerConfig[string]{
+		Id:          "test",
+		MaxAttempts: 5,
+		Backoff:     time.Hour,
+		DeadLetters: dlq,
+		Logger:      logging.NewNoopLogger(),
+		Handle: func(ctx context.Context, event string) error {
+			failed <- struct{}{}
+			return errors.New("unavailable")
+		},
+	})
+
+	topic.Publish(context.Background(), "a")
+	<-failed
+	stop()
+
+	require.Eventually(t, func() bool { return len(dlq.List()) == 1 }, time.Second, time.Millisecond)
+	l := dlq.List()[0]
+	require.Equal(t, "a", l.Event)
+	require.Equal(t, 1, l.Attempts)
+}
+
+func TestDeadLetterQueueIsBounded(t *testing.T) {
+	dlq := NewDeadLetterQueue[int](2, logging.NewNoopLogger())
+	for i := 0; i < 3; i++ {
+		dlq.add("test", i, errors.New("failed"), 1)
+	}
+	letters := dlq.List()
+	require.Len(t, letters, 2)
+	require.Equal(t, 1, letters[0].Event)
+	require.Equal(t, 2, letters[1].Event)
+
+	require.True(t, dlq.Remove(letters[0].Id))
+	require.False(t, dlq.Remove(letters[0].Id))
+	require.Len(t, dlq.List(), 1)
+}
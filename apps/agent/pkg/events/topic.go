@@ -5,21 +5,33 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/unkeyed/unkey/apps/agent/pkg/prometheus"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-type EventEmitter[E any] interface {
-	Emit(ctx context.Context, event E)
+type EventPublisher[E any] interface {
+	Publish(ctx context.Context, event E)
+	// TryPublish delivers the event to every subscriber with room in its
+	// buffer, without blocking. It returns false if any subscriber missed it.
+	TryPublish(ctx context.Context, event E) bool
 }
 
 type EventSubscriber[E any] interface {
-	Subscribe(id string) <-chan E
+	// Subscribe calls the handler for every event, one at a time, on its own
+	// goroutine until the returned function is called.
+	// An error returned by the handler only affects this subscriber, it is
+	// recorded and the next event is handled. Use Handle to retry instead.
+	Subscribe(id string, handler func(ctx context.Context, event E) error) func()
+	// SubscribeChannel returns a channel that will receive events from the topic
+	SubscribeChannel(id string) <-chan E
+	// Unsubscribe stops sending events to the channel and closes it
+	Unsubscribe(ch <-chan E)
 }
 
 type Topic[E any] interface {
-	EventEmitter[E]
+	EventPublisher[E]
 	EventSubscriber[E]
 }
 
@@ -28,10 +40,22 @@ type listener[E any] struct {
 	ch chan E
 }
 
+// delivery carries the publisher's span, so handlers continue its trace
+type delivery[E any] struct {
+	span  trace.SpanContext
+	event E
+}
+
+type handlerListener[E any] struct {
+	id string
+	ch chan delivery[E]
+}
+
 type topic[E any] struct {
 	sync.RWMutex
 	bufferSize int
 	listeners  []listener[E]
+	handlers   []handlerListener[E]
 }
 
 // NewTopic creates a new topic with an optional buffer size
@@ -44,30 +68,113 @@ func NewTopic[E any](bufferSize ...int) Topic[E] {
 	return &topic[E]{
 		bufferSize: n,
 		listeners:  []listener[E]{},
+		handlers:   []handlerListener[E]{},
 	}
 }
 
-func (t *topic[E]) Emit(ctx context.Context, event E) {
+// Publish delivers the event to every subscriber and blocks while the buffer
+// of any of them is full.
+func (t *topic[E]) Publish(ctx context.Context, event E) {
 
 	t.Lock()
 	defer t.Unlock()
 	for _, l := range t.listeners {
 		var span trace.Span
-		ctx, span = tracing.Start(ctx, fmt.Sprintf("topic.Emit:%s", l.id))
+		ctx, span = tracing.Start(ctx, fmt.Sprintf("topic.Publish:%s", l.id))
 		span.SetAttributes(attribute.Int("channelSize", len(l.ch)))
 		l.ch <- event
 		span.End()
 	}
+	for _, h := range t.handlers {
+		var span trace.Span
+		ctx, span = tracing.Start(ctx, fmt.Sprintf("topic.Publish:%s", h.id))
+		span.SetAttributes(attribute.Int("channelSize", len(h.ch)))
+		h.ch <- delivery[E]{span: span.SpanContext(), event: event}
+		span.End()
+	}
+
+}
+
+func (t *topic[E]) TryPublish(ctx context.Context, event E) bool {
+	t.Lock()
+	defer t.Unlock()
+	delivered := true
+	for _, l := range t.listeners {
+		var span trace.Span
+		ctx, span = tracing.Start(ctx, fmt.Sprintf("topic.Publish:%s", l.id))
+		select {
+		case l.ch <- event:
+		default:
+			delivered = false
+		}
+		span.End()
+	}
+	for _, h := range t.handlers {
+		var span trace.Span
+		ctx, span = tracing.Start(ctx, fmt.Sprintf("topic.Publish:%s", h.id))
+		select {
+		case h.ch <- delivery[E]{span: span.SpanContext(), event: event}:
+		default:
+			delivered = false
+		}
+		span.End()
+	}
+	return delivered
+}
+
+func (t *topic[E]) Subscribe(id string, handler func(ctx context.Context, event E) error) func() {
+	t.Lock()
+	ch := make(chan delivery[E], t.bufferSize)
+	t.handlers = append(t.handlers, handlerListener[E]{id: id, ch: ch})
+	t.Unlock()
 
+	go func() {
+		for d := range ch {
+			ctx, span := tracing.Start(trace.ContextWithRemoteSpanContext(context.Background(), d.span), fmt.Sprintf("topic.Handle:%s", id))
+			err := handler(ctx, d.event)
+			if err != nil {
+				tracing.RecordError(span, err)
+				prometheus.EventHandlerErrors.WithLabelValues(id).Inc()
+			}
+			span.End()
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.Lock()
+			defer t.Unlock()
+			for i, h := range t.handlers {
+				if h.ch == ch {
+					close(h.ch)
+					t.handlers = append(t.handlers[:i], t.handlers[i+1:]...)
+					return
+				}
+			}
+		})
+	}
 }
 
-// Subscribe returns a channel that will receive events from the topic
+// SubscribeChannel returns a channel that will receive events from the topic
 // The channel will be closed when the topic is closed
 // The id is used for debugging and tracing, not for uniqueness
-func (t *topic[E]) Subscribe(id string) <-chan E {
+func (t *topic[E]) SubscribeChannel(id string) <-chan E {
 	t.Lock()
 	defer t.Unlock()
 	ch := make(chan E, t.bufferSize)
 	t.listeners = append(t.listeners, listener[E]{id: id, ch: ch})
 	return ch
 }
+
+func (t *topic[E]) Unsubscribe(ch <-chan E) {
+	t.Lock()
+	defer t.Unlock()
+	for i, l := range t.listeners {
+		if l.ch == ch {
+			close(l.ch)
+			t.listeners = append(t.listeners[:i], t.listeners[i+1:]...)
+			return
+		}
+	}
+}
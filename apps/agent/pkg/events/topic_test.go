@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeIsolatesFailingSubscribers(t *testing.T) {
+	topic := NewTopic[int](10)
+
+	stopFailing := topic.Subscribe("failing", func(ctx context.Context, event int) error {
+		return errors.New("always fails")
+	})
+	defer stopFailing()
+
+	mu := sync.Mutex{}
+	received := []int{}
+	stop := topic.Subscribe("collecting", func(ctx context.Context, event int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event)
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		topic.Publish(context.Background(), i)
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	}, time.Second, time.Millisecond)
+	require.Equal(t, []int{0, 1, 2}, received)
+
+	// stopping twice is fine and no longer delivers events
+	stop()
+	stop()
+	topic.Publish(context.Background(), 3)
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 3)
+}
+
+func TestTryPublishSkipsFullSubscribers(t *testing.T) {
+	topic := NewTopic[int](1)
+	a := topic.SubscribeChannel("a")
+	b := topic.SubscribeChannel("b")
+
+	require.True(t, topic.TryPublish(context.Background(), 1))
+	// drain only a, b is full now
+	require.Equal(t, 1, <-a)
+
+	require.False(t, topic.TryPublish(context.Background(), 2))
+	require.Equal(t, 2, <-a)
+	require.Equal(t, 1, <-b)
+	require.Len(t, b, 0)
+}
@@ -0,0 +1,92 @@
+package geoip
+
+import (
+	"net"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+// Location is what we know about where a request came from.
+// Fields are empty if they could not be resolved.
+type Location struct {
+	// ISO 3166-1 alpha-2 country code, e.g. "DE"
+	Country string
+	// Autonomous system number of the network
+	ASN uint32
+}
+
+type Resolver interface {
+	Lookup(ip string) Location
+}
+
+type Config struct {
+	// Path to a MaxMind GeoIP2 or GeoLite2 Country or City database
+	CountryDatabase string
+	// Path to a MaxMind GeoIP2 or GeoLite2 ASN database
+	AsnDatabase string
+
+	Logger logging.Logger
+}
+
+type resolver struct {
+	country *mmdb
+	asn     *mmdb
+	logger  logging.Logger
+}
+
+// New loads the configured MaxMind databases into memory. Either database is
+// optional, the corresponding fields are left empty without it.
+func New(config Config) (Resolver, error) {
+	r := &resolver{logger: config.Logger}
+	var err error
+	if config.CountryDatabase != "" {
+		r.country, err = openMMDB(config.CountryDatabase)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.AsnDatabase != "" {
+		r.asn, err = openMMDB(config.AsnDatabase)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *resolver) Lookup(ip string) Location {
+	loc := Location{}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return loc
+	}
+
+	if r.country != nil {
+		record, err := r.country.lookup(parsed)
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("failed to look up country")
+		}
+		if country, ok := record["country"].(map[string]any); ok {
+			loc.Country, _ = country["iso_code"].(string)
+		}
+	}
+	if r.asn != nil {
+		record, err := r.asn.lookup(parsed)
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("failed to look up asn")
+		}
+		loc.ASN = uint32(asUint(record["autonomous_system_number"]))
+	}
+	return loc
+}
+
+type noop struct{}
+
+// NewNoop returns a resolver that never resolves anything.
+func NewNoop() Resolver {
+	return noop{}
+}
+
+func (noop) Lookup(ip string) Location {
+	return Location{}
+}
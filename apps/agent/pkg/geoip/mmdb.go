@@ -0,0 +1,317 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+)
+
+// metadataMarker precedes the metadata section at the end of every MaxMind DB
+// file, see https://maxmind.github.io/MaxMind-DB/
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the number of zero bytes between the search tree and
+// the data section.
+const dataSectionSeparator = 16
+
+// mmdb is a minimal reader for MaxMind DB files. It loads the whole file into
+// memory and decodes records into plain go values on lookup.
+type mmdb struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// The node where the ipv4 subtree starts in an ipv6 database
+	ipv4Start uint
+}
+
+func openMMDB(path string) (*mmdb, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fault.Wrap(err, fmsg.With("failed to read maxmind database"))
+	}
+	return newMMDB(buf)
+}
+
+func newMMDB(buf []byte) (*mmdb, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, fault.New("invalid maxmind database, metadata not found")
+	}
+	metaStart := i + len(metadataMarker)
+	raw, _, err := decoder{data: buf[metaStart:]}.decode(0)
+	if err != nil {
+		return nil, fault.Wrap(err, fmsg.With("failed to decode maxmind metadata"))
+	}
+	meta, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fault.New("invalid maxmind metadata")
+	}
+
+	db := &mmdb{
+		buf:        buf,
+		nodeCount:  uint(asUint(meta["node_count"])),
+		recordSize: uint(asUint(meta["record_size"])),
+		ipVersion:  uint(asUint(meta["ip_version"])),
+	}
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fault.New("unsupported maxmind record size")
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(i) {
+		return nil, fault.New("invalid maxmind database, search tree exceeds file")
+	}
+	db.data = buf[treeSize+dataSectionSeparator : i]
+
+	if db.ipVersion == 6 {
+		// ipv4 addresses are stored as ::a.b.c.d, so skip the 96 leading zero bits
+		node := uint(0)
+		for j := 0; j < 96 && node < db.nodeCount; j++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// lookup returns the record for the ip, or nil if the database has none.
+func (db *mmdb) lookup(ip net.IP) (map[string]any, error) {
+	node := uint(0)
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		bits = 32
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		// ipv6 addresses can't be found in an ipv4 database
+		return nil, nil
+	}
+
+	for i := 0; i < bits && node < db.nodeCount; i++ {
+		bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+		node = db.record(node, uint(bit))
+	}
+
+	if node == db.nodeCount {
+		return nil, nil
+	}
+	if node < db.nodeCount {
+		return nil, fault.New("invalid maxmind database, ran out of address bits")
+	}
+
+	offset := node - db.nodeCount - dataSectionSeparator
+	raw, _, err := decoder{data: db.data}.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fault.New("invalid maxmind record")
+	}
+	return record, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a node.
+func (db *mmdb) record(node uint, bit uint) uint {
+	size := db.recordSize / 4
+	b := db.buf[node*size : (node+1)*size]
+	switch db.recordSize {
+	case 24:
+		if bit == 0 {
+			return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3])<<16 | uint(b[4])<<8 | uint(b[5])
+	case 28:
+		// The middle byte holds the most significant bits of both records
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		if bit == 0 {
+			return uint(binary.BigEndian.Uint32(b[0:4]))
+		}
+		return uint(binary.BigEndian.Uint32(b[4:8]))
+	}
+}
+
+// Data section types
+const (
+	typeExtended = 0
+	typePointer  = 1
+	typeString   = 2
+	typeDouble   = 3
+	typeBytes    = 4
+	typeUint16   = 5
+	typeUint32   = 6
+	typeMap      = 7
+	typeInt32    = 8
+	typeUint64   = 9
+	typeUint128  = 10
+	typeArray    = 11
+	typeBool     = 14
+	typeFloat    = 15
+)
+
+type decoder struct {
+	data []byte
+}
+
+// decode decodes the value at offset and returns it together with the offset of
+// the next value.
+func (d decoder) decode(offset uint) (any, uint, error) {
+	if offset >= uint(len(d.data)) {
+		return nil, 0, fault.New("maxmind data offset out of range")
+	}
+	ctrl := d.data[offset]
+	offset++
+	typ := uint(ctrl >> 5)
+
+	if typ == typePointer {
+		pointer, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		// Pointers never point to other pointers, and decoding continues after
+		// the pointer itself rather than after the value it points to.
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+
+	if typ == typeExtended {
+		if offset >= uint(len(d.data)) {
+			return nil, 0, fault.New("maxmind data offset out of range")
+		}
+		typ = 7 + uint(d.data[offset])
+		offset++
+	}
+
+	size, offset, err := d.size(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			var key, value any
+			key, offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fault.New("maxmind map key is not a string")
+			}
+			m[k] = value
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, size)
+		for i := range a {
+			a[i], offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.data)) {
+		return nil, 0, fault.New("maxmind value exceeds data section")
+	}
+	b := d.data[offset : offset+size]
+	next := offset + size
+
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes, typeUint128:
+		return append([]byte{}, b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fault.New("invalid maxmind double")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fault.New("invalid maxmind float")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		u := uint64(0)
+		for _, c := range b {
+			u = u<<8 | uint64(c)
+		}
+		return u, next, nil
+	case typeInt32:
+		u := uint32(0)
+		for _, c := range b {
+			u = u<<8 | uint32(c)
+		}
+		return int32(u), next, nil
+	default:
+		return nil, 0, fault.New("unsupported maxmind data type")
+	}
+}
+
+func (d decoder) size(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+	n := size - 28
+	if offset+n > uint(len(d.data)) {
+		return 0, 0, fault.New("maxmind size exceeds data section")
+	}
+	b := d.data[offset : offset+n]
+	switch size {
+	case 29:
+		return 29 + uint(b[0]), offset + n, nil
+	case 30:
+		return 285 + (uint(b[0])<<8 | uint(b[1])), offset + n, nil
+	default:
+		return 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])), offset + n, nil
+	}
+}
+
+func (d decoder) pointer(ctrl byte, offset uint) (uint, uint, error) {
+	ss := uint(ctrl>>3) & 0x3
+	n := ss + 1
+	if offset+n > uint(len(d.data)) {
+		return 0, 0, fault.New("maxmind pointer exceeds data section")
+	}
+	b := d.data[offset : offset+n]
+	vvv := uint(ctrl & 0x7)
+	switch ss {
+	case 0:
+		return vvv<<8 | uint(b[0]), offset + n, nil
+	case 1:
+		return (vvv<<16 | uint(b[0])<<8 | uint(b[1])) + 2048, offset + n, nil
+	case 2:
+		return (vvv<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336, offset + n, nil
+	default:
+		return uint(binary.BigEndian.Uint32(b)), offset + n, nil
+	}
+}
+
+func asUint(v any) uint64 {
+	u, _ := v.(uint64)
+	return u
+}
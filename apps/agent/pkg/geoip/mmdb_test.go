@@ -0,0 +1,107 @@
+package geoip
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+func encodeString(s string) []byte {
+	return append([]byte{byte(typeString<<5 | len(s))}, s...)
+}
+
+func encodeUint(typ int, b ...byte) []byte {
+	return append([]byte{byte(typ<<5 | len(b))}, b...)
+}
+
+func encodeMap(pairs ...[]byte) []byte {
+	out := []byte{byte(typeMap<<5 | len(pairs)/2)}
+	for _, p := range pairs {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// buildDatabase creates an ipv4 database with 24 bit records, mapping 1.0.0.0/8
+// to the given record.
+func buildDatabase(t *testing.T, record []byte) []byte {
+	t.Helper()
+	const nodeCount = 8
+	tree := []byte{}
+	put := func(v uint) {
+		tree = append(tree, byte(v>>16), byte(v>>8), byte(v))
+	}
+	// 1.0.0.0/8 is 0b00000001, so follow the left record seven times, then the right one
+	for node := uint(0); node < nodeCount; node++ {
+		if node < nodeCount-1 {
+			put(node + 1)
+			put(nodeCount)
+		} else {
+			put(nodeCount)
+			// the record is at offset 0 of the data section
+			put(nodeCount + dataSectionSeparator)
+		}
+	}
+
+	buf := append(tree, make([]byte, dataSectionSeparator)...)
+	buf = append(buf, record...)
+	buf = append(buf, metadataMarker...)
+	buf = append(buf, encodeMap(
+		encodeString("node_count"), encodeUint(typeUint32, nodeCount),
+		encodeString("record_size"), encodeUint(typeUint16, 24),
+		encodeString("ip_version"), encodeUint(typeUint16, 4),
+	)...)
+	return buf
+}
+
+func TestLookupCountry(t *testing.T) {
+	buf := buildDatabase(t, encodeMap(
+		encodeString("country"), encodeMap(encodeString("iso_code"), encodeString("AU")),
+	))
+	db, err := newMMDB(buf)
+	require.NoError(t, err)
+
+	record, err := db.lookup(net.ParseIP("1.2.3.4"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"country": map[string]any{"iso_code": "AU"}}, record)
+
+	record, err = db.lookup(net.ParseIP("2.2.3.4"))
+	require.NoError(t, err)
+	require.Nil(t, record)
+
+	record, err = db.lookup(net.ParseIP("2001:db8::1"))
+	require.NoError(t, err)
+	require.Nil(t, record)
+}
+
+func TestResolver(t *testing.T) {
+	dir := t.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	asnPath := filepath.Join(dir, "asn.mmdb")
+	require.NoError(t, os.WriteFile(countryPath, buildDatabase(t, encodeMap(
+		encodeString("country"), encodeMap(encodeString("iso_code"), encodeString("AU")),
+	)), 0644))
+	require.NoError(t, os.WriteFile(asnPath, buildDatabase(t, encodeMap(
+		encodeString("autonomous_system_number"), encodeUint(typeUint32, 0x34, 0x17),
+	)), 0644))
+
+	r, err := New(Config{
+		CountryDatabase: countryPath,
+		AsnDatabase:     asnPath,
+		Logger:          logging.NewNoopLogger(),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, Location{Country: "AU", ASN: 13335}, r.Lookup("1.1.1.1"))
+	require.Equal(t, Location{}, r.Lookup("8.8.8.8"))
+	require.Equal(t, Location{}, r.Lookup("not an ip"))
+}
+
+func TestInvalidDatabase(t *testing.T) {
+	_, err := newMMDB([]byte("definitely not a maxmind database"))
+	require.Error(t, err)
+}
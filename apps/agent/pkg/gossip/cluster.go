@@ -99,7 +99,7 @@ func New(config Config) (*cluster, error) {
 //
 // Stops automatic when a message from the shutdown topic is received
 func (c *cluster) run() {
-	stop := c.shutdown.Subscribe("cluster shutdown")
+	stop := c.shutdown.SubscribeChannel("cluster shutdown")
 	t := time.NewTicker(c.config.GossipInterval)
 
 	for {
@@ -180,7 +180,7 @@ func (c *cluster) Join(ctx context.Context, rpcAddrs ...string) error {
 
 		for _, m := range resp.Msg.Members {
 			c.members[m.NodeId] = m
-			c.memberJoinTopic.Emit(ctx, Member{
+			c.memberJoinTopic.Publish(ctx, Member{
 				NodeId:  m.NodeId,
 				RpcAddr: m.RpcAddr,
 			})
@@ -204,7 +204,7 @@ func (c *cluster) Join(ctx context.Context, rpcAddrs ...string) error {
 
 func (c *cluster) Shutdown(ctx context.Context) error {
 
-	c.shutdown.Emit(ctx, true)
+	c.shutdown.Publish(ctx, true)
 
 	c.Lock()
 	defer c.Unlock()
@@ -252,15 +252,15 @@ func (c *cluster) Shutdown(ctx context.Context) error {
 }
 
 func (c *cluster) SubscribeJoinEvents(callerName string) <-chan Member {
-	return c.memberJoinTopic.Subscribe(callerName)
+	return c.memberJoinTopic.SubscribeChannel(callerName)
 }
 
 func (c *cluster) SubscribeUpdateEvents(callerName string) <-chan Member {
-	return c.memberUpdateTopic.Subscribe(callerName)
+	return c.memberUpdateTopic.SubscribeChannel(callerName)
 }
 
 func (c *cluster) SubscribeLeaveEvents(callerName string) <-chan Member {
-	return c.memberLeaveTopic.Subscribe(callerName)
+	return c.memberLeaveTopic.SubscribeChannel(callerName)
 }
 
 func (c *cluster) randomPeers(n int, withoutNodeIds ...string) ([]*gossipv1.Member, error) {
@@ -307,7 +307,7 @@ func (c *cluster) addMemberToState(ctx context.Context, member *gossipv1.Member)
 	c.members[member.NodeId] = member
 
 	if !ok {
-		c.memberJoinTopic.Emit(ctx, Member{
+		c.memberJoinTopic.Publish(ctx, Member{
 			NodeId:  member.NodeId,
 			RpcAddr: member.RpcAddr,
 		})
@@ -324,7 +324,7 @@ func (c *cluster) removeMemberFromState(ctx context.Context, nodeId string) {
 	}
 
 	delete(c.members, member.NodeId)
-	c.memberLeaveTopic.Emit(ctx, Member{
+	c.memberLeaveTopic.Publish(ctx, Member{
 		NodeId:  member.NodeId,
 		RpcAddr: member.RpcAddr,
 	})
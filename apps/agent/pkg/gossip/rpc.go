@@ -26,7 +26,7 @@ func (c *cluster) join(ctx context.Context, req *gossipv1.JoinRequest) (*gossipv
 
 	existing, ok := c.members[req.Self.NodeId]
 	if !ok {
-		c.memberJoinTopic.Emit(ctx, newMember)
+		c.memberJoinTopic.Publish(ctx, newMember)
 		c.members[req.Self.NodeId] = req.Self
 	} else {
 		e, err := proto.Marshal(existing)
@@ -38,7 +38,7 @@ func (c *cluster) join(ctx context.Context, req *gossipv1.JoinRequest) (*gossipv
 			return nil, fault.Wrap(err, fmsg.With("failed to marshal new member"))
 		}
 		if !bytes.Equal(e, j) {
-			c.memberUpdateTopic.Emit(ctx, newMember)
+			c.memberUpdateTopic.Publish(ctx, newMember)
 			c.members[req.Self.NodeId] = req.Self
 		}
 
@@ -58,7 +58,7 @@ func (c *cluster) leave(ctx context.Context, req *gossipv1.LeaveRequest) (*gossi
 	c.Lock()
 	delete(c.members, req.Self.NodeId)
 	c.Unlock()
-	c.memberLeaveTopic.Emit(ctx, Member{
+	c.memberLeaveTopic.Publish(ctx, Member{
 		NodeId:  req.Self.NodeId,
 		RpcAddr: req.Self.RpcAddr,
 	})
@@ -0,0 +1,176 @@
+package loadshedding
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/clock"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+// Limiter admits requests while fewer than its limit are in flight, so
+// requests beyond our capacity are rejected right away instead of queueing
+// up and dragging down the latency of all others.
+//
+// The limit adapts to the latency of admitted requests: it grows by one for
+// every request that finished within the target latency while at least half
+// of the limit was used, and shrinks by a tenth whenever one took longer.
+// Requests that were already in flight when the limit shrank don't shrink it
+// again, so a single slow period lowers the limit once rather than once per
+// request that was caught up in it.
+type Limiter struct {
+	sync.Mutex
+	config *config
+
+	limit    float64
+	inFlight int
+	// when the limit was last lowered
+	lastDecrease time.Time
+}
+
+type config struct {
+	name string
+
+	// The limit before any requests were observed
+	initialLimit int
+
+	// Bounds of the limit, no matter the latency
+	minLimit int
+	maxLimit int
+
+	// Requests that take longer indicate we are overloaded
+	targetLatency time.Duration
+
+	// Clock to use for timing, defaults to the system clock but can be overridden for testing
+	clock clock.Clock
+
+	logger logging.Logger
+}
+
+func WithInitialLimit(initialLimit int) applyConfig {
+	return func(c *config) {
+		c.initialLimit = initialLimit
+	}
+}
+
+func WithMinLimit(minLimit int) applyConfig {
+	return func(c *config) {
+		c.minLimit = minLimit
+	}
+}
+
+func WithMaxLimit(maxLimit int) applyConfig {
+	return func(c *config) {
+		c.maxLimit = maxLimit
+	}
+}
+
+func WithTargetLatency(targetLatency time.Duration) applyConfig {
+	return func(c *config) {
+		c.targetLatency = targetLatency
+	}
+}
+
+// for testing
+func WithClock(clock clock.Clock) applyConfig {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+func WithLogger(logger logging.Logger) applyConfig {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// applyConfig applies a config setting to the limiter
+type applyConfig func(*config)
+
+func New(name string, applyConfigs ...applyConfig) *Limiter {
+	cfg := &config{
+		name:          name,
+		initialLimit:  100,
+		minLimit:      10,
+		maxLimit:      1000,
+		targetLatency: 250 * time.Millisecond,
+		clock:         clock.New(),
+		logger:        logging.New(nil),
+	}
+
+	for _, apply := range applyConfigs {
+		apply(cfg)
+	}
+
+	l := &Limiter{
+		config: cfg,
+		limit:  math.Min(math.Max(float64(cfg.initialLimit), float64(cfg.minLimit)), float64(cfg.maxLimit)),
+	}
+	limit.WithLabelValues(cfg.name).Set(l.limit)
+	return l
+}
+
+// Acquire admits a request, unless the limit is reached. Admitted requests
+// must call release once they are done.
+func (l *Limiter) Acquire() (release func(), ok bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.inFlight >= int(l.limit) {
+		shed.WithLabelValues(l.config.name).Inc()
+		return nil, false
+	}
+	l.inFlight++
+	inFlight.WithLabelValues(l.config.name).Set(float64(l.inFlight))
+
+	start := l.config.clock.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.release(start, l.config.clock.Now())
+		})
+	}, true
+}
+
+func (l *Limiter) release(start time.Time, end time.Time) {
+	latency := end.Sub(start)
+
+	l.Lock()
+	defer l.Unlock()
+
+	// Checked before decrementing, the request itself counts towards utilization
+	utilized := float64(l.inFlight) >= l.limit/2
+	l.inFlight--
+	inFlight.WithLabelValues(l.config.name).Set(float64(l.inFlight))
+
+	next := l.limit
+	if latency > l.config.targetLatency {
+		if start.After(l.lastDecrease) {
+			next = math.Max(float64(l.config.minLimit), l.limit*0.9)
+			l.lastDecrease = end
+		}
+	} else if utilized {
+		next = math.Min(float64(l.config.maxLimit), l.limit+1)
+	}
+	if int(next) < int(l.limit) {
+		l.config.logger.Debug().Str("name", l.config.name).Int("limit", int(next)).Dur("latency", latency).Msg("lowering concurrency limit")
+	}
+	l.limit = next
+	limit.WithLabelValues(l.config.name).Set(l.limit)
+}
+
+// Limit returns the current limit, rounded down.
+func (l *Limiter) Limit() int {
+	l.Lock()
+	defer l.Unlock()
+	return int(l.limit)
+}
+
+// Utilization returns the share of the limit that is in flight, from 0 for idle
+// to 1 when every further request is shed.
+func (l *Limiter) Utilization() float64 {
+	l.Lock()
+	defer l.Unlock()
+	return math.Min(float64(l.inFlight)/l.limit, 1)
+}
@@ -0,0 +1,93 @@
+package loadshedding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clock"
+)
+
+func TestShedsBeyondLimit(t *testing.T) {
+	l := New("test", WithInitialLimit(2), WithMinLimit(1), WithMaxLimit(2))
+
+	r1, ok := l.Acquire()
+	require.True(t, ok)
+	_, ok = l.Acquire()
+	require.True(t, ok)
+
+	_, ok = l.Acquire()
+	require.False(t, ok)
+
+	r1()
+	// releasing twice must not free another slot
+	r1()
+	_, ok = l.Acquire()
+	require.True(t, ok)
+	_, ok = l.Acquire()
+	require.False(t, ok)
+}
+
+func TestLimitAdaptsToLatency(t *testing.T) {
+	c := clock.NewTestClock()
+	l := New("test", WithInitialLimit(10), WithMinLimit(5), WithMaxLimit(12), WithTargetLatency(100*time.Millisecond), WithClock(c))
+
+	acquireAll := func(n int) []func() {
+		releases := make([]func(), n)
+		for i := range releases {
+			release, ok := l.Acquire()
+			require.True(t, ok)
+			releases[i] = release
+		}
+		return releases
+	}
+
+	// fast requests while utilized raise the limit, up to the max
+	for _, release := range acquireAll(10) {
+		c.Tick(10 * time.Millisecond)
+		release()
+	}
+	require.Equal(t, 12, l.Limit())
+
+	// slow requests lower it, down to the min
+	for range 20 {
+		release, ok := l.Acquire()
+		require.True(t, ok)
+		c.Tick(time.Second)
+		release()
+	}
+	require.Equal(t, 5, l.Limit())
+
+	// fast requests while mostly idle leave it alone
+	release, ok := l.Acquire()
+	require.True(t, ok)
+	release()
+	require.Equal(t, 5, l.Limit())
+}
+
+func TestLimitDecreasesOncePerSlowPeriod(t *testing.T) {
+	c := clock.NewTestClock()
+	l := New("test", WithInitialLimit(100), WithMinLimit(10), WithMaxLimit(100), WithTargetLatency(100*time.Millisecond), WithClock(c))
+
+	releases := make([]func(), 50)
+	for i := range releases {
+		release, ok := l.Acquire()
+		require.True(t, ok)
+		releases[i] = release
+	}
+
+	// All of them were caught in the same slow period
+	c.Tick(time.Second)
+	for _, release := range releases {
+		release()
+	}
+	require.Equal(t, 90, l.Limit())
+
+	// A request started afterwards lowers it again
+	c.Tick(time.Millisecond)
+	release, ok := l.Acquire()
+	require.True(t, ok)
+	c.Tick(time.Second)
+	release()
+	require.Equal(t, 81, l.Limit())
+}
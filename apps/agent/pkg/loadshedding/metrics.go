@@ -0,0 +1,28 @@
+package loadshedding
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	limit = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "agent",
+		Subsystem: "loadshedding",
+		Name:      "limit",
+		Help:      "How many requests may be in flight before new ones are shed",
+	}, []string{"name"})
+
+	inFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "agent",
+		Subsystem: "loadshedding",
+		Name:      "in_flight",
+	}, []string{"name"})
+
+	shed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "agent",
+		Subsystem: "loadshedding",
+		Name:      "shed_total",
+		Help:      "Requests that were rejected because the limit was reached",
+	}, []string{"name"})
+)
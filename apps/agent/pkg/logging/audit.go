@@ -0,0 +1,182 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Every line of the audit log ends with the hash of the previous line and the
+// hash of the line itself, up to and including the previous hash:
+//
+//	{"level":"info",...,"prev":"<hex>","hash":"<hex>"}
+//
+// Hashes are HMAC-SHA256 with a secret key, so whoever can edit the file can't
+// recompute the chain without the key as well. Changing, removing or
+// reordering lines breaks the chain, see VerifyAuditLog.
+var (
+	prevField = []byte(`,"prev":"`)
+	hashField = []byte(`,"hash":"`)
+)
+
+// AuditWriter appends hash chained lines to a file.
+type AuditWriter struct {
+	mu   sync.Mutex
+	f    *os.File
+	key  []byte
+	prev string
+
+	torn int64
+}
+
+// NewAuditWriter opens or creates the audit log at path and continues its chain.
+//
+// A partial last line, left behind when the process died while writing it,
+// is cut off and the chain continues from the last complete line. The cut is
+// recorded in the audit log itself and reported by TornBytes.
+func NewAuditWriter(path string, key []byte) (*AuditWriter, error) {
+	if len(key) == 0 {
+		return nil, errors.New("audit log key must not be empty")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %w", err)
+	}
+	prev, complete, err := lastHash(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w := &AuditWriter{f: f, key: key, prev: prev}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to stat audit log: %w", err)
+	}
+	if info.Size() > complete {
+		w.torn = info.Size() - complete
+		err = f.Truncate(complete)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("unable to truncate torn audit log line: %w", err)
+		}
+		logger := NewAuditLogger(w)
+		logger.Warn().Int64("bytes", w.torn).Msg("truncated torn audit log line")
+	}
+	return w, nil
+}
+
+// TornBytes returns how many bytes of a partial last line were cut off when
+// the audit log was opened.
+func (w *AuditWriter) TornBytes() int64 {
+	return w.torn
+}
+
+func (w *AuditWriter) Write(p []byte) (int, error) {
+	line := bytes.TrimRight(p, "\n")
+	if !bytes.HasSuffix(line, []byte("}")) {
+		return 0, fmt.Errorf("audit log lines must be json objects")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	chained := make([]byte, 0, len(line)+len(prevField)+len(hashField)+2*64+4)
+	chained = append(chained, line[:len(line)-1]...)
+	chained = append(chained, prevField...)
+	chained = append(chained, w.prev...)
+	chained = append(chained, '"')
+	hash := hashLine(w.key, chained)
+	chained = append(chained, hashField...)
+	chained = append(chained, hash...)
+	chained = append(chained, '"', '}', '\n')
+
+	_, err := w.f.Write(chained)
+	if err != nil {
+		return 0, err
+	}
+	w.prev = hash
+	return len(p), nil
+}
+
+func (w *AuditWriter) Close() error {
+	return w.f.Close()
+}
+
+// NewAuditLogger returns a logger that writes to the audit log only.
+func NewAuditLogger(w *AuditWriter) Logger {
+	return zerolog.New(w).With().Timestamp().Logger()
+}
+
+// VerifyAuditLog checks that the hash chain of an audit log is intact.
+func VerifyAuditLog(r io.Reader, key []byte) error {
+	prev := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		linePrev, hash, err := parseChain(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if linePrev != prev {
+			return fmt.Errorf("line %d: does not follow the previous line", n)
+		}
+		if !hmac.Equal([]byte(hashLine(key, line[:bytes.LastIndex(line, hashField)])), []byte(hash)) {
+			return fmt.Errorf("line %d: hash does not match", n)
+		}
+		prev = hash
+	}
+	return scanner.Err()
+}
+
+func hashLine(key []byte, b []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseChain returns the previous and own hash of a line
+func parseChain(line []byte) (string, string, error) {
+	i := bytes.LastIndex(line, prevField)
+	j := bytes.LastIndex(line, hashField)
+	if i < 0 || j < i || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return "", "", fmt.Errorf("missing hash chain")
+	}
+	prev := line[i+len(prevField) : j-1]
+	hash := line[j+len(hashField) : len(line)-2]
+	return string(prev), string(hash), nil
+}
+
+// lastHash returns the hash of the last complete line and where that line ends.
+// Bytes after it are a line that was not fully written.
+func lastHash(f *os.File) (string, int64, error) {
+	hash := ""
+	complete := int64(0)
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return hash, complete, nil
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("unable to read audit log: %w", err)
+		}
+		_, h, parseErr := parseChain(bytes.TrimRight(line, "\n"))
+		if parseErr != nil {
+			return "", 0, fmt.Errorf("unable to continue audit log: %w", parseErr)
+		}
+		hash = h
+		complete += int64(len(line))
+	}
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	key := []byte("secret")
+	w, err := NewAuditWriter(path, key)
+	require.NoError(t, err)
+	logger := NewAuditLogger(w)
+	logger.Info().Str("resource", "keys").Msg("evicted keys from cache")
+	logger.Info().Str("module", "vault").Msg("changed log level")
+	require.NoError(t, w.Close())
+
+	// the chain continues after reopening
+	w, err = NewAuditWriter(path, key)
+	require.NoError(t, err)
+	logger = NewAuditLogger(w)
+	logger.Info().Msg("requeued dead letter")
+	require.NoError(t, w.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 3, bytes.Count(b, []byte("\n")))
+	require.NoError(t, VerifyAuditLog(bytes.NewReader(b), key))
+
+	tampered := bytes.Replace(b, []byte("vault"), []byte("cache"), 1)
+	require.ErrorContains(t, VerifyAuditLog(bytes.NewReader(tampered), key), "line 2: hash does not match")
+
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	removed := append(append([]byte{}, lines[0]...), lines[2]...)
+	require.ErrorContains(t, VerifyAuditLog(bytes.NewReader(removed), key), "line 2: does not follow the previous line")
+
+	// without the key, the chain can't be verified or forged
+	require.ErrorContains(t, VerifyAuditLog(bytes.NewReader(b), []byte("guess")), "line 1: hash does not match")
+}
+
+func TestAuditLogRecoversFromTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	key := []byte("secret")
+
+	w, err := NewAuditWriter(path, key)
+	require.NoError(t, err)
+	logger := NewAuditLogger(w)
+	logger.Info().Msg("evicted keys from cache")
+	require.NoError(t, w.Close())
+
+	// the process died while writing the next line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"level":"info","mess`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	w, err = NewAuditWriter(path, key)
+	require.NoError(t, err)
+	require.Equal(t, int64(21), w.TornBytes())
+	logger = NewAuditLogger(w)
+	logger.Info().Msg("requeued dead letter")
+	require.NoError(t, w.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, VerifyAuditLog(bytes.NewReader(b), key))
+	lines := bytes.Split(bytes.TrimSpace(b), []byte("\n"))
+	require.Len(t, lines, 3)
+	require.Contains(t, string(lines[1]), "truncated torn audit log line")
+}
@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileWriter appends log lines to a file and rotates it when it grows too
+// large or too old. Rotated files are renamed to <path>.<timestamp> and
+// optionally compressed with gzip in the background.
+type FileWriter struct {
+	mu     sync.Mutex
+	config FileWriterConfig
+
+	f        *os.File
+	size     int64
+	openedAt time.Time
+
+	// waits for compression to finish on close
+	wg sync.WaitGroup
+}
+
+type FileWriterConfig struct {
+	Path string
+	// Rotate when the file would grow beyond this many bytes, 0 disables it
+	MaxSize int64
+	// Rotate when the file is older than this, 0 disables it
+	MaxAge time.Duration
+	// How many rotated files to keep, 0 keeps all
+	MaxBackups int
+	// Gzip rotated files
+	Compress bool
+}
+
+const rotatedTimeFormat = "20060102T150405.000"
+
+func NewFileWriter(config FileWriterConfig) (*FileWriter, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	err := os.MkdirAll(filepath.Dir(config.Path), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create log directory: %w", err)
+	}
+	fw := &FileWriter{config: config}
+	err = fw.open()
+	if err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+func (fw *FileWriter) open() error {
+	f, err := os.OpenFile(fw.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open log file: %w", err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to stat log file: %w", err)
+	}
+	fw.f = f
+	fw.size = stat.Size()
+	// The modification time is the best guess we have for existing files
+	fw.openedAt = time.Now()
+	if fw.size > 0 {
+		fw.openedAt = stat.ModTime()
+	}
+	return nil
+}
+
+func (fw *FileWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.size > 0 && fw.shouldRotate(int64(len(p))) {
+		err := fw.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := fw.f.Write(p)
+	fw.size += int64(n)
+	return n, err
+}
+
+func (fw *FileWriter) shouldRotate(next int64) bool {
+	if fw.config.MaxSize > 0 && fw.size+next > fw.config.MaxSize {
+		return true
+	}
+	if fw.config.MaxAge > 0 && time.Since(fw.openedAt) > fw.config.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (fw *FileWriter) rotate() error {
+	err := fw.f.Close()
+	if err != nil {
+		return fmt.Errorf("unable to close log file: %w", err)
+	}
+	rotated := fmt.Sprintf("%s.%s", fw.config.Path, time.Now().UTC().Format(rotatedTimeFormat))
+	err = os.Rename(fw.config.Path, rotated)
+	if err != nil {
+		return fmt.Errorf("unable to rotate log file: %w", err)
+	}
+	err = fw.open()
+	if err != nil {
+		return err
+	}
+
+	fw.wg.Add(1)
+	go func() {
+		defer fw.wg.Done()
+		if fw.config.Compress {
+			if err := compress(rotated); err != nil {
+				// We can't log this through the logger without causing a loop
+				log.Printf("unable to compress %s: %s", rotated, err)
+			}
+		}
+		if err := fw.removeOldBackups(); err != nil {
+			log.Printf("unable to remove old log files: %s", err)
+		}
+	}()
+	return nil
+}
+
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = dst.Close()
+	} else {
+		dst.Close()
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// removeOldBackups keeps the newest MaxBackups rotated files
+func (fw *FileWriter) removeOldBackups() error {
+	if fw.config.MaxBackups <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(fw.config.Path + ".*")
+	if err != nil {
+		return err
+	}
+	backups := map[string][]string{}
+	for _, m := range matches {
+		// a file and its compressed version are the same backup
+		name := strings.TrimSuffix(m, ".gz")
+		backups[name] = append(backups[name], m)
+	}
+	names := make([]string, 0, len(backups))
+	for name := range backups {
+		names = append(names, name)
+	}
+	// The timestamps sort lexicographically
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names[min(len(names), fw.config.MaxBackups):] {
+		for _, f := range backups[name] {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes the file and waits for pending compressions.
+func (fw *FileWriter) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.wg.Wait()
+	return fw.f.Close()
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWriterRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent.log")
+
+	fw, err := NewFileWriter(FileWriterConfig{Path: path, MaxSize: 10, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+
+	for _, line := range []string{"line one\n", "line two\n", "line three\n", "line four\n"} {
+		_, err = fw.Write([]byte(line))
+		require.NoError(t, err)
+		// rotated files are named by millisecond
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.NoError(t, fw.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "line four\n", string(current))
+
+	backups, err := filepath.Glob(path + ".*.gz")
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+
+	f, err := os.Open(backups[len(backups)-1])
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	require.Equal(t, "line three\n", string(b))
+}
+
+func TestFileWriterRotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+
+	fw, err := NewFileWriter(FileWriterConfig{Path: path, MaxAge: time.Millisecond})
+	require.NoError(t, err)
+
+	_, err = fw.Write([]byte("old\n"))
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = fw.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, fw.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "new\n", string(current))
+
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+}
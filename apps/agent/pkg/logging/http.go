@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/batch"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+)
+
+// HttpWriter ships log lines to an http ingest endpoint in batches of
+// newline delimited json, e.g. axiom's https://api.axiom.co/v1/datasets/<dataset>/ingest
+type HttpWriter struct {
+	batcher *batch.BatchProcessor[[]byte]
+}
+
+type HttpWriterConfig struct {
+	Url string
+	// Sent with every request, e.g. for authentication
+	Headers map[string]string
+	// Defaults to 1000 lines
+	BatchSize int
+	// Defaults to 5s
+	FlushInterval time.Duration
+}
+
+func NewHttpWriter(config HttpWriterConfig) (*HttpWriter, error) {
+	if config.Url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1000
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	return &HttpWriter{
+		batcher: batch.New(batch.Config[[]byte]{
+			Name: "logging_http",
+			// Logging must never block the caller
+			Drop:          true,
+			BatchSize:     config.BatchSize,
+			BufferSize:    config.BatchSize * 10,
+			FlushInterval: config.FlushInterval,
+			Flush: func(ctx context.Context, lines [][]byte) {
+				body := bytes.Join(lines, nil)
+				err := util.Retry(func() error {
+					return send(ctx, client, config.Url, config.Headers, body)
+				}, 3, func(n int) time.Duration {
+					return 500 * time.Millisecond << n
+				})
+				if err != nil {
+					// We can't log this through the logger without causing a loop
+					log.Printf("unable to ship %d log lines: %s", len(lines), err)
+				}
+			},
+		}),
+	}, nil
+}
+
+func send(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("ingest responded with %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (hw *HttpWriter) Close() {
+	hw.batcher.Close()
+}
+
+func (hw *HttpWriter) Write(p []byte) (int, error) {
+	// zerolog reuses the buffer after Write returns
+	line := make([]byte, len(p))
+	copy(line, p)
+	hw.batcher.Buffer(line)
+	return len(p), nil
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpWriter(t *testing.T) {
+	lines := make(chan string, 10)
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}))
+	defer srv.Close()
+
+	w, err := NewHttpWriter(HttpWriterConfig{
+		Url:           srv.URL,
+		Headers:       map[string]string{"Authorization": "Bearer token"},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	buf := []byte(`{"message":"one"}` + "\n")
+	_, err = w.Write(buf)
+	require.NoError(t, err)
+	// the caller may reuse its buffer
+	copy(buf, `{"message":"two"}`+"\n")
+	_, err = w.Write(buf)
+	require.NoError(t, err)
+
+	require.Equal(t, `{"message":"one"}`, <-lines)
+	require.Equal(t, `{"message":"two"}`, <-lines)
+}
@@ -17,6 +17,8 @@ const timeFormat = "2006-01-02T15:04:05.000MST"
 type Config struct {
 	Debug  bool
 	Writer []io.Writer
+	// Words starting with any of these prefixes are redacted, e.g. "sk_"
+	KeyPrefixes []string
 }
 
 func init() {
@@ -41,7 +43,7 @@ func New(config *Config) Logger {
 
 	multi := zerolog.MultiLevelWriter(writers...)
 
-	logger := zerolog.New(multi).With().Timestamp().Caller().Logger()
+	logger := zerolog.New(newRedactor(multi, config.KeyPrefixes)).With().Timestamp().Caller().Logger()
 	if config.Debug {
 		logger = logger.Level(zerolog.DebugLevel)
 	} else {
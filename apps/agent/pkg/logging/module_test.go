@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+)
+
+func TestModule(t *testing.T) {
+	buf := &bytes.Buffer{}
+	// modules are global, this must not collide with other runs
+	module := uid.New("module")
+	logger := Module(zerolog.New(buf).Level(zerolog.InfoLevel), module)
+	require.Equal(t, zerolog.InfoLevel, ModuleLevels()[module])
+
+	logger.Debug().Msg("hidden")
+	require.Empty(t, buf.String())
+
+	require.NoError(t, SetModuleLevel(module, zerolog.DebugLevel))
+	logger.Debug().Msg("visible")
+	require.Equal(t, fmt.Sprintf(`{"level":"debug","module":"%s","message":"visible"}`+"\n", module), buf.String())
+
+	buf.Reset()
+	require.NoError(t, SetModuleLevel(module, zerolog.ErrorLevel))
+	logger.Warn().Msg("hidden")
+	require.Empty(t, buf.String())
+
+	require.Error(t, SetModuleLevel("does_not_exist", zerolog.DebugLevel))
+}
+
+func TestModuleChecksLevelBeforeBuildingEvents(t *testing.T) {
+	module := uid.New("module")
+	logger := Module(zerolog.New(&bytes.Buffer{}).Level(zerolog.InfoLevel), module)
+
+	require.Nil(t, logger.Debug())
+	require.NotNil(t, logger.Info())
+}
+
+func TestSampledModule(t *testing.T) {
+	buf := &bytes.Buffer{}
+	module := uid.New("module")
+	logger := Sampled(Module(zerolog.New(buf).Level(zerolog.InfoLevel), module), 1, time.Hour)
+
+	logger.Debug().Msg("hidden")
+	require.Empty(t, buf.String())
+
+	require.NoError(t, SetModuleLevel(module, zerolog.DebugLevel))
+	logger.Debug().Msg("visible")
+	logger.Debug().Msg("sampled")
+	require.Equal(t, 1, strings.Count(buf.String(), "visible"))
+	require.NotContains(t, buf.String(), "sampled")
+}
@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const redacted = "<REDACTED>"
+
+// redactor rewrites log lines before they reach any writer, so key material
+// never leaves the process even if it was logged by accident.
+//
+// Values of fields named "key" or "authorization" are replaced entirely, bearer
+// tokens and words starting with one of the key prefixes are replaced wherever
+// they appear in a string. Identifiers that merely are keys of something, such
+// as cache or ratelimit keys, must be logged under another name, e.g. cacheKey.
+type redactor struct {
+	next zerolog.LevelWriter
+	// cheap checks to skip decoding lines that can't contain secrets
+	needles  [][]byte
+	patterns []*regexp.Regexp
+}
+
+func newRedactor(next zerolog.LevelWriter, keyPrefixes []string) *redactor {
+	r := &redactor{
+		next:     next,
+		needles:  [][]byte{[]byte(`"key"`), []byte(`"Key"`), []byte("uthorization"), []byte("earer")},
+		patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)(bearer\s+)\S+`)},
+	}
+	for _, prefix := range keyPrefixes {
+		if prefix == "" {
+			continue
+		}
+		r.needles = append(r.needles, []byte(prefix))
+		r.patterns = append(r.patterns, regexp.MustCompile(`\b`+regexp.QuoteMeta(prefix)+`[A-Za-z0-9_\-]+`))
+	}
+	return r
+}
+
+func (r *redactor) Write(p []byte) (int, error) {
+	return r.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (r *redactor) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	redactedLine, ok := r.redact(p)
+	if !ok {
+		return r.next.WriteLevel(level, p)
+	}
+	_, err := r.next.WriteLevel(level, redactedLine)
+	if err != nil {
+		return 0, err
+	}
+	// Callers check that everything they passed in was written
+	return len(p), nil
+}
+
+// redact returns the rewritten line and true, if anything was redacted
+func (r *redactor) redact(p []byte) ([]byte, bool) {
+	suspicious := false
+	for _, needle := range r.needles {
+		if bytes.Contains(p, needle) {
+			suspicious = true
+			break
+		}
+	}
+	if !suspicious {
+		return nil, false
+	}
+
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	var e map[string]any
+	if err := d.Decode(&e); err != nil {
+		return nil, false
+	}
+	changed := false
+	r.redactMap(e, &changed)
+	if !changed {
+		return nil, false
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, false
+	}
+	return append(b, '\n'), true
+}
+
+func (r *redactor) redactMap(m map[string]any, changed *bool) {
+	for k, v := range m {
+		switch strings.ToLower(k) {
+		case "key", "authorization":
+			if v != redacted {
+				m[k] = redacted
+				*changed = true
+			}
+		default:
+			m[k] = r.redactValue(v, changed)
+		}
+	}
+}
+
+func (r *redactor) redactValue(v any, changed *bool) any {
+	switch v := v.(type) {
+	case string:
+		s := v
+		for _, pattern := range r.patterns {
+			s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+				sub := pattern.FindStringSubmatch(match)
+				if len(sub) > 1 {
+					return sub[1] + redacted
+				}
+				return redacted
+			})
+		}
+		if s != v {
+			*changed = true
+		}
+		return s
+	case map[string]any:
+		r.redactMap(v, changed)
+		return v
+	case []any:
+		for i := range v {
+			v[i] = r.redactValue(v[i], changed)
+		}
+		return v
+	default:
+		return v
+	}
+}
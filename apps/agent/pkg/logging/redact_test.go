@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := zerolog.New(newRedactor(zerolog.MultiLevelWriter(buf), []string{"sk_"}))
+
+	logger.Info().
+		Str("key", "sk_1234").
+		Str("message2", "verifying sk_abc_DEF-9 for user").
+		Dict("headers", zerolog.Dict().Str("Authorization", "Bearer secret").Str("Content-Type", "application/json")).
+		Str("raw", "Authorization: Bearer secret").
+		Int("count", 12345678901).
+		Msg("hello")
+
+	e := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	require.Equal(t, redacted, e["key"])
+	require.Equal(t, "verifying <REDACTED> for user", e["message2"])
+	require.Equal(t, map[string]any{"Authorization": redacted, "Content-Type": "application/json"}, e["headers"])
+	require.Equal(t, "Authorization: Bearer <REDACTED>", e["raw"])
+	require.Equal(t, float64(12345678901), e["count"])
+	require.Equal(t, "hello", e["message"])
+}
+
+func TestRedactLeavesCleanLinesAlone(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := zerolog.New(newRedactor(zerolog.MultiLevelWriter(buf), []string{"sk_"}))
+
+	logger.Info().Str("keyId", "key_123").Str("cacheKey", "ws_123").Msg("hello")
+	require.Equal(t, `{"level":"info","keyId":"key_123","cacheKey":"ws_123","message":"hello"}`+"\n", buf.String())
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Sampled returns a logger for hot paths, that writes at most burst lines per
+// period and drops the rest. Errors are always written.
+//
+// Each call creates its own budget, so loggers for different hot paths don't
+// starve each other. The budget is enforced in a hook rather than a sampler,
+// so it does not replace the level check of module loggers.
+func Sampled(logger Logger, burst uint32, period time.Duration) Logger {
+	s := &zerolog.BurstSampler{Burst: burst, Period: period}
+	return logger.Hook(sampledHook{sampler: zerolog.LevelSampler{
+		TraceSampler: s,
+		DebugSampler: s,
+		InfoSampler:  s,
+		WarnSampler:  s,
+	}})
+}
+
+type sampledHook struct {
+	sampler zerolog.Sampler
+}
+
+func (h sampledHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if !h.sampler.Sample(level) {
+		e.Discard()
+	}
+}
@@ -0,0 +1,26 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := Sampled(zerolog.New(buf), 2, time.Hour)
+
+	for range 10 {
+		logger.Info().Msg("cache miss")
+	}
+	require.Equal(t, 2, strings.Count(buf.String(), "cache miss"))
+
+	for range 10 {
+		logger.Error().Msg("failed")
+	}
+	require.Equal(t, 10, strings.Count(buf.String(), "failed"))
+}
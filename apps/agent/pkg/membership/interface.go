@@ -9,5 +9,14 @@ type Membership interface {
 
 	SubscribeLeaveEvents() <-chan Member
 
+	// Broadcast sends a small event to all members of the cluster, including this node.
+	// Serf limits the size of the payload, so this is not suitable for bulk data.
+	Broadcast(eventType string, payload []byte) error
+
+	SubscribeGossipEvents() <-chan GossipEvent
+
+	// SetLoad shares how busy this node is with the cluster, see Member.Load.
+	SetLoad(load float64) error
+
 	NodeId() string
 }
@@ -1,6 +1,8 @@
 package membership
 
 import (
+	"strconv"
+
 	"github.com/Southclaws/fault"
 	"github.com/Southclaws/fault/fmsg"
 )
@@ -21,6 +23,12 @@ type Member struct {
 	RpcAddr  string `json:"addr"`
 	SerfAddr string `json:"serfAddr"`
 	State    string `json:"state"`
+
+	// Optional, where the node is running
+	Region string `json:"region,omitempty"`
+	// How busy the node reported to be, from 0 for idle to 1 for at capacity.
+	// Gossiped periodically, so it may be a few seconds old.
+	Load float64 `json:"load"`
 }
 
 func (m *Member) Marshal() (map[string]string, error) {
@@ -45,6 +53,11 @@ func (m *Member) Marshal() (map[string]string, error) {
 	}
 	out["state"] = m.State
 
+	if m.Region != "" {
+		out["region"] = m.Region
+	}
+	out["load"] = strconv.FormatFloat(m.Load, 'f', 2, 64)
+
 	return out, nil
 }
 
@@ -68,5 +81,15 @@ func (t *Member) Unmarshal(m map[string]string) error {
 		return fault.New("State is missing")
 	}
 
+	// Nodes running an older version don't share these
+	t.Region = m["region"]
+	if load, ok := m["load"]; ok {
+		var err error
+		t.Load, err = strconv.ParseFloat(load, 64)
+		if err != nil {
+			return fault.Wrap(err, fmsg.With("Load is invalid"))
+		}
+	}
+
 	return nil
 }
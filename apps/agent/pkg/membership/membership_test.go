@@ -157,6 +157,58 @@ func TestLeave_emits_leave_event(t *testing.T) {
 	}
 }
 
+// Test whether the region and load of a node are shared with all other nodes,
+// including load changes after the node joined.
+func TestMembers_share_region_and_load(t *testing.T) {
+
+	freePort := port.New()
+
+	m1, err := membership.New(membership.Config{
+		NodeId:   "node_1",
+		SerfAddr: fmt.Sprintf("localhost:%d", freePort.Get()),
+		RpcAddr:  fmt.Sprintf("http://localhost:%d", freePort.Get()),
+		Region:   "eu-west-1",
+		Logger:   logging.New(nil),
+	})
+	require.NoError(t, err)
+	require.NoError(t, m1.SetLoad(0.25))
+
+	m2, err := membership.New(membership.Config{
+		NodeId:   "node_2",
+		SerfAddr: fmt.Sprintf("localhost:%d", freePort.Get()),
+		RpcAddr:  fmt.Sprintf("http://localhost:%d", freePort.Get()),
+		Logger:   logging.New(nil),
+	})
+	require.NoError(t, err)
+
+	_, err = m1.Join()
+	require.NoError(t, err)
+	_, err = m2.Join(m1.SerfAddr())
+	require.NoError(t, err)
+
+	findNode1 := func() membership.Member {
+		members, err := m2.Members()
+		require.NoError(t, err)
+		for _, m := range members {
+			if m.NodeId == "node_1" {
+				return m
+			}
+		}
+		return membership.Member{}
+	}
+
+	require.Eventually(t, func() bool {
+		m := findNode1()
+		return m.Region == "eu-west-1" && m.Load == 0.25
+	}, 10*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, m1.SetLoad(0.8))
+	require.Eventually(t, func() bool {
+		return findNode1().Load == 0.8
+	}, 10*time.Second, 100*time.Millisecond)
+
+}
+
 func runMany(t *testing.T, n int) []membership.Membership {
 
 	freePort := port.New()
@@ -0,0 +1,66 @@
+package membership
+
+import (
+	"net"
+)
+
+// serf joins this port when an address has none
+const defaultSerfPort = "7946"
+
+// Peers returns the join addresses that do not point at this node itself.
+//
+// When the cluster is discovered via dns, the records usually include the node
+// that is looking them up, so a single node would otherwise wait forever for
+// somebody else to join.
+func Peers(joinAddrs []string, serfAddr string) []string {
+	selfHost, selfPort, err := net.SplitHostPort(serfAddr)
+	if err != nil {
+		return joinAddrs
+	}
+
+	local := map[string]bool{}
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, a := range ifaceAddrs {
+			if ipNet, ok := a.(*net.IPNet); ok {
+				local[ipNet.IP.String()] = true
+			}
+		}
+	}
+	for _, ip := range lookup(selfHost) {
+		local[ip.String()] = true
+	}
+
+	peers := []string{}
+	for _, addr := range joinAddrs {
+		host, port, splitErr := net.SplitHostPort(addr)
+		if splitErr != nil {
+			host, port = addr, defaultSerfPort
+		}
+		if port != selfPort || !isLocal(local, lookup(host)) {
+			peers = append(peers, addr)
+		}
+	}
+	return peers
+}
+
+func isLocal(local map[string]bool, ips []net.IP) bool {
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsUnspecified() || local[ip.String()] {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup resolves the host, unresolvable hosts have no ips
+func lookup(host string) []net.IP {
+	if host == "" {
+		return nil
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil
+	}
+	return ips
+}
@@ -0,0 +1,19 @@
+package membership_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/membership"
+)
+
+func TestPeersExcludesSelf(t *testing.T) {
+	join := []string{"127.0.0.1:7373", "localhost:7373", "[::1]:7373", "127.0.0.1:7374", "203.0.113.1:7373"}
+	require.Equal(t, []string{"127.0.0.1:7374", "203.0.113.1:7373"}, membership.Peers(join, "localhost:7373"))
+}
+
+func TestPeersUsesDefaultPortForDnsRecords(t *testing.T) {
+	join := []string{"127.0.0.1", "203.0.113.1"}
+	require.Equal(t, []string{"203.0.113.1"}, membership.Peers(join, "0.0.0.0:7946"))
+	require.Equal(t, join, membership.Peers(join, "0.0.0.0:7373"))
+}
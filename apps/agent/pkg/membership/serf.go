@@ -3,6 +3,7 @@ package membership
 import (
 	"context"
 	"fmt"
+	"math"
 	"net"
 	"sync"
 	"time"
@@ -20,11 +21,13 @@ type Config struct {
 	SerfAddr string
 	Logger   logging.Logger
 	RpcAddr  string
+	// Optional, shared with other members
+	Region string
 }
 
-type gossipEvent struct {
-	event   string
-	payload []byte
+type GossipEvent struct {
+	Event   string
+	Payload []byte
 }
 
 type membership struct {
@@ -34,7 +37,7 @@ type membership struct {
 	self         Member
 	joinEvents   events.Topic[Member]
 	leaveEvents  events.Topic[Member]
-	gossipEvents events.Topic[gossipEvent]
+	gossipEvents events.Topic[GossipEvent]
 	serf         *serf.Serf
 	events       chan serf.Event
 	logger       logging.Logger
@@ -49,11 +52,12 @@ func New(config Config) (Membership, error) {
 			SerfAddr: config.SerfAddr,
 			RpcAddr:  config.RpcAddr,
 			State:    "alive",
+			Region:   config.Region,
 		},
 		logger:       config.Logger.With().Str("node", config.NodeId).Str("SerfAddr", config.SerfAddr).Logger(),
 		joinEvents:   events.NewTopic[Member](),
 		leaveEvents:  events.NewTopic[Member](),
-		gossipEvents: events.NewTopic[gossipEvent](),
+		gossipEvents: events.NewTopic[GossipEvent](),
 	}
 
 	return m, nil
@@ -66,15 +70,15 @@ func (m *membership) SerfAddr() string {
 	return m.serfAddr
 }
 func (m *membership) SubscribeJoinEvents() <-chan Member {
-	return m.joinEvents.Subscribe("serfJoinEvents")
+	return m.joinEvents.SubscribeChannel("serfJoinEvents")
 }
 
 func (m *membership) SubscribeLeaveEvents() <-chan Member {
-	return m.leaveEvents.Subscribe("serfLeaveEvents")
+	return m.leaveEvents.SubscribeChannel("serfLeaveEvents")
 }
 
-func (m *membership) SubscribeGossipEvents() <-chan gossipEvent {
-	return m.gossipEvents.Subscribe("serfGossipEvents")
+func (m *membership) SubscribeGossipEvents() <-chan GossipEvent {
+	return m.gossipEvents.SubscribeChannel("serfGossipEvents")
 }
 
 func (m *membership) Shutdown() error {
@@ -139,6 +143,29 @@ func (m *membership) Join(joinAddrs ...string) (int, error) {
 	return m.serf.Memberlist().NumMembers(), nil
 }
 
+func (m *membership) SetLoad(load float64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	// Every change is gossiped to all members, so we don't bother them with
+	// changes they can't see.
+	load = math.Round(load*100) / 100
+	if load == m.self.Load {
+		return nil
+	}
+	m.self.Load = load
+
+	// Shared when joining
+	if m.serf == nil {
+		return nil
+	}
+	tags, err := m.self.Marshal()
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("Failed to convert tags to map"))
+	}
+	return m.serf.SetTags(tags)
+}
+
 func (m *membership) Broadcast(eventType string, payload []byte) error {
 	return m.serf.UserEvent(eventType, payload, true)
 }
@@ -147,7 +174,14 @@ func (m *membership) eventHandler() {
 	for e := range m.events {
 		ctx := context.Background()
 
-		m.logger.Info().Str("type", e.EventType().String()).Msg("Event")
+		switch e.EventType() {
+		case serf.EventMemberUpdate:
+			// Members update their load every few seconds
+			m.logger.Debug().Str("type", e.EventType().String()).Msg("Event")
+		default:
+			m.logger.Info().Str("type", e.EventType().String()).Msg("Event")
+		}
+
 		switch e.EventType() {
 		case serf.EventMemberJoin:
 			for _, serfMember := range e.(serf.MemberEvent).Members {
@@ -157,7 +191,7 @@ func (m *membership) eventHandler() {
 					m.logger.Error().Err(err).Msg("Failed to unmarshal tags")
 					continue
 				}
-				m.joinEvents.Emit(ctx, member)
+				m.joinEvents.Publish(ctx, member)
 			}
 		case serf.EventMemberLeave, serf.EventMemberFailed:
 			for _, serfMember := range e.(serf.MemberEvent).Members {
@@ -166,12 +200,12 @@ func (m *membership) eventHandler() {
 					m.logger.Error().Err(err).Msg("Failed to unmarshal tags")
 					continue
 				}
-				m.leaveEvents.Emit(ctx, member)
+				m.leaveEvents.Publish(ctx, member)
 			}
 		case serf.EventUser:
-			m.gossipEvents.Emit(ctx, gossipEvent{
-				event:   e.(serf.UserEvent).Name,
-				payload: e.(serf.UserEvent).Payload,
+			m.gossipEvents.Publish(ctx, GossipEvent{
+				Event:   e.(serf.UserEvent).Name,
+				Payload: e.(serf.UserEvent).Payload,
 			})
 		}
 
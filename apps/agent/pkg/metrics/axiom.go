@@ -102,3 +102,11 @@ func (a *axiom) Record(m Metric) {
 
 	a.batcher.Buffer(a.merge(m, time.Now()))
 }
+
+// Observe sends every sample as its own event, axiom computes the percentiles
+// at query time.
+func (a *axiom) Observe(m Metric, value float64) {
+	data := a.merge(m, time.Now())
+	data["value"] = value
+	a.batcher.Buffer(data)
+}
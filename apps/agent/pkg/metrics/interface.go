@@ -2,6 +2,9 @@ package metrics
 
 type Metrics interface {
 	Record(metric Metric)
+	// Observe records a single sample of a distribution, such as a latency.
+	// The metric identifies the histogram, its fields become attributes.
+	Observe(metric Metric, value float64)
 	Close()
 }
 
@@ -9,3 +9,48 @@ type RingState struct {
 func (m RingState) Name() string {
 	return "metric.ring.state"
 }
+
+type CacheSize struct {
+	Resource string `json:"resource"`
+	// Number of entries currently stored
+	Entries int `json:"entries"`
+	// The configured maximum total weight of all entries
+	Capacity int `json:"capacity"`
+	// Total number of entries evicted since the cache was created
+	Evicted int64 `json:"evicted"`
+	// Total weight of entries evicted since the cache was created
+	EvictedWeight int64 `json:"evictedWeight"`
+}
+
+func (m CacheSize) Name() string {
+	return "metric.cache.size"
+}
+
+// CacheUsage aggregates reads of a cache over a flush interval
+type CacheUsage struct {
+	Resource string `json:"resource"`
+	Tier     string `json:"tier"`
+	Hits     int64  `json:"hits"`
+	Misses   int64  `json:"misses"`
+	// Reads that found a cached null, meaning the entry does not exist in the origin
+	Nulls int64 `json:"nulls"`
+	// Sum of all read latencies in microseconds, divide by the number of reads
+	// for the average. Loading missed entries from the origin is not included.
+	ReadLatencyMicros int64 `json:"readLatencyMicros"`
+}
+
+func (m CacheUsage) Name() string {
+	return "metric.cache.usage"
+}
+
+// ServiceLatency is observed in milliseconds for every call of a service method
+type ServiceLatency struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	// "success" or "error"
+	Outcome string `json:"outcome"`
+}
+
+func (m ServiceLatency) Name() string {
+	return "metric.service.latency"
+}
@@ -11,3 +11,5 @@ func NewNoop() Metrics {
 func (n *noop) Close() {}
 
 func (n *noop) Record(metric Metric) {}
+
+func (n *noop) Observe(metric Metric, value float64) {}
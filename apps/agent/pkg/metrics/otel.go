@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+type OtelConfig struct {
+	// The OTLP/HTTP endpoint of the collector, e.g. http://localhost:4318
+	Endpoint string
+	// Sent with every export, e.g. for authentication
+	Headers map[string]string
+	// How often metrics are pushed, defaults to 1 minute
+	Interval time.Duration
+	// Should be the same resource the tracer uses
+	Resource *resource.Resource
+	Logger   logging.Logger
+}
+
+// otel records every numeric field of a metric as a gauge named after the
+// metric and the field, e.g. metric.cache.size.entries. All other fields are
+// attached as attributes.
+type otel struct {
+	logger   logging.Logger
+	provider *sdkmetric.MeterProvider
+	meter    metric.Meter
+
+	mu         sync.Mutex
+	gauges     map[string]metric.Float64Gauge
+	histograms map[string]metric.Float64Histogram
+}
+
+func NewOtel(config OtelConfig) (*otel, error) {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+
+	exporter, err := otlpmetrichttp.New(context.Background(),
+		otlpmetrichttp.WithEndpointURL(config.Endpoint),
+		otlpmetrichttp.WithHeaders(config.Headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create otlp exporter: %w", err)
+	}
+
+	opts := []sdkmetric.Option{
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(config.Interval))),
+	}
+	if config.Resource != nil {
+		opts = append(opts, sdkmetric.WithResource(config.Resource))
+	}
+	provider := sdkmetric.NewMeterProvider(opts...)
+
+	return &otel{
+		logger:     config.Logger,
+		provider:   provider,
+		meter:      provider.Meter("agent"),
+		gauges:     map[string]metric.Float64Gauge{},
+		histograms: map[string]metric.Float64Histogram{},
+	}, nil
+}
+
+func (o *otel) Record(m Metric) {
+	values, attributes := split(util.StructToMap(m))
+	for field, value := range values {
+		g, err := o.gauge(fmt.Sprintf("%s.%s", m.Name(), field))
+		if err != nil {
+			o.logger.Err(err).Str("metric", m.Name()).Msg("failed to create gauge")
+			continue
+		}
+		g.Record(context.Background(), value, metric.WithAttributes(attributes...))
+	}
+}
+
+func (o *otel) gauge(name string) (metric.Float64Gauge, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	g, ok := o.gauges[name]
+	if ok {
+		return g, nil
+	}
+	g, err := o.meter.Float64Gauge(name)
+	if err != nil {
+		return nil, err
+	}
+	o.gauges[name] = g
+	return g, nil
+}
+
+func (o *otel) Observe(m Metric, value float64) {
+	_, attributes := split(util.StructToMap(m))
+	h, err := o.histogram(m.Name())
+	if err != nil {
+		o.logger.Err(err).Str("metric", m.Name()).Msg("failed to create histogram")
+		return
+	}
+	h.Record(context.Background(), value, metric.WithAttributes(attributes...))
+}
+
+func (o *otel) histogram(name string) (metric.Float64Histogram, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	h, ok := o.histograms[name]
+	if ok {
+		return h, nil
+	}
+	h, err := o.meter.Float64Histogram(name)
+	if err != nil {
+		return nil, err
+	}
+	o.histograms[name] = h
+	return h, nil
+}
+
+func (o *otel) Close() {
+	err := o.provider.Shutdown(context.Background())
+	if err != nil {
+		o.logger.Err(err).Msg("failed to shut down meter provider")
+	}
+}
+
+// split separates the numeric fields of a metric from the ones describing it
+func split(data map[string]any) (map[string]float64, []attribute.KeyValue) {
+	values := map[string]float64{}
+	attributes := []attribute.KeyValue{}
+	for key, value := range data {
+		switch v := value.(type) {
+		case int:
+			values[key] = float64(v)
+		case int32:
+			values[key] = float64(v)
+		case int64:
+			values[key] = float64(v)
+		case uint32:
+			values[key] = float64(v)
+		case uint64:
+			values[key] = float64(v)
+		case float32:
+			values[key] = float64(v)
+		case float64:
+			values[key] = v
+		case string:
+			attributes = append(attributes, attribute.String(key, v))
+		case bool:
+			attributes = append(attributes, attribute.Bool(key, v))
+		default:
+			attributes = append(attributes, attribute.String(key, fmt.Sprintf("%v", v)))
+		}
+	}
+	return values, attributes
+}
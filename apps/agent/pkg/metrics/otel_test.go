@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestSplit(t *testing.T) {
+	values, attributes := split(map[string]any{
+		"resource": "key",
+		"entries":  10,
+		"evicted":  int64(2),
+		"ratio":    0.5,
+		"healthy":  true,
+	})
+	require.Equal(t, map[string]float64{"entries": 10, "evicted": 2, "ratio": 0.5}, values)
+	require.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("resource", "key"),
+		attribute.Bool("healthy", true),
+	}, attributes)
+}
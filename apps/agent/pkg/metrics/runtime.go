@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/repeat"
+)
+
+// Runtime describes the go runtime of this process
+type Runtime struct {
+	Goroutines int `json:"goroutines"`
+	// Bytes of allocated heap objects
+	HeapAlloc uint64 `json:"heapAlloc"`
+	// Bytes of heap memory obtained from the OS
+	HeapSys     uint64 `json:"heapSys"`
+	HeapObjects uint64 `json:"heapObjects"`
+	// Garbage collections since the previous report
+	GCCycles uint32 `json:"gcCycles"`
+	// Total and longest stop-the-world pause of these collections
+	GCPauseMicros    int64 `json:"gcPauseMicros"`
+	GCMaxPauseMicros int64 `json:"gcMaxPauseMicros"`
+	// Open file descriptors, -1 if they can not be counted on this platform
+	OpenFiles int `json:"openFiles"`
+}
+
+func (m Runtime) Name() string {
+	return "metric.runtime"
+}
+
+// ReportRuntime records the state of the go runtime every interval until the
+// returned function is called.
+func ReportRuntime(m Metrics, interval time.Duration) func() {
+	var lastNumGC uint32
+	return repeat.Every(interval, func() {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+
+		r := Runtime{
+			Goroutines:  runtime.NumGoroutine(),
+			HeapAlloc:   stats.HeapAlloc,
+			HeapSys:     stats.HeapSys,
+			HeapObjects: stats.HeapObjects,
+			GCCycles:    stats.NumGC - lastNumGC,
+			OpenFiles:   openFiles(),
+		}
+		// PauseNs is a ring buffer of the most recent pauses
+		for gc := lastNumGC + 1; gc <= stats.NumGC && stats.NumGC-gc < uint32(len(stats.PauseNs)); gc++ {
+			pause := int64(stats.PauseNs[(gc+255)%256] / 1000)
+			r.GCPauseMicros += pause
+			r.GCMaxPauseMicros = max(r.GCMaxPauseMicros, pause)
+		}
+		lastNumGC = stats.NumGC
+
+		m.Record(r)
+	})
+}
+
+func openFiles() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	Metrics
+	mu       sync.Mutex
+	recorded []Metric
+}
+
+func (r *recorder) Record(m Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recorded = append(r.recorded, m)
+}
+
+func TestReportRuntime(t *testing.T) {
+	r := &recorder{}
+	stop := ReportRuntime(r, 10*time.Millisecond)
+	defer stop()
+
+	runtime.GC()
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.recorded) > 0
+	}, time.Second, time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rt, ok := r.recorded[0].(Runtime)
+	require.True(t, ok)
+	require.Greater(t, rt.Goroutines, 0)
+	require.Greater(t, rt.HeapAlloc, uint64(0))
+	require.GreaterOrEqual(t, rt.GCCycles, uint32(1))
+}
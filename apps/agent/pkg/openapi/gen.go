@@ -3,8 +3,20 @@
 // Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.3.0 DO NOT EDIT.
 package openapi
 
+// Defines values for V1LoggingSetLevelRequestBodyLevel.
+const (
+	Debug V1LoggingSetLevelRequestBodyLevel = "debug"
+	Error V1LoggingSetLevelRequestBodyLevel = "error"
+	Info  V1LoggingSetLevelRequestBodyLevel = "info"
+	Trace V1LoggingSetLevelRequestBodyLevel = "trace"
+	Warn  V1LoggingSetLevelRequestBodyLevel = "warn"
+)
+
 // BaseError defines model for BaseError.
 type BaseError struct {
+	// Code A machine-readable code for the kind of problem, one of BAD_REQUEST, UNAUTHORIZED, NOT_FOUND, TOO_MANY_REQUESTS, INTERNAL_SERVER_ERROR or DEADLINE_EXCEEDED. Unlike the title, it is safe to match on.
+	Code string `json:"code"`
+
 	// Detail A human-readable explanation specific to this occurrence of the problem.
 	Detail string `json:"detail"`
 
@@ -20,16 +32,49 @@ type BaseError struct {
 	// Title A short, human-readable summary of the problem type. This value should not change between occurrences of the error.
 	Title string `json:"title"`
 
+	// TraceId The trace of this request, if it was traced. Operators can look it up directly.
+	TraceId *string `json:"traceId,omitempty"`
+
 	// Type A URI reference to human-readable documentation for the error.
 	Type string `json:"type"`
 }
 
+// DeadLetter defines model for DeadLetter.
+type DeadLetter struct {
+	// Attempts How often the subscriber tried to handle the event.
+	Attempts int64 `json:"attempts"`
+
+	// Error The last error returned by the subscriber.
+	Error string `json:"error"`
+
+	// Event The event as it was published.
+	Event interface{} `json:"event"`
+
+	// Id The id of the dead letter.
+	Id string `json:"id"`
+
+	// Subscriber The subscriber that failed to handle the event.
+	Subscriber string `json:"subscriber"`
+
+	// Time When the event was moved to the dead letter queue, unix milliseconds.
+	Time int64 `json:"time"`
+}
+
 // Encrypted defines model for Encrypted.
 type Encrypted struct {
 	Encrypted string `json:"encrypted"`
 	KeyId     string `json:"keyId"`
 }
 
+// HotKey defines model for HotKey.
+type HotKey struct {
+	// Id The first 8 bytes of the sha256 hash of the key, hex encoded.
+	Id string `json:"id"`
+
+	// Reads How often the entry has been read since it was written.
+	Reads int64 `json:"reads"`
+}
+
 // Item defines model for Item.
 type Item struct {
 	// Cost The cost of the request.
@@ -43,6 +88,9 @@ type Item struct {
 
 	// Limit The maximum number of requests allowed.
 	Limit int64 `json:"limit"`
+
+	// Shadow Evaluate the ratelimit without enforcing it. Requests that exceed the limit still pass, but are reported with wouldBlock=true.
+	Shadow *bool `json:"shadow,omitempty"`
 }
 
 // Lease defines model for Lease.
@@ -54,6 +102,45 @@ type Lease struct {
 	Timeout int64 `json:"timeout"`
 }
 
+// RatelimitBucket defines model for RatelimitBucket.
+type RatelimitBucket struct {
+	// Current Requests counted in the current window.
+	Current int64 `json:"current"`
+
+	// Duration The window duration in milliseconds.
+	Duration int64 `json:"duration"`
+
+	// Id The first 8 bytes of the sha256 hash of the bucket key, hex encoded.
+	Id string `json:"id"`
+
+	// Limit The limit of the bucket.
+	Limit int64 `json:"limit"`
+}
+
+// ReadinessCheck defines model for ReadinessCheck.
+type ReadinessCheck struct {
+	// Error Why the check failed.
+	Error *string `json:"error,omitempty"`
+
+	// Latency How long the check took in milliseconds.
+	Latency int64 `json:"latency"`
+
+	// Status Either ok or failing.
+	Status string `json:"status"`
+}
+
+// ReadyzResponseBody defines model for ReadyzResponseBody.
+type ReadyzResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Checks The result of every readiness check, by dependency.
+	Checks map[string]ReadinessCheck `json:"checks"`
+
+	// Status Either ok or unavailable, if any check is failing.
+	Status string `json:"status"`
+}
+
 // SingleRatelimitResponse defines model for SingleRatelimitResponse.
 type SingleRatelimitResponse struct {
 	// Current The current number of requests made in the current window.
@@ -70,6 +157,9 @@ type SingleRatelimitResponse struct {
 
 	// Success Whether the request passed the ratelimit. If false, the request must be blocked.
 	Success bool `json:"success"`
+
+	// WouldBlock Whether the request would have been blocked if the ratelimit was not in shadow mode.
+	WouldBlock *bool `json:"wouldBlock,omitempty"`
 }
 
 // V0EventsRequestBody NDJSON payload of events
@@ -87,6 +177,225 @@ type V0EventsResponseBody struct {
 	SuccessfulRows int `json:"successful_rows"`
 }
 
+// V1AnalyticsGetApiStatsRequestBody defines model for V1AnalyticsGetApiStatsRequestBody.
+type V1AnalyticsGetApiStatsRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// End Unix timestamp in milliseconds, exclusive.
+	End int64 `json:"end"`
+
+	// KeySpaceId The key space of the api.
+	KeySpaceId string `json:"keySpaceId"`
+
+	// Start Unix timestamp in milliseconds, inclusive.
+	Start int64 `json:"start"`
+
+	// WorkspaceId The workspace of the keys.
+	WorkspaceId string `json:"workspaceId"`
+}
+
+// V1AnalyticsGetApiStatsResponseBody defines model for V1AnalyticsGetApiStatsResponseBody.
+type V1AnalyticsGetApiStatsResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// ActiveKeys Distinct keys with at least one verification.
+	ActiveKeys int64 `json:"activeKeys"`
+
+	// Outcomes Verifications by outcome, e.g. VALID or RATE_LIMITED.
+	Outcomes map[string]int64 `json:"outcomes"`
+
+	// Verifications The number of verifications of all keys of the api.
+	Verifications int64 `json:"verifications"`
+}
+
+// V1AnalyticsGetLatencyStatsRequestBody defines model for V1AnalyticsGetLatencyStatsRequestBody.
+type V1AnalyticsGetLatencyStatsRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// End Unix timestamp in milliseconds, exclusive.
+	End int64 `json:"end"`
+
+	// KeySpaceId The key space of the api.
+	KeySpaceId string `json:"keySpaceId"`
+
+	// Start Unix timestamp in milliseconds, inclusive.
+	Start int64 `json:"start"`
+
+	// WorkspaceId The workspace of the keys.
+	WorkspaceId string `json:"workspaceId"`
+}
+
+// V1AnalyticsGetLatencyStatsResponseBody defines model for V1AnalyticsGetLatencyStatsResponseBody.
+type V1AnalyticsGetLatencyStatsResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// P50 The median verification latency in milliseconds.
+	P50 float64 `json:"p50"`
+
+	// P95 The 95th percentile of the verification latency in milliseconds.
+	P95 float64 `json:"p95"`
+
+	// P99 The 99th percentile of the verification latency in milliseconds.
+	P99 float64 `json:"p99"`
+}
+
+// V1AnalyticsGetMonthlyActiveKeysRequestBody defines model for V1AnalyticsGetMonthlyActiveKeysRequestBody.
+type V1AnalyticsGetMonthlyActiveKeysRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Month The calendar month in UTC.
+	Month string `json:"month"`
+
+	// WorkspaceId Only return this workspace.
+	WorkspaceId *string `json:"workspaceId,omitempty"`
+}
+
+// V1AnalyticsGetMonthlyActiveKeysResponseBody defines model for V1AnalyticsGetMonthlyActiveKeysResponseBody.
+type V1AnalyticsGetMonthlyActiveKeysResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Month The calendar month in UTC.
+	Month string `json:"month"`
+
+	// Workspaces Active keys per workspace, ordered by workspace id.
+	Workspaces []WorkspaceActiveKeys `json:"workspaces"`
+}
+
+// V1AnalyticsGetOwnerStatsRequestBody defines model for V1AnalyticsGetOwnerStatsRequestBody.
+type V1AnalyticsGetOwnerStatsRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// End Unix timestamp in milliseconds, exclusive.
+	End int64 `json:"end"`
+
+	// OwnerId The owner of the keys.
+	OwnerId string `json:"ownerId"`
+
+	// Start Unix timestamp in milliseconds, inclusive.
+	Start int64 `json:"start"`
+
+	// WorkspaceId The workspace of the keys.
+	WorkspaceId string `json:"workspaceId"`
+}
+
+// V1AnalyticsGetOwnerStatsResponseBody defines model for V1AnalyticsGetOwnerStatsResponseBody.
+type V1AnalyticsGetOwnerStatsResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// ActiveKeys Distinct keys with at least one verification.
+	ActiveKeys int64 `json:"activeKeys"`
+
+	// Outcomes Verifications by outcome, e.g. VALID or RATE_LIMITED.
+	Outcomes map[string]int64 `json:"outcomes"`
+
+	// Verifications The number of verifications of all keys of the owner.
+	Verifications int64 `json:"verifications"`
+}
+
+// V1AnalyticsGetWorkspaceStatsRequestBody defines model for V1AnalyticsGetWorkspaceStatsRequestBody.
+type V1AnalyticsGetWorkspaceStatsRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// End Unix timestamp in milliseconds, exclusive.
+	End int64 `json:"end"`
+
+	// Start Unix timestamp in milliseconds, inclusive.
+	Start int64 `json:"start"`
+
+	// WorkspaceId The workspace of the keys.
+	WorkspaceId string `json:"workspaceId"`
+}
+
+// V1AnalyticsGetWorkspaceStatsResponseBody defines model for V1AnalyticsGetWorkspaceStatsResponseBody.
+type V1AnalyticsGetWorkspaceStatsResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// ActiveKeys Distinct keys with at least one verification.
+	ActiveKeys int64 `json:"activeKeys"`
+
+	// Outcomes Verifications by outcome, e.g. VALID or RATE_LIMITED.
+	Outcomes map[string]int64 `json:"outcomes"`
+
+	// Verifications The number of verifications of all keys of the workspace.
+	Verifications int64 `json:"verifications"`
+}
+
+// V1CacheEvictRequestBody defines model for V1CacheEvictRequestBody.
+type V1CacheEvictRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Deleted Set when the keys were deleted in the origin. They are replaced with tombstones instead of being removed, so loads that are still in flight can't write the deleted data back.
+	Deleted *bool `json:"deleted,omitempty"`
+
+	// Keys The keys to remove from the cache.
+	Keys []string `json:"keys"`
+
+	// Resource The cache to remove the keys from.
+	Resource string `json:"resource"`
+}
+
+// V1CacheFlushRequestBody defines model for V1CacheFlushRequestBody.
+type V1CacheFlushRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Resource The cache to remove all entries from.
+	Resource string `json:"resource"`
+}
+
+// V1CacheInspectRequestBody defines model for V1CacheInspectRequestBody.
+type V1CacheInspectRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Resource The cache to inspect.
+	Resource string `json:"resource"`
+
+	// TopN How many of the hottest keys to return.
+	TopN *int64 `json:"topN,omitempty"`
+}
+
+// V1CacheInspectResponseBody defines model for V1CacheInspectResponseBody.
+type V1CacheInspectResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Entries The number of entries in the cache.
+	Entries int64 `json:"entries"`
+
+	// HitRatio The ratio of hits to all lookups since the cache was created.
+	HitRatio float64 `json:"hitRatio"`
+
+	// Hottest The most read entries, in descending order.
+	Hottest []HotKey `json:"hottest"`
+
+	// Oldest Unix timestamp in milliseconds of when the oldest entry was written. Omitted if the cache is empty.
+	Oldest *int64 `json:"oldest,omitempty"`
+
+	// Resource The inspected cache.
+	Resource string `json:"resource"`
+}
+
+// V1CacheWarmupRequestBody defines model for V1CacheWarmupRequestBody.
+type V1CacheWarmupRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Keyrings The keyrings whose latest data encryption key is loaded into the cache.
+	Keyrings []string `json:"keyrings"`
+}
+
 // V1DecryptRequestBody defines model for V1DecryptRequestBody.
 type V1DecryptRequestBody struct {
 	// Schema A URL to the JSON Schema for this object.
@@ -147,6 +456,45 @@ type V1EncryptResponseBody struct {
 	KeyId string `json:"keyId"`
 }
 
+// V1EventsFlushResponseBody defines model for V1EventsFlushResponseBody.
+type V1EventsFlushResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Flushed The buffers that were flushed, in order.
+	Flushed []string `json:"flushed"`
+}
+
+// V1EventsListDeadLettersRequestBody defines model for V1EventsListDeadLettersRequestBody.
+type V1EventsListDeadLettersRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Queue The dead letter queue to list.
+	Queue string `json:"queue"`
+}
+
+// V1EventsListDeadLettersResponseBody defines model for V1EventsListDeadLettersResponseBody.
+type V1EventsListDeadLettersResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// DeadLetters All dead letters, oldest first.
+	DeadLetters []DeadLetter `json:"deadLetters"`
+}
+
+// V1EventsRequeueDeadLetterRequestBody defines model for V1EventsRequeueDeadLetterRequestBody.
+type V1EventsRequeueDeadLetterRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Id The id of the dead letter to hand to its subscriber again.
+	Id string `json:"id"`
+
+	// Queue The dead letter queue the event is in.
+	Queue string `json:"queue"`
+}
+
 // V1LivenessResponseBody defines model for V1LivenessResponseBody.
 type V1LivenessResponseBody struct {
 	// Schema A URL to the JSON Schema for this object.
@@ -156,6 +504,30 @@ type V1LivenessResponseBody struct {
 	Message string `json:"message"`
 }
 
+// V1LoggingSetLevelRequestBody defines model for V1LoggingSetLevelRequestBody.
+type V1LoggingSetLevelRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Level The lowest level to log.
+	Level V1LoggingSetLevelRequestBodyLevel `json:"level"`
+
+	// Module The subsystem to change.
+	Module string `json:"module"`
+}
+
+// V1LoggingSetLevelRequestBodyLevel The lowest level to log.
+type V1LoggingSetLevelRequestBodyLevel string
+
+// V1LoggingSetLevelResponseBody defines model for V1LoggingSetLevelResponseBody.
+type V1LoggingSetLevelResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Levels The current level of every module, after the change.
+	Levels map[string]string `json:"levels"`
+}
+
 // V1RatelimitCommitLeaseRequestBody defines model for V1RatelimitCommitLeaseRequestBody.
 type V1RatelimitCommitLeaseRequestBody struct {
 	// Schema A URL to the JSON Schema for this object.
@@ -168,6 +540,30 @@ type V1RatelimitCommitLeaseRequestBody struct {
 	Lease string `json:"lease"`
 }
 
+// V1RatelimitInspectRequestBody defines model for V1RatelimitInspectRequestBody.
+type V1RatelimitInspectRequestBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// TopN How many of the hottest buckets to return.
+	TopN *int64 `json:"topN,omitempty"`
+}
+
+// V1RatelimitInspectResponseBody defines model for V1RatelimitInspectResponseBody.
+type V1RatelimitInspectResponseBody struct {
+	// Schema A URL to the JSON Schema for this object.
+	Schema *string `json:"$schema,omitempty"`
+
+	// Buckets How many buckets this node holds.
+	Buckets int64 `json:"buckets"`
+
+	// Hottest The buckets with the most requests in their current window, in descending order.
+	Hottest []RatelimitBucket `json:"hottest"`
+
+	// Windows How many windows this node holds across all buckets.
+	Windows int64 `json:"windows"`
+}
+
 // V1RatelimitMultiRatelimitRequestBody defines model for V1RatelimitMultiRatelimitRequestBody.
 type V1RatelimitMultiRatelimitRequestBody struct {
 	// Schema A URL to the JSON Schema for this object.
@@ -184,6 +580,9 @@ type V1RatelimitMultiRatelimitResponseBody struct {
 
 	// Ratelimits The rate limits that were checked.
 	Ratelimits []SingleRatelimitResponse `json:"ratelimits"`
+
+	// Success Whether all ratelimits passed. If false, the request must be blocked and no tokens were consumed.
+	Success bool `json:"success"`
 }
 
 // V1RatelimitRatelimitRequestBody defines model for V1RatelimitRatelimitRequestBody.
@@ -203,6 +602,9 @@ type V1RatelimitRatelimitRequestBody struct {
 
 	// Limit The maximum number of requests allowed.
 	Limit int64 `json:"limit"`
+
+	// Shadow Evaluate the ratelimit without enforcing it. Requests that exceed the limit still pass, but are reported with wouldBlock=true.
+	Shadow *bool `json:"shadow,omitempty"`
 }
 
 // V1RatelimitRatelimitResponseBody defines model for V1RatelimitRatelimitResponseBody.
@@ -227,10 +629,16 @@ type V1RatelimitRatelimitResponseBody struct {
 
 	// Success Whether the request passed the ratelimit. If false, the request must be blocked.
 	Success bool `json:"success"`
+
+	// WouldBlock Whether the request would have been blocked if the ratelimit was not in shadow mode.
+	WouldBlock *bool `json:"wouldBlock,omitempty"`
 }
 
 // ValidationError defines model for ValidationError.
 type ValidationError struct {
+	// Code A machine-readable code for the kind of problem, one of BAD_REQUEST, UNAUTHORIZED, NOT_FOUND, TOO_MANY_REQUESTS, INTERNAL_SERVER_ERROR or DEADLINE_EXCEEDED. Unlike the title, it is safe to match on.
+	Code string `json:"code"`
+
 	// Detail A human-readable explanation specific to this occurrence of the problem.
 	Detail string `json:"detail"`
 
@@ -249,6 +657,9 @@ type ValidationError struct {
 	// Title A short, human-readable summary of the problem type. This value should not change between occurrences of the error.
 	Title string `json:"title"`
 
+	// TraceId The trace of this request, if it was traced. Operators can look it up directly.
+	TraceId *string `json:"traceId,omitempty"`
+
 	// Type A URI reference to human-readable documentation for the error.
 	Type string `json:"type"`
 }
@@ -265,12 +676,51 @@ type ValidationErrorDetail struct {
 	Message string `json:"message"`
 }
 
+// WorkspaceActiveKeys defines model for WorkspaceActiveKeys.
+type WorkspaceActiveKeys struct {
+	// ActiveKeys Distinct keys with at least one verification during the month.
+	ActiveKeys int64 `json:"activeKeys"`
+
+	// WorkspaceId The workspace id.
+	WorkspaceId string `json:"workspaceId"`
+}
+
 // RatelimitV1MultiRatelimitJSONRequestBody defines body for RatelimitV1MultiRatelimit for application/json ContentType.
 type RatelimitV1MultiRatelimitJSONRequestBody = V1RatelimitMultiRatelimitRequestBody
 
 // RatelimitV1RatelimitJSONRequestBody defines body for RatelimitV1Ratelimit for application/json ContentType.
 type RatelimitV1RatelimitJSONRequestBody = V1RatelimitRatelimitRequestBody
 
+// V1AnalyticsGetApiStatsJSONRequestBody defines body for V1AnalyticsGetApiStats for application/json ContentType.
+type V1AnalyticsGetApiStatsJSONRequestBody = V1AnalyticsGetApiStatsRequestBody
+
+// V1AnalyticsGetLatencyStatsJSONRequestBody defines body for V1AnalyticsGetLatencyStats for application/json ContentType.
+type V1AnalyticsGetLatencyStatsJSONRequestBody = V1AnalyticsGetLatencyStatsRequestBody
+
+// V1AnalyticsGetMonthlyActiveKeysJSONRequestBody defines body for V1AnalyticsGetMonthlyActiveKeys for application/json ContentType.
+type V1AnalyticsGetMonthlyActiveKeysJSONRequestBody = V1AnalyticsGetMonthlyActiveKeysRequestBody
+
+// V1AnalyticsGetOwnerStatsJSONRequestBody defines body for V1AnalyticsGetOwnerStats for application/json ContentType.
+type V1AnalyticsGetOwnerStatsJSONRequestBody = V1AnalyticsGetOwnerStatsRequestBody
+
+// V1AnalyticsGetWorkspaceStatsJSONRequestBody defines body for V1AnalyticsGetWorkspaceStats for application/json ContentType.
+type V1AnalyticsGetWorkspaceStatsJSONRequestBody = V1AnalyticsGetWorkspaceStatsRequestBody
+
+// V1CacheEvictJSONRequestBody defines body for V1CacheEvict for application/json ContentType.
+type V1CacheEvictJSONRequestBody = V1CacheEvictRequestBody
+
+// V1CacheInspectJSONRequestBody defines body for V1CacheInspect for application/json ContentType.
+type V1CacheInspectJSONRequestBody = V1CacheInspectRequestBody
+
+// V1EventsListDeadLettersJSONRequestBody defines body for V1EventsListDeadLetters for application/json ContentType.
+type V1EventsListDeadLettersJSONRequestBody = V1EventsListDeadLettersRequestBody
+
+// V1EventsRequeueDeadLetterJSONRequestBody defines body for V1EventsRequeueDeadLetter for application/json ContentType.
+type V1EventsRequeueDeadLetterJSONRequestBody = V1EventsRequeueDeadLetterRequestBody
+
+// V1LoggingSetLevelJSONRequestBody defines body for V1LoggingSetLevel for application/json ContentType.
+type V1LoggingSetLevelJSONRequestBody = V1LoggingSetLevelRequestBody
+
 // V1RatelimitCommitLeaseJSONRequestBody defines body for V1RatelimitCommitLease for application/json ContentType.
 type V1RatelimitCommitLeaseJSONRequestBody = V1RatelimitCommitLeaseRequestBody
 
@@ -0,0 +1,64 @@
+package prometheus
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultLabelLimit is how many distinct values a guarded label keeps by default
+const DefaultLabelLimit = 1000
+
+// Values beyond the limit are spread across this many series
+const overflowBuckets = 16
+
+var labelLimit atomic.Int64
+
+func init() {
+	labelLimit.Store(DefaultLabelLimit)
+}
+
+// SetLabelLimit changes the limit of all guarded labels. Values that were
+// already seen are kept.
+func SetLabelLimit(limit int) {
+	labelLimit.Store(int64(limit))
+}
+
+// LabelGuard bounds the number of series created by a label with unbounded
+// values, such as cache keys or request paths.
+//
+// The first values are passed through unchanged. Once the limit is reached,
+// new values are hashed into a few overflow buckets, so totals remain correct
+// while a single hot workspace can not explode the series count.
+type LabelGuard struct {
+	mu   sync.RWMutex
+	seen map[string]struct{}
+}
+
+func NewLabelGuard() *LabelGuard {
+	return &LabelGuard{seen: map[string]struct{}{}}
+}
+
+// Value returns the label value to record for v
+func (g *LabelGuard) Value(v string) string {
+	g.mu.RLock()
+	_, ok := g.seen[v]
+	g.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok = g.seen[v]; ok {
+		return v
+	}
+	if int64(len(g.seen)) < labelLimit.Load() {
+		g.seen[v] = struct{}{}
+		return v
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(v))
+	return fmt.Sprintf("overflow_%02d", h.Sum32()%overflowBuckets)
+}
@@ -0,0 +1,25 @@
+package prometheus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelGuard(t *testing.T) {
+	SetLabelLimit(10)
+	defer SetLabelLimit(DefaultLabelLimit)
+
+	g := NewLabelGuard()
+	values := map[string]struct{}{}
+	for i := 0; i < 1000; i++ {
+		values[g.Value(fmt.Sprintf("key_%d", i))] = struct{}{}
+	}
+	require.LessOrEqual(t, len(values), 10+overflowBuckets)
+
+	// known values are kept, overflowing ones are stable
+	require.Equal(t, "key_1", g.Value("key_1"))
+	require.Equal(t, g.Value("key_999"), g.Value("key_999"))
+	require.Contains(t, g.Value("key_999"), "overflow_")
+}
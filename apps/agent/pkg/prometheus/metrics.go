@@ -6,6 +6,11 @@ import (
 )
 
 var (
+	// Guard labels with unbounded values
+	HTTPPathLabel  = NewLabelGuard()
+	CacheKeyLabel  = NewLabelGuard()
+	WorkspaceLabel = NewLabelGuard()
+
 	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "agent",
 		Subsystem: "http",
@@ -62,6 +67,36 @@ var (
 		Subsystem: "event_router",
 		Name:      "flushed_rows",
 	}, []string{"datasource"})
+	EventHandlerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "agent",
+		Subsystem: "events",
+		Name:      "handler_errors",
+		Help:      "Events a subscriber failed to handle",
+	}, []string{"subscriber"})
+	WorkspaceVerifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "agent",
+		Subsystem: "workspace",
+		Name:      "verifications_total",
+		Help:      "Key verifications per workspace, only recorded if enabled",
+	}, []string{"workspaceId"})
+	WorkspaceDenials = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "agent",
+		Subsystem: "workspace",
+		Name:      "denials_total",
+		Help:      "Key verifications per workspace that were not valid, only recorded if enabled",
+	}, []string{"workspaceId"})
+	ClickhouseFailedRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "agent",
+		Subsystem: "clickhouse",
+		Name:      "failed_rows",
+		Help:      "Rows that could not be inserted after all retries",
+	}, []string{"table"})
+	ClickhouseSlowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "agent",
+		Subsystem: "clickhouse",
+		Name:      "slow_queries",
+		Help:      "Queries that took longer than the configured threshold",
+	}, []string{"method"})
 	RatelimitPushPullEvents = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "agent",
 		Subsystem: "ratelimit",
@@ -1,16 +1,29 @@
 package repeat
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // Every runs the given function in a go routine every d duration until the returned function is called.
 func Every(d time.Duration, fn func()) func() {
 	t := time.NewTicker(d)
+	done := make(chan struct{})
 	go func() {
-		for range t.C {
-			fn()
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				fn()
+			}
 		}
 	}()
+	var once sync.Once
 	return func() {
-		t.Stop()
+		once.Do(func() {
+			close(done)
+		})
 	}
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	axiom "github.com/axiomhq/axiom-go/axiom/otel"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
 )
 
 type Config struct {
@@ -12,19 +14,44 @@ type Config struct {
 	Application string
 	Version     string
 	AxiomToken  string
+	// Optional, is built from the application and version if omitted
+	Resource *resource.Resource
+	// The fraction of traces to record, all if omitted
+	SampleRatio *float64
 }
 
 // Coser is a function that closes the global tracer.
 type Closer func() error
 
 func Init(ctx context.Context, config Config) (Closer, error) {
-	tp, err := axiom.TracerProvider(ctx, config.Dataset, config.Application, config.Version, axiom.SetNoEnv(), axiom.SetToken(config.AxiomToken))
+	exporter, err := axiom.TraceExporter(ctx, config.Dataset, axiom.SetNoEnv(), axiom.SetToken(config.AxiomToken))
 	if err != nil {
 		return nil, fmt.Errorf("unable to init tracing: %w", err)
 	}
+	rs := config.Resource
+	if rs == nil {
+		rs, err = NewResource(ResourceConfig{Application: config.Application, Version: config.Version})
+		if err != nil {
+			return nil, fmt.Errorf("unable to create resource: %w", err)
+		}
+	}
+	return setProvider(exporter, rs, config.SampleRatio), nil
+}
+
+// setProvider makes a provider exporting sampled spans the global tracer
+func setProvider(exporter trace.SpanExporter, rs *resource.Resource, sampleRatio *float64) Closer {
+	ratio := 1.0
+	if sampleRatio != nil {
+		ratio = *sampleRatio
+	}
+	tp := trace.NewTracerProvider(
+		trace.WithBatcher(exporter, trace.WithMaxQueueSize(1024*10)),
+		trace.WithResource(rs),
+		trace.WithSampler(NewSampler(ratio)),
+	)
 	globalTracer = tp
 
 	return func() error {
 		return tp.Shutdown(context.Background())
-	}, nil
+	}
 }
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+type JaegerConfig struct {
+	// The host and port of jaeger's OTLP/gRPC receiver, e.g. localhost:4317
+	Endpoint string
+	// Disable TLS, for collectors running next to the agent
+	Insecure bool
+	// Describes this process, should be shared with metrics
+	Resource *resource.Resource
+	// The fraction of traces to record, all if omitted
+	SampleRatio *float64
+}
+
+// InitJaeger exports traces to a jaeger collector.
+//
+// The dedicated jaeger exporter has been removed from opentelemetry in favour
+// of OTLP, which jaeger receives natively.
+func InitJaeger(ctx context.Context, config JaegerConfig) (Closer, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("jaeger endpoint is required")
+	}
+	return InitOtlp(ctx, OtlpConfig{
+		Endpoint:    config.Endpoint,
+		Insecure:    config.Insecure,
+		Resource:    config.Resource,
+		SampleRatio: config.SampleRatio,
+	})
+}
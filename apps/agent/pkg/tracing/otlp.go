@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+type OtlpConfig struct {
+	// The host and port of the collector's OTLP/gRPC receiver, e.g. localhost:4317
+	Endpoint string
+	// Disable TLS, for collectors running next to the agent
+	Insecure bool
+	// Sent with every export, e.g. for authentication
+	Headers map[string]string
+	// Describes this process, should be shared with metrics
+	Resource *resource.Resource
+	// The fraction of traces to record, all if omitted
+	SampleRatio *float64
+}
+
+// InitOtlp exports traces to an opentelemetry collector over gRPC.
+func InitOtlp(ctx context.Context, config OtlpConfig) (Closer, error) {
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(config.Endpoint),
+		otlptracegrpc.WithHeaders(config.Headers),
+	}
+	if config.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create otlp exporter: %w", err)
+	}
+
+	rs := config.Resource
+	if rs == nil {
+		rs = resource.Default()
+	}
+	return setProvider(exporter, rs, config.SampleRatio), nil
+}
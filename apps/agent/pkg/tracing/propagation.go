@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Propagator reads and writes the W3C traceparent and tracestate headers
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Extract continues the trace of an incoming request, if it carries one
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return Propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject adds the trace of ctx to the headers of an outgoing request
+func Inject(ctx context.Context, header http.Header) {
+	Propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,27 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPropagation(t *testing.T) {
+	header := http.Header{}
+	header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	header.Set("tracestate", "unkey=1")
+
+	ctx := Extract(context.Background(), header)
+	sc := trace.SpanContextFromContext(ctx)
+	require.True(t, sc.IsRemote())
+	require.Equal(t, "0af7651916cd43dd8448eb211c80319c", sc.TraceID().String())
+	require.True(t, sc.IsSampled())
+
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+	require.Equal(t, header.Get("traceparent"), outgoing.Get("traceparent"))
+	require.Equal(t, "unkey=1", outgoing.Get("tracestate"))
+}
@@ -0,0 +1,25 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+type ResourceConfig struct {
+	Application string
+	Version     string
+	NodeId      string
+	Region      string
+}
+
+// NewResource describes this process to opentelemetry, it is shared by traces
+// and metrics so both can be correlated.
+func NewResource(config ResourceConfig) (*resource.Resource, error) {
+	return resource.Merge(resource.Default(), resource.NewWithAttributes(
+		"",
+		semconv.ServiceName(config.Application),
+		semconv.ServiceVersion(config.Version),
+		semconv.ServiceInstanceID(config.NodeId),
+		semconv.CloudRegion(config.Region),
+	))
+}
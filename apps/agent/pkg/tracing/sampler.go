@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DebugHeader forces the request to be traced regardless of the sample ratio
+const DebugHeader = "Unkey-Debug-Trace"
+
+type forceSampleKey struct{}
+
+// ForceSample records all spans started from this context, even if the
+// sampler would drop them.
+func ForceSample(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceSampleKey{}, true)
+}
+
+// NewSampler samples the given fraction of new traces and follows the decision
+// of the parent span otherwise. Contexts marked with ForceSample are always sampled.
+func NewSampler(ratio float64) trace.Sampler {
+	return &sampler{
+		forced: trace.AlwaysSample(),
+		next:   trace.ParentBased(trace.TraceIDRatioBased(ratio)),
+	}
+}
+
+type sampler struct {
+	forced trace.Sampler
+	next   trace.Sampler
+}
+
+func (s *sampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	if forced, _ := p.ParentContext.Value(forceSampleKey{}).(bool); forced {
+		return s.forced.ShouldSample(p)
+	}
+	return s.next.ShouldSample(p)
+}
+
+func (s *sampler) Description() string {
+	return "ForceSampleOr{" + s.next.Description() + "}"
+}
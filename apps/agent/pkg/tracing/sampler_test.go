@@ -0,0 +1,30 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(0)
+	traceId := oteltrace.TraceID{1}
+
+	res := s.ShouldSample(trace.SamplingParameters{ParentContext: context.Background(), TraceID: traceId, Name: "test"})
+	require.Equal(t, trace.Drop, res.Decision)
+
+	res = s.ShouldSample(trace.SamplingParameters{ParentContext: ForceSample(context.Background()), TraceID: traceId, Name: "test"})
+	require.Equal(t, trace.RecordAndSample, res.Decision)
+
+	// sampled parents are followed
+	parent := oteltrace.ContextWithRemoteSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     oteltrace.SpanID{1},
+		TraceFlags: oteltrace.FlagsSampled,
+	}))
+	res = s.ShouldSample(trace.SamplingParameters{ParentContext: parent, TraceID: traceId, Name: "test"})
+	require.Equal(t, trace.RecordAndSample, res.Decision)
+}
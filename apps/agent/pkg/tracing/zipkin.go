@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+type ZipkinConfig struct {
+	// The span collection endpoint, e.g. http://localhost:9411/api/v2/spans
+	Url string
+	// Describes this process, should be shared with metrics
+	Resource *resource.Resource
+	// The fraction of traces to record, all if omitted
+	SampleRatio *float64
+}
+
+// InitZipkin exports traces to zipkin using its v2 json api.
+func InitZipkin(ctx context.Context, config ZipkinConfig) (Closer, error) {
+	if config.Url == "" {
+		return nil, fmt.Errorf("zipkin url is required")
+	}
+	exporter, err := zipkin.New(config.Url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create zipkin exporter: %w", err)
+	}
+
+	rs := config.Resource
+	if rs == nil {
+		rs = resource.Default()
+	}
+	return setProvider(exporter, rs, config.SampleRatio), nil
+}
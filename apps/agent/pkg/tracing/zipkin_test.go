@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestZipkinExportsSpans(t *testing.T) {
+	type zipkinSpan struct {
+		Name          string `json:"name"`
+		Kind          string `json:"kind"`
+		LocalEndpoint struct {
+			ServiceName string `json:"serviceName"`
+		} `json:"localEndpoint"`
+		Tags map[string]string `json:"tags"`
+	}
+
+	mu := sync.Mutex{}
+	received := []zipkinSpan{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spans := []zipkinSpan{}
+		if err := json.NewDecoder(r.Body).Decode(&spans); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, spans...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	previous := globalTracer
+	t.Cleanup(func() { globalTracer = previous })
+
+	closeTracer, err := InitZipkin(context.Background(), ZipkinConfig{
+		Url:      srv.URL,
+		Resource: resource.NewSchemaless(semconv.ServiceName("agent")),
+	})
+	require.NoError(t, err)
+
+	_, span := Start(context.Background(), "verify", oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+	span.SetAttributes(attribute.String("key.id", "key_123"))
+	span.End()
+	require.NoError(t, closeTracer())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 1)
+	require.Equal(t, "verify", received[0].Name)
+	require.Equal(t, "SERVER", received[0].Kind)
+	require.Equal(t, "agent", received[0].LocalEndpoint.ServiceName)
+	require.Equal(t, "key_123", received[0].Tags["key.id"])
+}
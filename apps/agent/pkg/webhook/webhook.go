@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body
+const SignatureHeader = "Unkey-Signature"
+
+// Post sends the payload as json and signs it with the secret, so the receiver
+// can verify it came from us. Any non 2xx response is an error.
+func Post(ctx context.Context, client *http.Client, url, secret string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to marshal webhook payload"))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to create request"))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, b))
+	tracing.Inject(ctx, req.Header)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to post webhook"))
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fault.New(fmt.Sprintf("webhook responded with %d", res.StatusCode))
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
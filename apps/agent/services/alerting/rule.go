@@ -0,0 +1,117 @@
+package alerting
+
+import (
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse/schema"
+	"github.com/unkeyed/unkey/apps/agent/services/eventrouter"
+)
+
+type Rule struct {
+	Id          string
+	WorkspaceId string
+	// Optionally only evaluate verifications of this api
+	ApiId string
+	// Optionally only evaluate verifications of this key
+	KeyId string
+
+	// Verifications are counted in consecutive windows of this length
+	Window time.Duration
+
+	// Fire as soon as there are more verifications than this within a window.
+	// 0 disables this check.
+	MaxVerifications int
+
+	// Fire at the end of a window when more than this fraction of verifications
+	// were not valid. 0 disables this check.
+	MaxErrorRate float64
+	// Don't evaluate the error rate of windows with fewer verifications,
+	// so a single failure doesn't fire an alert
+	MinVerifications int
+}
+
+const (
+	ReasonVerificationsExceeded = "verifications_exceeded"
+	ReasonErrorRateExceeded     = "error_rate_exceeded"
+)
+
+type Alert struct {
+	RuleId      string `json:"ruleId"`
+	WorkspaceId string `json:"workspaceId"`
+	ApiId       string `json:"apiId,omitempty"`
+	KeyId       string `json:"keyId,omitempty"`
+	Reason      string `json:"reason"`
+	// The window the rule fired in, unix milliseconds
+	WindowStart   int64   `json:"windowStart"`
+	WindowEnd     int64   `json:"windowEnd"`
+	Verifications int     `json:"verifications"`
+	Errors        int     `json:"errors"`
+	ErrorRate     float64 `json:"errorRate"`
+}
+
+// ruleState counts verifications of a rule within the current window
+type ruleState struct {
+	rule          Rule
+	windowStart   time.Time
+	verifications int
+	errors        int
+	// Each check fires at most once per window
+	firedVerifications bool
+}
+
+func (s *ruleState) matches(e eventrouter.KeyVerificationEvent) bool {
+	return e.WorkspaceId == s.rule.WorkspaceId &&
+		(s.rule.ApiId == "" || e.ApiId == s.rule.ApiId) &&
+		(s.rule.KeyId == "" || e.KeyId == s.rule.KeyId)
+}
+
+// record counts the verification and returns an alert if the verification
+// limit was crossed.
+func (s *ruleState) record(e eventrouter.KeyVerificationEvent) *Alert {
+	s.verifications++
+	if e.Outcome != schema.OutcomeValid {
+		s.errors++
+	}
+	if s.rule.MaxVerifications > 0 && !s.firedVerifications && s.verifications > s.rule.MaxVerifications {
+		s.firedVerifications = true
+		return s.alert(ReasonVerificationsExceeded)
+	}
+	return nil
+}
+
+// closeWindow returns an alert if the error rate of the ending window was too
+// high and starts a new window.
+func (s *ruleState) closeWindow(now time.Time) *Alert {
+	var alert *Alert
+	if s.rule.MaxErrorRate > 0 && s.verifications > 0 && s.verifications >= s.rule.MinVerifications &&
+		s.errorRate() > s.rule.MaxErrorRate {
+		alert = s.alert(ReasonErrorRateExceeded)
+	}
+	s.windowStart = now
+	s.verifications = 0
+	s.errors = 0
+	s.firedVerifications = false
+	return alert
+}
+
+func (s *ruleState) errorRate() float64 {
+	if s.verifications == 0 {
+		return 0
+	}
+	return float64(s.errors) / float64(s.verifications)
+}
+
+func (s *ruleState) alert(reason string) *Alert {
+	return &Alert{
+		RuleId:        s.rule.Id,
+		WorkspaceId:   s.rule.WorkspaceId,
+		ApiId:         s.rule.ApiId,
+		KeyId:         s.rule.KeyId,
+		Reason:        reason,
+		WindowStart:   s.windowStart.UnixMilli(),
+		WindowEnd:     s.windowStart.Add(s.rule.Window).UnixMilli(),
+		Verifications: s.verifications,
+		Errors:        s.errors,
+		ErrorRate:     s.errorRate(),
+	}
+}
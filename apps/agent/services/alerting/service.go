@@ -0,0 +1,224 @@
+package alerting
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/webhook"
+	"github.com/unkeyed/unkey/apps/agent/services/eventrouter"
+	"github.com/unkeyed/unkey/apps/agent/services/ratelimit"
+)
+
+type Config struct {
+	Logger        logging.Logger
+	Verifications events.EventSubscriber[eventrouter.KeyVerificationEvent]
+	Rules         []Rule
+
+	// Optionally post every alert to this url
+	WebhookUrl string
+	// Used to sign the payload, see webhook.Sign
+	Secret string
+
+	// Optionally also post these events to the webhook, see forwardThresholds
+	RatelimitExceeded events.EventSubscriber[ratelimit.ExceededEvent]
+	UsageExceeded     events.EventSubscriber[eventrouter.UsageExceededEvent]
+}
+
+// Service evaluates alerting rules against the key verifications arriving at
+// this node.
+//
+// Rules only see verifications routed through this node, so in a cluster each
+// node evaluates its share of the traffic.
+type Service struct {
+	sync.Mutex
+	logger        logging.Logger
+	verifications events.EventSubscriber[eventrouter.KeyVerificationEvent]
+	states        []*ruleState
+	alerts        events.Topic[Alert]
+	webhookUrl    string
+	secret        string
+	httpClient    *http.Client
+	// Alerts that could not be delivered to the webhook
+	deadLetters *events.DeadLetterQueue[Alert]
+
+	ratelimitExceeded    events.EventSubscriber[ratelimit.ExceededEvent]
+	usageExceeded        events.EventSubscriber[eventrouter.UsageExceededEvent]
+	registry             *events.Registry
+	thresholds           events.Topic[events.Envelope]
+	thresholdDeadLetters *events.DeadLetterQueue[events.Envelope]
+}
+
+func New(config Config) (*Service, error) {
+	if config.Verifications == nil {
+		return nil, fault.New("verifications are required")
+	}
+	now := time.Now()
+	states := make([]*ruleState, len(config.Rules))
+	for i, rule := range config.Rules {
+		if rule.Id == "" || rule.WorkspaceId == "" {
+			return nil, fault.New("rules require an id and workspace id")
+		}
+		if rule.Window <= 0 {
+			return nil, fault.New("rule " + rule.Id + " requires a window")
+		}
+		if rule.MaxVerifications <= 0 && rule.MaxErrorRate <= 0 {
+			return nil, fault.New("rule " + rule.Id + " must limit verifications or the error rate")
+		}
+		states[i] = &ruleState{rule: rule, windowStart: now}
+	}
+
+	return &Service{
+		logger:        config.Logger,
+		verifications: config.Verifications,
+		states:        states,
+		alerts:        events.NewTopic[Alert](100),
+		webhookUrl:    config.WebhookUrl,
+		secret:        config.Secret,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		deadLetters:   events.NewDeadLetterQueue[Alert](1000, config.Logger),
+
+		ratelimitExceeded:    config.RatelimitExceeded,
+		usageExceeded:        config.UsageExceeded,
+		registry:             newThresholdRegistry(),
+		thresholds:           events.NewTopic[events.Envelope](100),
+		thresholdDeadLetters: events.NewDeadLetterQueue[events.Envelope](1000, config.Logger),
+	}, nil
+}
+
+// Alerts lets other services react to fired alerts.
+func (s *Service) Alerts() events.EventSubscriber[Alert] {
+	return s.alerts
+}
+
+// DeadLetters are the alerts that could not be delivered to the webhook.
+func (s *Service) DeadLetters() events.DeadLetterInspector {
+	return s.deadLetters
+}
+
+// Start evaluates the rules until the returned function is called.
+func (s *Service) Start() func() {
+	stopDelivering := func() {}
+	stopForwarding := func() {}
+	if s.webhookUrl != "" {
+		stopDelivering = s.deliverAlerts()
+		stopForwarding = s.forwardThresholds()
+	}
+
+	stopProcessing := s.verifications.Subscribe("alerting", func(_ context.Context, e eventrouter.KeyVerificationEvent) error {
+		s.process(e)
+		return nil
+	})
+	ticker := time.NewTicker(time.Second)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				s.tick(now)
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+		stopProcessing()
+		stopForwarding()
+		stopDelivering()
+	}
+}
+
+func (s *Service) process(e eventrouter.KeyVerificationEvent) {
+	s.Lock()
+	fired := []Alert{}
+	for _, state := range s.states {
+		if !state.matches(e) {
+			continue
+		}
+		if alert := state.record(e); alert != nil {
+			fired = append(fired, *alert)
+		}
+	}
+	s.Unlock()
+
+	for _, alert := range fired {
+		s.notify(alert)
+	}
+}
+
+// tick closes all windows that have ended
+func (s *Service) tick(now time.Time) {
+	s.Lock()
+	fired := []Alert{}
+	for _, state := range s.states {
+		if now.Sub(state.windowStart) < state.rule.Window {
+			continue
+		}
+		if alert := state.closeWindow(now); alert != nil {
+			fired = append(fired, *alert)
+		}
+	}
+	s.Unlock()
+
+	for _, alert := range fired {
+		s.notify(alert)
+	}
+}
+
+// notify publishes the alert without blocking, evaluating rules is on the path
+// of ingesting verifications and must not wait for a slow webhook.
+func (s *Service) notify(alert Alert) {
+	s.logger.Warn().
+		Str("ruleId", alert.RuleId).
+		Str("workspaceId", alert.WorkspaceId).
+		Str("reason", alert.Reason).
+		Int("verifications", alert.Verifications).
+		Float64("errorRate", alert.ErrorRate).
+		Msg("alert fired")
+
+	if !s.alerts.TryPublish(context.Background(), alert) {
+		s.logger.Error().
+			Str("ruleId", alert.RuleId).
+			Str("workspaceId", alert.WorkspaceId).
+			Msg("alert subscribers are falling behind, dropping alert")
+	}
+}
+
+// deliverAlerts posts every alert to the webhook at least once. Failed
+// deliveries are not acked and retried after the ack timeout, until they are
+// moved to the dead letter queue.
+// The returned function stops delivering.
+func (s *Service) deliverAlerts() func() {
+	deliveries, stop := events.SubscribeAcked[Alert](s.alerts, events.AckConfig[Alert]{
+		Id:          "alerting.webhook",
+		AckTimeout:  time.Minute,
+		MaxAttempts: 5,
+		DeadLetters: s.deadLetters,
+		Logger:      s.logger,
+	})
+	go func() {
+		for d := range deliveries {
+			err := s.deliver(context.Background(), d.Event)
+			if err != nil {
+				s.logger.Warn().Err(err).Str("ruleId", d.Event.RuleId).Int("attempt", d.Attempt).Msg("failed to deliver alert")
+				continue
+			}
+			d.Ack()
+		}
+	}()
+	return stop
+}
+
+func (s *Service) deliver(ctx context.Context, alert Alert) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return webhook.Post(ctx, s.httpClient, s.webhookUrl, s.secret, alert)
+}
@@ -0,0 +1,175 @@
+package alerting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse/schema"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/services/eventrouter"
+)
+
+func newService(t *testing.T, rules ...Rule) (*Service, <-chan Alert) {
+	t.Helper()
+	s, err := New(Config{
+		Logger:        logging.NewNoopLogger(),
+		Verifications: events.NewTopic[eventrouter.KeyVerificationEvent](),
+		Rules:         rules,
+	})
+	require.NoError(t, err)
+	return s, s.Alerts().SubscribeChannel("test")
+}
+
+func verification(keyId, outcome string) eventrouter.KeyVerificationEvent {
+	return eventrouter.KeyVerificationEvent{WorkspaceId: "ws_1", ApiId: "api_1", KeyId: keyId, Outcome: outcome}
+}
+
+func TestMaxVerifications(t *testing.T) {
+	s, alerts := newService(t, Rule{
+		Id:               "rule_1",
+		WorkspaceId:      "ws_1",
+		KeyId:            "key_1",
+		Window:           time.Minute,
+		MaxVerifications: 2,
+	})
+
+	s.process(verification("key_1", schema.OutcomeValid))
+	s.process(verification("key_2", schema.OutcomeValid))
+	s.process(verification("key_1", schema.OutcomeValid))
+	require.Len(t, alerts, 0)
+
+	s.process(verification("key_1", schema.OutcomeValid))
+	require.Len(t, alerts, 1)
+	alert := <-alerts
+	require.Equal(t, ReasonVerificationsExceeded, alert.Reason)
+	require.Equal(t, 3, alert.Verifications)
+
+	// fires only once per window
+	s.process(verification("key_1", schema.OutcomeValid))
+	require.Len(t, alerts, 0)
+
+	s.tick(time.Now().Add(time.Minute))
+	for i := 0; i < 3; i++ {
+		s.process(verification("key_1", schema.OutcomeValid))
+	}
+	require.Len(t, alerts, 1)
+}
+
+func TestMaxErrorRate(t *testing.T) {
+	s, alerts := newService(t, Rule{
+		Id:               "rule_1",
+		WorkspaceId:      "ws_1",
+		ApiId:            "api_1",
+		Window:           time.Minute,
+		MaxErrorRate:     0.05,
+		MinVerifications: 10,
+	})
+
+	// too few verifications to be evaluated
+	s.process(verification("key_1", schema.OutcomeRateLimited))
+	s.tick(time.Now().Add(time.Minute))
+	require.Len(t, alerts, 0)
+
+	for i := 0; i < 9; i++ {
+		s.process(verification("key_1", schema.OutcomeValid))
+	}
+	s.process(verification("key_2", schema.OutcomeUsageExceeded))
+	// the window has not ended yet
+	s.tick(time.Now().Add(time.Minute))
+	require.Len(t, alerts, 0)
+
+	s.tick(time.Now().Add(2 * time.Minute))
+	require.Len(t, alerts, 1)
+	alert := <-alerts
+	require.Equal(t, ReasonErrorRateExceeded, alert.Reason)
+	require.Equal(t, 10, alert.Verifications)
+	require.Equal(t, 1, alert.Errors)
+	require.InDelta(t, 0.1, alert.ErrorRate, 0.0001)
+}
+
+func TestSlowSubscribersDontBlockProcessing(t *testing.T) {
+	// More alerts than fit into the buffer of a subscriber that never reads
+	rules := make([]Rule, 150)
+	for i := range rules {
+		rules[i] = Rule{
+			Id:               fmt.Sprintf("rule_%d", i),
+			WorkspaceId:      "ws_1",
+			Window:           time.Minute,
+			MaxVerifications: 1,
+		}
+	}
+	s, err := New(Config{
+		Logger:        logging.NewNoopLogger(),
+		Verifications: events.NewTopic[eventrouter.KeyVerificationEvent](),
+		Rules:         rules,
+	})
+	require.NoError(t, err)
+	alerts := s.Alerts().SubscribeChannel("stuck")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.process(verification("key_1", schema.OutcomeValid))
+		s.process(verification("key_1", schema.OutcomeValid))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("processing blocked on a full subscriber")
+	}
+	require.Len(t, alerts, cap(alerts))
+}
+
+func TestInvalidRules(t *testing.T) {
+	for _, rule := range []Rule{
+		{WorkspaceId: "ws_1", Window: time.Minute, MaxVerifications: 1},
+		{Id: "rule_1", WorkspaceId: "ws_1", MaxVerifications: 1},
+		{Id: "rule_1", WorkspaceId: "ws_1", Window: time.Minute},
+	} {
+		_, err := New(Config{
+			Logger:        logging.NewNoopLogger(),
+			Verifications: events.NewTopic[eventrouter.KeyVerificationEvent](),
+			Rules:         []Rule{rule},
+		})
+		require.Error(t, err)
+	}
+}
+
+func TestDeliversAlertsToWebhook(t *testing.T) {
+	received := make(chan Alert, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alert := Alert{}
+		if json.NewDecoder(r.Body).Decode(&alert) == nil {
+			received <- alert
+		}
+	}))
+	defer srv.Close()
+
+	s, err := New(Config{
+		Logger:        logging.NewNoopLogger(),
+		Verifications: events.NewTopic[eventrouter.KeyVerificationEvent](),
+		Rules:         []Rule{{Id: "rule_1", WorkspaceId: "ws_1", Window: time.Minute, MaxVerifications: 1}},
+		WebhookUrl:    srv.URL,
+	})
+	require.NoError(t, err)
+	stop := s.Start()
+	defer stop()
+
+	s.process(verification("key_1", schema.OutcomeValid))
+	s.process(verification("key_1", schema.OutcomeValid))
+
+	select {
+	case alert := <-received:
+		require.Equal(t, "rule_1", alert.RuleId)
+	case <-time.After(5 * time.Second):
+		t.Fatal("alert was not delivered")
+	}
+	require.Empty(t, s.deadLetters.List())
+}
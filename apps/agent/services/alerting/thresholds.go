@@ -0,0 +1,76 @@
+package alerting
+
+import (
+	"context"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/webhook"
+	"github.com/unkeyed/unkey/apps/agent/services/eventrouter"
+	"github.com/unkeyed/unkey/apps/agent/services/ratelimit"
+)
+
+const thresholdEventVersion = 1
+
+func newThresholdRegistry() *events.Registry {
+	r := events.NewRegistry()
+	events.Register[ratelimit.ExceededEvent](r, ratelimit.ExceededEventType, thresholdEventVersion, nil)
+	events.Register[eventrouter.UsageExceededEvent](r, eventrouter.UsageExceededEventType, thresholdEventVersion, nil)
+	return r
+}
+
+// ThresholdDeadLetters are the ratelimit.exceeded and usage.exceeded events
+// that could not be delivered to the webhook.
+func (s *Service) ThresholdDeadLetters() events.DeadLetterInspector {
+	return s.thresholdDeadLetters
+}
+
+// forwardThresholds posts every exceeded ratelimit and usage limit to the
+// webhook, wrapped in an envelope so receivers can tell them apart.
+// The returned function stops forwarding.
+func (s *Service) forwardThresholds() func() {
+	stops := []func(){
+		events.Handle[events.Envelope](s.thresholds, events.HandlerConfig[events.Envelope]{
+			Id: "alerting.thresholds",
+			Handle: func(ctx context.Context, e events.Envelope) error {
+				ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				defer cancel()
+				return webhook.Post(ctx, s.httpClient, s.webhookUrl, s.secret, e)
+			},
+			MaxAttempts: 5,
+			Backoff:     time.Second,
+			DeadLetters: s.thresholdDeadLetters,
+			Logger:      s.logger,
+		}),
+	}
+
+	if s.ratelimitExceeded != nil {
+		stops = append(stops, s.ratelimitExceeded.Subscribe("alerting.ratelimitExceeded", func(ctx context.Context, e ratelimit.ExceededEvent) error {
+			return s.publishThreshold(ctx, ratelimit.ExceededEventType, "", e)
+		}))
+	}
+	if s.usageExceeded != nil {
+		stops = append(stops, s.usageExceeded.Subscribe("alerting.usageExceeded", func(ctx context.Context, e eventrouter.UsageExceededEvent) error {
+			return s.publishThreshold(ctx, eventrouter.UsageExceededEventType, e.WorkspaceId, e)
+		}))
+	}
+
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// publishThreshold hands the event to the webhook handler without blocking,
+// a slow webhook must not hold up ratelimiting or ingesting verifications.
+func (s *Service) publishThreshold(ctx context.Context, eventType string, workspaceId string, payload any) error {
+	e, err := s.registry.Wrap(ctx, eventType, thresholdEventVersion, workspaceId, payload)
+	if err != nil {
+		return err
+	}
+	if !s.thresholds.TryPublish(ctx, e) {
+		s.logger.Error().Str("type", eventType).Msg("webhook is falling behind, dropping event")
+	}
+	return nil
+}
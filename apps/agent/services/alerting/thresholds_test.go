@@ -0,0 +1,61 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/services/eventrouter"
+	"github.com/unkeyed/unkey/apps/agent/services/ratelimit"
+)
+
+func TestForwardsThresholdEvents(t *testing.T) {
+	received := make(chan events.Envelope, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := events.Envelope{}
+		if json.NewDecoder(r.Body).Decode(&e) == nil {
+			received <- e
+		}
+	}))
+	defer srv.Close()
+
+	ratelimitExceeded := events.NewTopic[ratelimit.ExceededEvent](10)
+	usageExceeded := events.NewTopic[eventrouter.UsageExceededEvent](10)
+	s, err := New(Config{
+		Logger:            logging.NewNoopLogger(),
+		Verifications:     events.NewTopic[eventrouter.KeyVerificationEvent](),
+		WebhookUrl:        srv.URL,
+		RatelimitExceeded: ratelimitExceeded,
+		UsageExceeded:     usageExceeded,
+	})
+	require.NoError(t, err)
+	stop := s.Start()
+	defer stop()
+
+	ratelimitExceeded.Publish(context.Background(), ratelimit.ExceededEvent{Name: "test", Identifier: "user_1", Limit: 10})
+	usageExceeded.Publish(context.Background(), eventrouter.UsageExceededEvent{WorkspaceId: "ws_1", KeyId: "key_1"})
+
+	types := map[string]events.Envelope{}
+	for len(types) < 2 {
+		select {
+		case e := <-received:
+			types[e.Type] = e
+		case <-time.After(5 * time.Second):
+			t.Fatal("events were not forwarded")
+		}
+	}
+
+	usage := types[eventrouter.UsageExceededEventType]
+	require.Equal(t, "ws_1", usage.WorkspaceId)
+	require.Equal(t, 1, usage.Version)
+
+	limit := ratelimit.ExceededEvent{}
+	require.NoError(t, json.Unmarshal(types[ratelimit.ExceededEventType].Payload, &limit))
+	require.Equal(t, "user_1", limit.Identifier)
+}
@@ -0,0 +1,162 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clock"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/repeat"
+	"github.com/unkeyed/unkey/apps/agent/pkg/webhook"
+)
+
+type Config struct {
+	Logger    logging.Logger
+	Analytics clickhouse.Querier
+
+	// Where usage records are posted to
+	WebhookUrl string
+	// Used to sign the payload, see webhook.Sign
+	Secret string
+	// The length of each reporting period, usage is sent once a period has ended.
+	// Defaults to 1 hour.
+	Interval time.Duration
+	// How long to wait after a period ended before reporting it. Verifications
+	// are buffered, retried and replayed from dead letter queues before they
+	// reach clickhouse, anything arriving after the period was reported is
+	// never billed.
+	// Defaults to 10 minutes.
+	SettlementDelay time.Duration
+	// Defaults to the real clock
+	Clock clock.Clock
+}
+
+type Service struct {
+	logger     logging.Logger
+	analytics  clickhouse.Querier
+	webhookUrl string
+	secret     string
+	interval   time.Duration
+	settlement time.Duration
+	clock      clock.Clock
+	httpClient *http.Client
+
+	mu sync.Mutex
+	// The end of the last period that was reported successfully
+	reportedUntil time.Time
+}
+
+// Payload is the body posted to the webhook
+type Payload struct {
+	// Unique per period, receivers should use it to ignore duplicate deliveries
+	Id      string   `json:"id"`
+	Start   int64    `json:"start"`
+	End     int64    `json:"end"`
+	Records []Record `json:"records"`
+}
+
+type Record struct {
+	WorkspaceId string `json:"workspaceId"`
+	// The identity, or owner, the keys belong to. Empty for keys without an identity.
+	IdentityId    string `json:"identityId,omitempty"`
+	Verifications uint64 `json:"verifications"`
+}
+
+func New(config Config) (*Service, error) {
+	if config.WebhookUrl == "" {
+		return nil, fault.New("webhook url is required")
+	}
+	if config.Analytics == nil {
+		return nil, fault.New("analytics is required")
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	settlement := config.SettlementDelay
+	if settlement <= 0 {
+		settlement = 10 * time.Minute
+	}
+	clk := config.Clock
+	if clk == nil {
+		clk = clock.New()
+	}
+	return &Service{
+		logger:     config.Logger,
+		analytics:  config.Analytics,
+		webhookUrl: config.WebhookUrl,
+		secret:     config.Secret,
+		interval:   interval,
+		settlement: settlement,
+		clock:      clk,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Start reports the last settled period right away and then every period.
+// Only one node in the cluster needs to run this.
+//
+// The returned function stops reporting.
+func (s *Service) Start() func() {
+	go s.reportPending()
+	return repeat.Every(s.interval/4, s.reportPending)
+}
+
+// reportPending reports all periods that ended at least the settlement delay
+// ago and have not been reported yet. Failed periods are retried on the next
+// call.
+func (s *Service) reportPending() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	end := s.clock.Now().Add(-s.settlement).UTC().Truncate(s.interval)
+	start := s.reportedUntil
+	if start.IsZero() {
+		start = end.Add(-s.interval)
+	}
+	for start.Before(end) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := s.Report(ctx, start, start.Add(s.interval))
+		cancel()
+		if err != nil {
+			s.logger.Error().Err(err).Time("start", start).Msg("failed to report usage")
+			return
+		}
+		start = start.Add(s.interval)
+		s.reportedUntil = start
+	}
+}
+
+// Report sends the usage between start and end to the webhook.
+func (s *Service) Report(ctx context.Context, start, end time.Time) error {
+	rows, err := s.analytics.GetUsageRecords(ctx, clickhouse.UsageRecordsRequest{Start: start, End: end})
+	if err != nil {
+		return err
+	}
+	payload := Payload{
+		Id:      fmt.Sprintf("usage_%d_%d", start.UnixMilli(), end.UnixMilli()),
+		Start:   start.UnixMilli(),
+		End:     end.UnixMilli(),
+		Records: make([]Record, len(rows)),
+	}
+	for i, row := range rows {
+		payload.Records[i] = Record{
+			WorkspaceId:   row.WorkspaceID,
+			IdentityId:    row.IdentityID,
+			Verifications: row.Verifications,
+		}
+	}
+
+	err = webhook.Post(ctx, s.httpClient, s.webhookUrl, s.secret, payload)
+	if err != nil {
+		return fault.Wrap(err, fmsg.With("failed to report usage records"))
+	}
+	s.logger.Info().Str("id", payload.Id).Int("records", len(payload.Records)).Msg("reported usage")
+	return nil
+}
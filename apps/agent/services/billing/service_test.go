@@ -0,0 +1,130 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clock"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/webhook"
+)
+
+type fakeQuerier struct {
+	clickhouse.Querier
+	requests []clickhouse.UsageRecordsRequest
+}
+
+func (f *fakeQuerier) GetUsageRecords(ctx context.Context, req clickhouse.UsageRecordsRequest) ([]clickhouse.UsageRecord, error) {
+	f.requests = append(f.requests, req)
+	return []clickhouse.UsageRecord{
+		{WorkspaceID: "ws_1", IdentityID: "user_1", Verifications: 42},
+	}, nil
+}
+
+func TestReport(t *testing.T) {
+	var payload Payload
+	var signature string
+	var body []byte
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &payload))
+		signature = r.Header.Get(webhook.SignatureHeader)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	q := &fakeQuerier{}
+	s, err := New(Config{
+		Logger:     logging.NewNoopLogger(),
+		Analytics:  q,
+		WebhookUrl: srv.URL,
+		Secret:     "secret",
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	require.NoError(t, s.Report(context.Background(), start, end))
+
+	require.Equal(t, []clickhouse.UsageRecordsRequest{{Start: start, End: end}}, q.requests)
+	require.Equal(t, Payload{
+		Id:      "usage_1704103200000_1704106800000",
+		Start:   start.UnixMilli(),
+		End:     end.UnixMilli(),
+		Records: []Record{{WorkspaceId: "ws_1", IdentityId: "user_1", Verifications: 42}},
+	}, payload)
+	require.Equal(t, webhook.Sign("secret", body), signature)
+
+	status = http.StatusInternalServerError
+	require.Error(t, s.Report(context.Background(), start, end))
+}
+
+func TestReportPendingCatchesUp(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer srv.Close()
+
+	// past the default settlement delay
+	c := clock.NewTestClock(time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC))
+	q := &fakeQuerier{}
+	s, err := New(Config{
+		Logger:     logging.NewNoopLogger(),
+		Analytics:  q,
+		WebhookUrl: srv.URL,
+		Interval:   time.Hour,
+		Clock:      c,
+	})
+	require.NoError(t, err)
+
+	end := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.reportedUntil = end.Add(-3 * time.Hour)
+	s.reportPending()
+	require.Equal(t, 3, calls)
+	require.Equal(t, end, s.reportedUntil)
+
+	// nothing left to report
+	s.reportPending()
+	require.Equal(t, 3, calls)
+}
+
+func TestReportPendingWaitsForSettlement(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer srv.Close()
+
+	end := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	c := clock.NewTestClock(end.Add(time.Minute))
+	s, err := New(Config{
+		Logger:          logging.NewNoopLogger(),
+		Analytics:       &fakeQuerier{},
+		WebhookUrl:      srv.URL,
+		Interval:        time.Hour,
+		SettlementDelay: 5 * time.Minute,
+		Clock:           c,
+	})
+	require.NoError(t, err)
+	s.reportedUntil = end.Add(-time.Hour)
+
+	// late verifications may still arrive for the period that just ended
+	s.reportPending()
+	require.Equal(t, 0, calls)
+
+	c.Tick(5 * time.Minute)
+	s.reportPending()
+	require.Equal(t, 1, calls)
+	require.Equal(t, end, s.reportedUntil)
+}
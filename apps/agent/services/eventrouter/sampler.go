@@ -0,0 +1,94 @@
+package eventrouter
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type SamplingConfig struct {
+	// How many verifications per second of a single key are always recorded
+	Threshold int
+	// The fraction of verifications recorded beyond the threshold, between 0 and 1
+	Rate float64
+	// Overrides Rate for individual workspaces
+	WorkspaceRates map[string]float64
+}
+
+// keyWindow counts the verifications of a key within one second
+type keyWindow struct {
+	second int64
+	count  int
+}
+
+// sampler keeps the number of recorded verifications of hot keys bounded.
+// Every recorded verification carries a weight, so counts can be extrapolated
+// by summing the weights.
+type sampler struct {
+	sync.Mutex
+	config    SamplingConfig
+	windows   map[string]*keyWindow
+	lastPrune int64
+	random    func() float64
+}
+
+func newSampler(config SamplingConfig) *sampler {
+	return &sampler{
+		config:  config,
+		windows: map[string]*keyWindow{},
+		random:  rand.Float64,
+	}
+}
+
+// sample returns the weight to record the verification with, or 0 if it
+// should be dropped.
+// t is the time of the verification in unix milliseconds.
+func (s *sampler) sample(workspaceId, keyId string, t int64) uint32 {
+	s.Lock()
+	defer s.Unlock()
+
+	second := t / 1000
+	s.prune(time.Now().Unix())
+
+	w, ok := s.windows[keyId]
+	if !ok || w.second != second {
+		w = &keyWindow{second: second}
+		s.windows[keyId] = w
+	}
+	w.count++
+	if w.count <= s.config.Threshold {
+		return 1
+	}
+
+	rate := s.config.Rate
+	if r, ok := s.config.WorkspaceRates[workspaceId]; ok {
+		rate = r
+	}
+	if rate >= 1 {
+		return 1
+	}
+	if rate <= 0 || s.random() >= rate {
+		return 0
+	}
+	// Round randomly, so the expected weight is exactly 1/rate. Rounding to
+	// the nearest integer would record a rate of 0.3 with a weight of 3.
+	weight, frac := math.Modf(1 / rate)
+	if s.random() < frac {
+		weight++
+	}
+	return uint32(weight)
+}
+
+// prune forgets keys that have not been verified in the last minute
+func (s *sampler) prune(now int64) {
+	if now-s.lastPrune < 60 {
+		return
+	}
+	s.lastPrune = now
+	for keyId, w := range s.windows {
+		if now-w.second > 60 {
+			delete(s.windows, keyId)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package eventrouter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler(t *testing.T) {
+	s := newSampler(SamplingConfig{
+		Threshold:      2,
+		Rate:           0.1,
+		WorkspaceRates: map[string]float64{"ws_all": 1},
+	})
+	random := 0.0
+	s.random = func() float64 { return random }
+
+	// below the threshold everything is recorded as is
+	require.Equal(t, uint32(1), s.sample("ws_1", "key_1", 1000))
+	require.Equal(t, uint32(1), s.sample("ws_1", "key_1", 1500))
+
+	// beyond the threshold, recorded verifications stand for 1/rate verifications
+	require.Equal(t, uint32(10), s.sample("ws_1", "key_1", 1999))
+	random = 0.5
+	require.Equal(t, uint32(0), s.sample("ws_1", "key_1", 1999))
+
+	// other keys are counted separately
+	require.Equal(t, uint32(1), s.sample("ws_1", "key_2", 1999))
+
+	// the next second starts over
+	require.Equal(t, uint32(1), s.sample("ws_1", "key_1", 2000))
+
+	// workspace overrides
+	for i := 0; i < 10; i++ {
+		require.Equal(t, uint32(1), s.sample("ws_all", "key_3", 3000))
+	}
+}
+
+func TestSamplerRoundsWeightsRandomly(t *testing.T) {
+	s := newSampler(SamplingConfig{Threshold: 0, Rate: 0.3})
+	draws := []float64{}
+	s.random = func() float64 {
+		r := draws[0]
+		draws = draws[1:]
+		return r
+	}
+
+	// 1/0.3 is recorded as 4 a third of the time and as 3 otherwise
+	draws = []float64{0.1, 0.2}
+	require.Equal(t, uint32(4), s.sample("ws_1", "key_1", 1000))
+	draws = []float64{0.1, 0.5}
+	require.Equal(t, uint32(3), s.sample("ws_1", "key_1", 1000))
+}
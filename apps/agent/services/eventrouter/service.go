@@ -3,19 +3,23 @@ package eventrouter
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
 	"time"
 
 	"github.com/unkeyed/unkey/apps/agent/pkg/auth"
 	"github.com/unkeyed/unkey/apps/agent/pkg/batch"
+	"github.com/unkeyed/unkey/apps/agent/pkg/circuitbreaker"
 	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
 	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse/schema"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/geoip"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
 	"github.com/unkeyed/unkey/apps/agent/pkg/openapi"
 	"github.com/unkeyed/unkey/apps/agent/pkg/prometheus"
+	"github.com/unkeyed/unkey/apps/agent/pkg/repeat"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tinybird"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
 )
@@ -35,19 +39,83 @@ type Config struct {
 	Metrics    metrics.Metrics
 	Clickhouse clickhouse.Bufferer
 	AuthToken  string
+	// Recorded with every key verification
+	NodeId string
+	// Optionally resolves the source ip of key verifications to a location
+	Geo geoip.Resolver
+	// Optionally sample the key verifications written to clickhouse
+	Sampling *SamplingConfig
+	// Count verifications and denials per workspace in prometheus
+	WorkspaceMetrics bool
+	// Optionally spill batches to this directory while tinybird is unavailable,
+	// they are replayed once it recovers. Otherwise they are dropped.
+	DeadLetterDir string
 }
 
 type Service struct {
-	logger     logging.Logger
-	metrics    metrics.Metrics
-	batcher    batch.BatchProcessor[event]
-	tb         *tinybird.Client
-	authToken  string
-	clickhouse clickhouse.Bufferer
+	logger  logging.Logger
+	metrics metrics.Metrics
+	batcher *batch.BatchProcessor[event]
+	// Key verifications are batched separately, they are also written to clickhouse
+	keyVerifications *batch.BatchProcessor[tinybirdKeyVerification]
+	tb               *tinybird.Client
+	authToken        string
+	clickhouse       clickhouse.Bufferer
+	// Every key verification received, for live streaming
+	verifications events.Topic[KeyVerificationEvent]
+	usageExceeded events.Topic[UsageExceededEvent]
+
+	workspaceMetrics bool
+	// stops replaying spilled batches
+	stopReplay func()
 }
 
 func New(config Config) (*Service, error) {
 
+	if config.Geo == nil {
+		config.Geo = geoip.NewNoop()
+	}
+
+	var s *sampler
+	if config.Sampling != nil {
+		s = newSampler(*config.Sampling)
+	}
+
+	// Fail fast while tinybird is unavailable instead of holding up the
+	// batches behind every failing request
+	tinybirdBreaker := circuitbreaker.New[any](
+		"eventrouter.tinybird",
+		circuitbreaker.WithLogger(config.Logger),
+		circuitbreaker.WithCyclicPeriod(10*time.Second),
+		circuitbreaker.WithTimeout(30*time.Second),
+		circuitbreaker.WithMaxRequests(5),
+		circuitbreaker.WithTripThreshold(10),
+	)
+
+	var sp *spill
+	stopReplay := func() {}
+	if config.DeadLetterDir != "" {
+		var err error
+		sp, err = newSpill(config.DeadLetterDir)
+		if err != nil {
+			return nil, err
+		}
+		stopReplay = repeat.Every(30*time.Second, func() {
+			replayed, replayErr := sp.replay(config.BatchSize, func(datasource string, rows []any) error {
+				_, err := tinybirdBreaker.Do(context.Background(), func(ctx context.Context) (any, error) {
+					return nil, config.Tinybird.Ingest(datasource, rows)
+				})
+				return err
+			})
+			if replayed > 0 {
+				config.Logger.Info().Int("rows", replayed).Msg("replayed spilled batches")
+			}
+			if replayErr != nil {
+				config.Logger.Warn().Err(replayErr).Msg("failed to replay spilled batches")
+			}
+		})
+	}
+
 	flush := func(ctx context.Context, events []event) {
 		if len(events) == 0 {
 			return
@@ -61,40 +129,45 @@ func New(config Config) (*Service, error) {
 			eventsByDatasource[e.datasource] = append(eventsByDatasource[e.datasource], e.row)
 		}
 		for datasource, rows := range eventsByDatasource {
-			err := config.Tinybird.Ingest(datasource, rows)
-			if err != nil {
-				config.Logger.Err(err).Str("datasource", datasource).Int("rows", len(rows)).Msg("Error ingesting")
-			}
-			prometheus.EventRouterFlushedRows.With(map[string]string{
-				"datasource": datasource,
-			}).Add(float64(len(rows)))
-
-			if datasource == "key_verifications__v2" {
-
-				for _, row := range rows {
-					e, ok := row.(tinybirdKeyVerification)
-					if !ok {
-						config.Logger.Error().Str("e", fmt.Sprintf("%T: %+v", row, row)).Msg("Error casting key verification")
-						continue
-					}
-					// dual write to clickhouse
-					outcome := "VALID"
-					if e.DeniedReason != "" {
-						outcome = e.DeniedReason
-					}
-					config.Clickhouse.BufferKeyVerification(schema.KeyVerificationRequestV1{
-						RequestID:   e.RequestID,
-						Time:        e.Time,
-						WorkspaceID: e.WorkspaceId,
-						KeySpaceID:  e.KeySpaceId,
-						KeyID:       e.KeyId,
-						Region:      e.Region,
-						Outcome:     outcome,
-						IdentityID:  e.OwnerId,
-					})
+			ingest(ctx, config, tinybirdBreaker, sp, datasource, rows)
+		}
+	}
+
+	flushKeyVerifications := func(ctx context.Context, verifications []tinybirdKeyVerification) {
+		if len(verifications) == 0 {
+			return
+		}
+		rows := make([]any, len(verifications))
+		for i, e := range verifications {
+			rows[i] = e
+		}
+		ingest(ctx, config, tinybirdBreaker, sp, keyVerificationsDatasource, rows)
+
+		for _, e := range verifications {
+			weight := uint32(1)
+			if s != nil {
+				weight = s.sample(e.WorkspaceId, e.KeyId, e.Time)
+				if weight == 0 {
+					continue
 				}
 			}
-
+			loc := config.Geo.Lookup(e.IpAddress)
+			// dual write to clickhouse
+			config.Clickhouse.BufferKeyVerification(schema.KeyVerificationRequestV1{
+				RequestID:   e.RequestID,
+				Time:        e.Time,
+				WorkspaceID: e.WorkspaceId,
+				KeySpaceID:  e.KeySpaceId,
+				KeyID:       e.KeyId,
+				Region:      e.Region,
+				Outcome:     e.outcome(),
+				IdentityID:  e.OwnerId,
+				NodeID:      config.NodeId,
+				Country:     loc.Country,
+				ASN:         loc.ASN,
+				Weight:      weight,
+				Latency:     e.Latency,
+			})
 		}
 	}
 
@@ -104,15 +177,76 @@ func New(config Config) (*Service, error) {
 		FlushInterval: config.FlushInterval,
 		Flush:         flush,
 	})
+	keyVerifications := batch.New(batch.Config[tinybirdKeyVerification]{
+		BatchSize:     config.BatchSize,
+		BufferSize:    config.BufferSize,
+		FlushInterval: config.FlushInterval,
+		Flush:         flushKeyVerifications,
+	})
 	return &Service{
-		logger:    config.Logger,
-		metrics:   config.Metrics,
-		batcher:   *batcher,
-		tb:        config.Tinybird,
-		authToken: config.AuthToken,
+		logger:           config.Logger,
+		metrics:          config.Metrics,
+		batcher:          batcher,
+		keyVerifications: keyVerifications,
+		tb:               config.Tinybird,
+		authToken:        config.AuthToken,
+		verifications:    events.NewTopic[KeyVerificationEvent](streamBufferSize),
+		usageExceeded:    events.NewTopic[UsageExceededEvent](streamBufferSize),
+		workspaceMetrics: config.WorkspaceMetrics,
+		stopReplay:       stopReplay,
 	}, nil
 }
 
+// Flush sends all buffered events without waiting for the flush interval.
+func (s *Service) Flush(ctx context.Context) error {
+	err := s.batcher.Flush(ctx)
+	if err != nil {
+		return err
+	}
+	return s.keyVerifications.Flush(ctx)
+}
+
+// Close flushes all buffered events. Events of requests that are still running
+// when it is called are dropped.
+func (s *Service) Close() {
+	s.stopReplay()
+	s.batcher.Close()
+	s.keyVerifications.Close()
+}
+
+const keyVerificationsDatasource = "key_verifications__v2"
+
+// ingest sends the rows to tinybird. Rows the circuit breaker rejected without
+// trying are spilled, if possible.
+func ingest(ctx context.Context, config Config, cb circuitbreaker.CircuitBreaker[any], sp *spill, datasource string, rows []any) {
+	_, err := cb.Do(ctx, func(ctx context.Context) (any, error) {
+		return nil, config.Tinybird.Ingest(datasource, rows)
+	})
+	rejected := errors.Is(err, circuitbreaker.ErrTripped) || errors.Is(err, circuitbreaker.ErrTooManyRequests)
+	if rejected && sp != nil {
+		spillErr := sp.write(datasource, rows)
+		if spillErr == nil {
+			config.Logger.Warn().Err(err).Str("datasource", datasource).Int("rows", len(rows)).Msg("tinybird is unavailable, spilled rows")
+			return
+		}
+		err = errors.Join(err, spillErr)
+	}
+	if err != nil {
+		config.Logger.Err(err).Str("datasource", datasource).Int("rows", len(rows)).Msg("Error ingesting")
+	}
+	prometheus.EventRouterFlushedRows.With(map[string]string{
+		"datasource": datasource,
+	}).Add(float64(len(rows)))
+}
+
+func recordWorkspaceMetrics(e tinybirdKeyVerification) {
+	workspaceId := prometheus.WorkspaceLabel.Value(e.WorkspaceId)
+	prometheus.WorkspaceVerifications.WithLabelValues(workspaceId).Inc()
+	if e.outcome() != schema.OutcomeValid {
+		prometheus.WorkspaceDenials.WithLabelValues(workspaceId).Inc()
+	}
+}
+
 // this is what we currently send to tinybird
 // we need to parse it and transform it into a clickhouse event, then dual write to both stores
 type tinybirdKeyVerification struct {
@@ -133,6 +267,23 @@ type tinybirdKeyVerification struct {
 	RequestID         string `json:"requestId,omitempty"`
 	RequestBody       string `json:"requestBody,omitempty"`
 	ResponeBody       string `json:"responseBody,omitempty"`
+	// How long the verification took in milliseconds
+	Latency float64 `json:"latency,omitempty"`
+}
+
+// outcome returns why the verification failed, or VALID.
+// Older clients don't send a denied reason, only flags.
+func (e tinybirdKeyVerification) outcome() string {
+	switch {
+	case e.DeniedReason != "":
+		return e.DeniedReason
+	case e.Ratelimited:
+		return schema.OutcomeRateLimited
+	case e.UsageExceeded:
+		return schema.OutcomeUsageExceeded
+	default:
+		return schema.OutcomeValid
+	}
 }
 
 func (s *Service) CreateHandler() (string, http.HandlerFunc) {
@@ -166,7 +317,7 @@ func (s *Service) CreateHandler() (string, http.HandlerFunc) {
 
 		successfulRows := 0
 		switch datasource {
-		case "key_verifications__v2":
+		case keyVerificationsDatasource:
 			events, decodeErr := decode[tinybirdKeyVerification](r.Body)
 			if decodeErr != nil {
 				s.logger.Err(decodeErr).Msg("Error decoding request")
@@ -178,7 +329,27 @@ func (s *Service) CreateHandler() (string, http.HandlerFunc) {
 				return
 			}
 			for _, e := range events {
-				s.batcher.Buffer(event{datasource, e})
+				s.keyVerifications.Buffer(e)
+				s.verifications.Publish(ctx, KeyVerificationEvent{
+					Time:        e.Time,
+					WorkspaceId: e.WorkspaceId,
+					ApiId:       e.ApiId,
+					KeyId:       e.KeyId,
+					Outcome:     e.outcome(),
+					Region:      e.Region,
+				})
+				if s.workspaceMetrics {
+					recordWorkspaceMetrics(e)
+				}
+				if e.outcome() == schema.OutcomeUsageExceeded {
+					s.usageExceeded.TryPublish(ctx, UsageExceededEvent{
+						Time:        e.Time,
+						WorkspaceId: e.WorkspaceId,
+						ApiId:       e.ApiId,
+						KeyId:       e.KeyId,
+						IdentityId:  e.OwnerId,
+					})
+				}
 			}
 			successfulRows = len(events)
 		default:
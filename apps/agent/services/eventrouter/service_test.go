@@ -0,0 +1,28 @@
+package eventrouter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse/schema"
+	"github.com/unkeyed/unkey/apps/agent/pkg/prometheus"
+	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+)
+
+func TestOutcome(t *testing.T) {
+	require.Equal(t, schema.OutcomeValid, tinybirdKeyVerification{}.outcome())
+	require.Equal(t, schema.OutcomeRateLimited, tinybirdKeyVerification{Ratelimited: true}.outcome())
+	require.Equal(t, schema.OutcomeUsageExceeded, tinybirdKeyVerification{UsageExceeded: true}.outcome())
+	require.Equal(t, schema.OutcomeExpired, tinybirdKeyVerification{DeniedReason: schema.OutcomeExpired, Ratelimited: true}.outcome())
+}
+
+func TestRecordWorkspaceMetrics(t *testing.T) {
+	workspaceId := uid.New("ws")
+
+	recordWorkspaceMetrics(tinybirdKeyVerification{WorkspaceId: workspaceId})
+	recordWorkspaceMetrics(tinybirdKeyVerification{WorkspaceId: workspaceId, Ratelimited: true})
+
+	require.Equal(t, 2.0, testutil.ToFloat64(prometheus.WorkspaceVerifications.WithLabelValues(workspaceId)))
+	require.Equal(t, 1.0, testutil.ToFloat64(prometheus.WorkspaceDenials.WithLabelValues(workspaceId)))
+}
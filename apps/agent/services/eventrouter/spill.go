@@ -0,0 +1,106 @@
+package eventrouter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Southclaws/fault"
+	"github.com/Southclaws/fault/fmsg"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+)
+
+// spill holds batches that were not sent because tinybird is unavailable,
+// with one dead letter queue per datasource, until they can be replayed.
+type spill struct {
+	mu     sync.Mutex
+	dir    string
+	queues map[string]*clickhouse.DeadLetterQueue[json.RawMessage]
+}
+
+// newSpill picks up the batches spilled before a restart.
+func newSpill(dir string) (*spill, error) {
+	s := &spill{
+		dir:    dir,
+		queues: map[string]*clickhouse.DeadLetterQueue[json.RawMessage]{},
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fault.Wrap(err, fmsg.With("failed to create dead letter directory"))
+	}
+	// .replaying files are left over from replays that were interrupted
+	for _, ext := range []string{".jsonl", ".replaying"} {
+		files, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+		if err != nil {
+			return nil, fault.Wrap(err, fmsg.With("failed to list dead letter files"))
+		}
+		for _, file := range files {
+			_, err = s.queue(strings.TrimSuffix(filepath.Base(file), ext))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *spill) queue(datasource string) (*clickhouse.DeadLetterQueue[json.RawMessage], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[datasource]
+	if ok {
+		return q, nil
+	}
+	q, err := clickhouse.NewDeadLetterQueue[json.RawMessage](s.dir, datasource, clickhouse.DefaultDeadLetterMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	s.queues[datasource] = q
+	return q, nil
+}
+
+func (s *spill) write(datasource string, rows []any) error {
+	q, err := s.queue(datasource)
+	if err != nil {
+		return err
+	}
+	raw := make([]json.RawMessage, len(rows))
+	for i, row := range rows {
+		raw[i], err = json.Marshal(row)
+		if err != nil {
+			return fault.Wrap(err, fmsg.With("failed to encode row"))
+		}
+	}
+	return q.Write(raw)
+}
+
+// replay passes all spilled rows to ingest, in batches of batchSize per
+// datasource. Rows that fail to ingest stay spilled for the next replay.
+func (s *spill) replay(batchSize int, ingest func(datasource string, rows []any) error) (int, error) {
+	s.mu.Lock()
+	queues := make(map[string]*clickhouse.DeadLetterQueue[json.RawMessage], len(s.queues))
+	for datasource, q := range s.queues {
+		queues[datasource] = q
+	}
+	s.mu.Unlock()
+
+	replayed := 0
+	for datasource, q := range queues {
+		n, err := q.Replay(batchSize, func(raw []json.RawMessage) error {
+			rows := make([]any, len(raw))
+			for i, row := range raw {
+				rows[i] = row
+			}
+			return ingest(datasource, rows)
+		})
+		replayed += n
+		if err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, nil
+}
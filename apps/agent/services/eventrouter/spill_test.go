@@ -0,0 +1,61 @@
+package eventrouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/circuitbreaker"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+)
+
+type trippedBreaker struct{}
+
+func (trippedBreaker) Do(ctx context.Context, fn func(context.Context) (any, error)) (any, error) {
+	return nil, circuitbreaker.ErrTripped
+}
+
+func TestIngestSpillsRejectedBatches(t *testing.T) {
+	dir := t.TempDir()
+	sp, err := newSpill(dir)
+	require.NoError(t, err)
+
+	rows := []any{
+		tinybirdKeyVerification{KeyId: "key_1"},
+		tinybirdKeyVerification{KeyId: "key_2"},
+	}
+	ingest(context.Background(), Config{Logger: logging.NewNoopLogger()}, trippedBreaker{}, sp, keyVerificationsDatasource, rows)
+
+	// Spilled batches survive a restart
+	sp, err = newSpill(dir)
+	require.NoError(t, err)
+
+	// tinybird is still down
+	replayed, err := sp.replay(10, func(datasource string, rows []any) error {
+		return errors.New("tinybird is down")
+	})
+	require.Error(t, err)
+	require.Equal(t, 0, replayed)
+
+	keyIds := []string{}
+	replayed, err = sp.replay(10, func(datasource string, rows []any) error {
+		require.Equal(t, keyVerificationsDatasource, datasource)
+		for _, row := range rows {
+			v := tinybirdKeyVerification{}
+			require.NoError(t, json.Unmarshal(row.(json.RawMessage), &v))
+			keyIds = append(keyIds, v.KeyId)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, replayed)
+	require.Equal(t, []string{"key_1", "key_2"}, keyIds)
+
+	replayed, err = sp.replay(10, func(datasource string, rows []any) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, replayed)
+}
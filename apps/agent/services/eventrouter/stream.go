@@ -0,0 +1,116 @@
+package eventrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/unkeyed/unkey/apps/agent/pkg/auth"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+)
+
+// KeyVerificationEvent is streamed to dashboards watching live traffic
+type KeyVerificationEvent struct {
+	Time        int64  `json:"time"`
+	WorkspaceId string `json:"workspaceId"`
+	ApiId       string `json:"apiId"`
+	KeyId       string `json:"keyId"`
+	Outcome     string `json:"outcome"`
+	Region      string `json:"region"`
+}
+
+// How many events are held for a slow client before they are dropped
+const streamBufferSize = 100
+
+// Verifications lets other services consume key verifications as they arrive at this node.
+func (s *Service) Verifications() events.EventSubscriber[KeyVerificationEvent] {
+	return s.verifications
+}
+
+// CreateStreamHandler returns a server-sent events endpoint streaming key
+// verifications of a workspace as they arrive at this node, optionally
+// filtered by api or key.
+//
+// Events are best effort, a slow client misses events rather than slowing
+// down ingestion.
+func (s *Service) CreateStreamHandler() (string, http.HandlerFunc) {
+	return "GET /v1/analytics.stream", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		err := auth.Authorize(ctx, s.authToken, r.Header.Get("Authorization"))
+		if err != nil {
+			s.logger.Warn().Err(err).Msg("failed to authorize request")
+			http.Error(w, "Unauthorized", http.StatusForbidden)
+			return
+		}
+
+		query := r.URL.Query()
+		workspaceId := query.Get("workspaceId")
+		if workspaceId == "" {
+			http.Error(w, "missing ?workspaceId= parameter", http.StatusBadRequest)
+			return
+		}
+		apiId := query.Get("apiId")
+		keyId := query.Get("keyId")
+
+		rc := http.NewResponseController(w)
+		// The connection stays open until the client goes away
+		err = rc.SetWriteDeadline(time.Time{})
+		if err != nil {
+			s.logger.Warn().Err(err).Msg("failed to disable write deadline")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		err = rc.Flush()
+		if err != nil {
+			s.logger.Warn().Err(err).Msg("failed to flush stream")
+			return
+		}
+
+		// Decouple the topic from the client, so a stalled connection
+		// never blocks publishing events
+		buffered := make(chan KeyVerificationEvent, streamBufferSize)
+		stop := s.verifications.Subscribe(uid.New("stream"), func(_ context.Context, e KeyVerificationEvent) error {
+			if e.WorkspaceId != workspaceId || (apiId != "" && e.ApiId != apiId) || (keyId != "" && e.KeyId != keyId) {
+				return nil
+			}
+			select {
+			case buffered <- e:
+			default:
+			}
+			return nil
+		})
+		defer stop()
+
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat.C:
+				_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			case e := <-buffered:
+				var b []byte
+				b, err = json.Marshal(e)
+				if err != nil {
+					s.logger.Err(err).Msg("failed to marshal event")
+					continue
+				}
+				_, err = fmt.Fprintf(w, "event: verification\ndata: %s\n\n", b)
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
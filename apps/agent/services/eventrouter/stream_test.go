@@ -0,0 +1,112 @@
+package eventrouter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unkeyed/unkey/apps/agent/pkg/clickhouse"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/tinybird"
+)
+
+func TestStreamKeyVerifications(t *testing.T) {
+	svc, err := New(Config{
+		BatchSize:     100,
+		BufferSize:    100,
+		FlushInterval: time.Hour,
+		Tinybird:      tinybird.New("http://localhost", ""),
+		Logger:        logging.NewNoopLogger(),
+		Clickhouse:    clickhouse.NewNoop(),
+		AuthToken:     "token",
+	})
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(svc.CreateHandler())
+	mux.HandleFunc(svc.CreateStreamHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/analytics.stream?workspaceId=ws_1&keyId=key_1", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	body := strings.Join([]string{
+		`{"workspaceId":"ws_1","keyId":"key_2","time":1}`,
+		`{"workspaceId":"ws_2","keyId":"key_1","time":2}`,
+		`{"workspaceId":"ws_1","keyId":"key_1","apiId":"api_1","time":3,"ratelimited":true}`,
+	}, "\n")
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	// The subscription is set up after the headers are flushed, so keep sending
+	// until the first event arrives
+	var got KeyVerificationEvent
+	require.Eventually(t, func() bool {
+		post, postErr := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/v0/events?name=key_verifications__v2", strings.NewReader(body))
+		require.NoError(t, postErr)
+		post.Header.Set("Authorization", "Bearer token")
+		postRes, postErr := http.DefaultClient.Do(post)
+		require.NoError(t, postErr)
+		postRes.Body.Close()
+
+		select {
+		case line := <-lines:
+			for !strings.HasPrefix(line, "data: ") {
+				line = <-lines
+			}
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &got))
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 3*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, KeyVerificationEvent{
+		Time:        3,
+		WorkspaceId: "ws_1",
+		ApiId:       "api_1",
+		KeyId:       "key_1",
+		Outcome:     "RATE_LIMITED",
+	}, got)
+}
+
+func TestStreamRequiresAuthorization(t *testing.T) {
+	svc, err := New(Config{
+		BatchSize:     100,
+		BufferSize:    100,
+		FlushInterval: time.Hour,
+		Tinybird:      tinybird.New("http://localhost", ""),
+		Logger:        logging.NewNoopLogger(),
+		Clickhouse:    clickhouse.NewNoop(),
+		AuthToken:     "token",
+	})
+	require.NoError(t, err)
+
+	_, handler := svc.CreateStreamHandler()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/v1/analytics.stream?workspaceId=ws_1", nil))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
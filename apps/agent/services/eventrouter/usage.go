@@ -0,0 +1,24 @@
+package eventrouter
+
+import (
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+)
+
+const UsageExceededEventType = "usage.exceeded"
+
+// UsageExceededEvent is published for every verification rejected because the
+// key has no remaining uses. The remaining limit and its refill interval are
+// not part of the verification, consumers need to look them up if required.
+type UsageExceededEvent struct {
+	Time        int64  `json:"time"`
+	WorkspaceId string `json:"workspaceId"`
+	ApiId       string `json:"apiId"`
+	KeyId       string `json:"keyId"`
+	IdentityId  string `json:"identityId,omitempty"`
+}
+
+// UsageExceeded lets other services consume rejected verifications of keys
+// without remaining uses, as they arrive at this node.
+func (s *Service) UsageExceeded() events.EventSubscriber[UsageExceededEvent] {
+	return s.usageExceeded
+}
@@ -53,6 +53,7 @@ func (s *service) getBucket(key bucketKey) (*bucket, bool) {
 		}
 		s.bucketsLock.Lock()
 		s.buckets[key.toString()] = b
+		activeRatelimits.Set(float64(len(s.buckets)))
 		s.bucketsLock.Unlock()
 	}
 	return b, ok
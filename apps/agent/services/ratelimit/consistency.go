@@ -30,7 +30,7 @@ func newConsistencyChecker(logger logging.Logger) *consistencyChecker {
 			if len(peers) > 1 {
 				// Our hashring ensures that a single key is only ever sent to a single node for pushpull
 				// In theory at least..
-				m.logger.Warn().Str("key", key).Interface("peers", peers).Msg("ratelimit used multiple origins")
+				m.logger.Warn().Str("bucketKey", key).Interface("peers", peers).Msg("ratelimit used multiple origins")
 			}
 
 		}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+)
+
+const ExceededEventType = "ratelimit.exceeded"
+
+// ExceededEvent is published whenever a ratelimit rejects a request, or would
+// have rejected it in shadow mode.
+type ExceededEvent struct {
+	Time       int64  `json:"time"`
+	Name       string `json:"name"`
+	Identifier string `json:"identifier"`
+	Limit      int64  `json:"limit"`
+	// The window duration in milliseconds
+	Window  int64 `json:"window"`
+	Current int64 `json:"current"`
+	Shadow  bool  `json:"shadow"`
+}
+
+// WithExceededEvents publishes an ExceededEvent for every ratelimit that was exceeded.
+func WithExceededEvents(publisher events.EventPublisher[ExceededEvent]) Middleware {
+	return func(svc Service) Service {
+		return &exceededMiddleware{Service: svc, publisher: publisher}
+	}
+}
+
+type exceededMiddleware struct {
+	Service
+	publisher events.EventPublisher[ExceededEvent]
+}
+
+func (mw *exceededMiddleware) Ratelimit(ctx context.Context, req *ratelimitv1.RatelimitRequest) (*ratelimitv1.RatelimitResponse, error) {
+	res, err := mw.Service.Ratelimit(ctx, req)
+	if err == nil {
+		mw.publish(ctx, req, res)
+	}
+	return res, err
+}
+
+func (mw *exceededMiddleware) MultiRatelimit(ctx context.Context, req *ratelimitv1.RatelimitMultiRequest) (*ratelimitv1.RatelimitMultiResponse, error) {
+	res, err := mw.Service.MultiRatelimit(ctx, req)
+	if err == nil {
+		for i, r := range res.Ratelimits {
+			if i < len(req.Ratelimits) {
+				mw.publish(ctx, req.Ratelimits[i], r)
+			}
+		}
+	}
+	return res, err
+}
+
+func (mw *exceededMiddleware) publish(ctx context.Context, req *ratelimitv1.RatelimitRequest, res *ratelimitv1.RatelimitResponse) {
+	if res.Success && !res.WouldBlock {
+		return
+	}
+	t := time.Now().UnixMilli()
+	if req.Time != nil {
+		t = req.GetTime()
+	}
+	// never hold up ratelimiting, slow subscribers miss events
+	mw.publisher.TryPublish(ctx, ExceededEvent{
+		Time:       t,
+		Name:       req.Name,
+		Identifier: req.Identifier,
+		Limit:      req.Limit,
+		Window:     req.Duration,
+		Current:    res.Current,
+		Shadow:     res.Success,
+	})
+}
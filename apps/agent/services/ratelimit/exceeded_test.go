@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/events"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+)
+
+// fakeService allows a fixed number of requests
+type fakeService struct {
+	Service
+	limit int64
+	taken int64
+}
+
+func (s *fakeService) Ratelimit(ctx context.Context, req *ratelimitv1.RatelimitRequest) (*ratelimitv1.RatelimitResponse, error) {
+	if s.taken >= s.limit {
+		return &ratelimitv1.RatelimitResponse{Limit: s.limit, Current: s.taken, Success: false}, nil
+	}
+	s.taken++
+	return &ratelimitv1.RatelimitResponse{Limit: s.limit, Current: s.taken, Remaining: s.limit - s.taken, Success: true}, nil
+}
+
+func TestWithExceededEvents(t *testing.T) {
+	topic := events.NewTopic[ExceededEvent](10)
+	exceeded := topic.SubscribeChannel("test")
+	svc := WithExceededEvents(topic)(&fakeService{limit: 2})
+
+	now := time.Now().UnixMilli()
+	req := func() *ratelimitv1.RatelimitRequest {
+		return &ratelimitv1.RatelimitRequest{
+			Name:       "test",
+			Identifier: "user_1",
+			Limit:      2,
+			Duration:   60_000,
+			Cost:       1,
+			Time:       util.Pointer(now),
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.Ratelimit(context.Background(), req())
+		require.NoError(t, err)
+	}
+
+	select {
+	case e := <-exceeded:
+		require.Equal(t, ExceededEvent{
+			Time:       now,
+			Name:       "test",
+			Identifier: "user_1",
+			Limit:      2,
+			Window:     60_000,
+			Current:    2,
+		}, e)
+	default:
+		t.Fatal("expected an exceeded event")
+	}
+	require.Len(t, exceeded, 0)
+}
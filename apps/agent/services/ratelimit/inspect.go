@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// Stats summarizes the buckets held by this node, to debug ratelimit
+// decisions in production.
+type Stats struct {
+	Buckets int
+	Windows int
+	// The buckets with the highest counter in their current window, in
+	// descending order
+	Hottest []BucketStats
+}
+
+type BucketStats struct {
+	// The hashed bucket key, so stats can be shared without leaking identifiers
+	Id       string
+	Limit    int64
+	Duration time.Duration
+	// Requests counted in the current window
+	Current int64
+}
+
+// Inspector is implemented by services that can report on their buckets.
+type Inspector interface {
+	// Inspect summarizes all buckets, including up to topN of the hottest.
+	Inspect(ctx context.Context, topN int) Stats
+}
+
+var _ Inspector = &service{}
+
+func (s *service) Inspect(ctx context.Context, topN int) Stats {
+	now := time.Now()
+	stats := Stats{Hottest: []BucketStats{}}
+
+	s.bucketsLock.RLock()
+	defer s.bucketsLock.RUnlock()
+
+	stats.Buckets = len(s.buckets)
+	for key, b := range s.buckets {
+		b.RLock()
+		stats.Windows += len(b.windows)
+		current := int64(0)
+		if w, ok := b.windows[calculateSequence(now, b.duration)]; ok {
+			current = w.Counter
+		}
+		b.RUnlock()
+
+		h := sha256.Sum256([]byte(key))
+		stats.Hottest = append(stats.Hottest, BucketStats{
+			Id:       hex.EncodeToString(h[:8]),
+			Limit:    b.limit,
+			Duration: b.duration,
+			Current:  current,
+		})
+	}
+
+	sort.Slice(stats.Hottest, func(i, j int) bool {
+		return stats.Hottest[i].Current > stats.Hottest[j].Current
+	})
+	if len(stats.Hottest) > topN {
+		stats.Hottest = stats.Hottest[:topN]
+	}
+	return stats
+}
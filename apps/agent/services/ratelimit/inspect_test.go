@@ -0,0 +1,29 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	s := &service{buckets: map[string]*bucket{}}
+	now := time.Now()
+
+	for i, counter := range []int64{3, 10, 1} {
+		b, _ := s.getBucket(bucketKey{identifier: string(rune('a' + i)), limit: 100, duration: time.Hour})
+		b.getCurrentWindow(now).Counter = counter
+		b.getPreviousWindow(now)
+	}
+
+	stats := s.Inspect(context.Background(), 2)
+	require.Equal(t, 3, stats.Buckets)
+	require.Equal(t, 6, stats.Windows)
+	require.Len(t, stats.Hottest, 2)
+	require.Equal(t, int64(10), stats.Hottest[0].Current)
+	require.Equal(t, int64(3), stats.Hottest[1].Current)
+	require.Equal(t, int64(100), stats.Hottest[0].Limit)
+	require.NotContains(t, stats.Hottest[0].Id, "b-100")
+}
@@ -24,6 +24,15 @@ var (
 		Name:      "ratelimits_total",
 	}, []string{"passed"})
 
+	// ratelimitDecisions counts allowed and denied requests by limiter: either
+	// decided by this node from memory, or by the origin node of the identifier
+	// which is chosen by consistent hashing
+	ratelimitDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "agent",
+		Subsystem: "ratelimit",
+		Name:      "decisions_total",
+	}, []string{"limiter", "outcome"})
+
 	// forceSync is a counter that increments every time the agent is forced to
 	// sync with the origin ratelimit service because it doesn't have enough data
 	forceSync = promauto.NewCounter(prometheus.CounterOpts{
@@ -32,3 +41,16 @@ var (
 		Name:      "force_sync",
 	})
 )
+
+const (
+	limiterMemory     = "memory"
+	limiterConsistent = "consistent"
+)
+
+func recordDecision(limiter string, passed bool) {
+	outcome := "allowed"
+	if !passed {
+		outcome = "denied"
+	}
+	ratelimitDecisions.WithLabelValues(limiter, outcome).Inc()
+}
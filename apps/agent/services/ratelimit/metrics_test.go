@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+)
+
+func TestMultiRatelimitRecordsDecisions(t *testing.T) {
+	rl, err := New(Config{
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	allowed := testutil.ToFloat64(ratelimitDecisions.WithLabelValues(limiterMemory, "allowed"))
+	denied := testutil.ToFloat64(ratelimitDecisions.WithLabelValues(limiterMemory, "denied"))
+
+	req := &ratelimitv1.RatelimitMultiRequest{
+		Ratelimits: []*ratelimitv1.RatelimitRequest{
+			{Identifier: uid.New("test"), Limit: 1, Duration: 60_000, Cost: 1},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		_, err = rl.MultiRatelimit(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, allowed+1, testutil.ToFloat64(ratelimitDecisions.WithLabelValues(limiterMemory, "allowed")))
+	require.Equal(t, denied+1, testutil.ToFloat64(ratelimitDecisions.WithLabelValues(limiterMemory, "denied")))
+	require.GreaterOrEqual(t, testutil.ToFloat64(activeRatelimits), 1.0)
+}
@@ -2,8 +2,10 @@ package ratelimit
 
 import (
 	"context"
+	"time"
 
 	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
 	"go.opentelemetry.io/otel/attribute"
 )
@@ -72,3 +74,52 @@ func (mw *tracingMiddleware) Mitigate(ctx context.Context, req *ratelimitv1.Miti
 	}
 	return res, err
 }
+
+// WithMetrics observes the latency and outcome of every method call.
+func WithMetrics(m metrics.Metrics) Middleware {
+	return func(svc Service) Service {
+		return &metricsMiddleware{next: svc, metrics: m}
+	}
+}
+
+type metricsMiddleware struct {
+	next    Service
+	metrics metrics.Metrics
+}
+
+func (mw *metricsMiddleware) observe(method string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	mw.metrics.Observe(metrics.ServiceLatency{
+		Service: "ratelimit",
+		Method:  method,
+		Outcome: outcome,
+	}, float64(time.Since(start).Microseconds())/1000)
+}
+
+func (mw *metricsMiddleware) Ratelimit(ctx context.Context, req *ratelimitv1.RatelimitRequest) (res *ratelimitv1.RatelimitResponse, err error) {
+	defer func(start time.Time) { mw.observe("Ratelimit", start, err) }(time.Now())
+	return mw.next.Ratelimit(ctx, req)
+}
+
+func (mw *metricsMiddleware) MultiRatelimit(ctx context.Context, req *ratelimitv1.RatelimitMultiRequest) (res *ratelimitv1.RatelimitMultiResponse, err error) {
+	defer func(start time.Time) { mw.observe("MultiRatelimit", start, err) }(time.Now())
+	return mw.next.MultiRatelimit(ctx, req)
+}
+
+func (mw *metricsMiddleware) PushPull(ctx context.Context, req *ratelimitv1.PushPullRequest) (res *ratelimitv1.PushPullResponse, err error) {
+	defer func(start time.Time) { mw.observe("PushPull", start, err) }(time.Now())
+	return mw.next.PushPull(ctx, req)
+}
+
+func (mw *metricsMiddleware) CommitLease(ctx context.Context, req *ratelimitv1.CommitLeaseRequest) (res *ratelimitv1.CommitLeaseResponse, err error) {
+	defer func(start time.Time) { mw.observe("CommitLease", start, err) }(time.Now())
+	return mw.next.CommitLease(ctx, req)
+}
+
+func (mw *metricsMiddleware) Mitigate(ctx context.Context, req *ratelimitv1.MitigateRequest) (res *ratelimitv1.MitigateResponse, err error) {
+	defer func(start time.Time) { mw.observe("Mitigate", start, err) }(time.Now())
+	return mw.next.Mitigate(ctx, req)
+}
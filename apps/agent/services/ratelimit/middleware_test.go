@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+)
+
+type fakeMetrics struct {
+	metrics.Metrics
+	observed []metrics.Metric
+}
+
+func (m *fakeMetrics) Observe(metric metrics.Metric, value float64) {
+	m.observed = append(m.observed, metric)
+}
+
+func TestWithMetricsObservesLatency(t *testing.T) {
+	m := &fakeMetrics{}
+	svc := WithMetrics(m)(&fakeService{limit: 1})
+
+	_, err := svc.Ratelimit(context.Background(), &ratelimitv1.RatelimitRequest{Identifier: "user_1"})
+	require.NoError(t, err)
+	require.Equal(t, []metrics.Metric{
+		metrics.ServiceLatency{Service: "ratelimit", Method: "Ratelimit", Outcome: "success"},
+	}, m.observed)
+}
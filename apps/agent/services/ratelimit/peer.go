@@ -49,7 +49,7 @@ func (s *service) getPeerClient(ctx context.Context, key string) (ratelimitv1con
 	c, ok := s.peers[url]
 	s.peersMu.RUnlock()
 	if !ok {
-		interceptor, err := otelconnect.NewInterceptor(otelconnect.WithTracerProvider(tracing.GetGlobalTraceProvider()))
+		interceptor, err := otelconnect.NewInterceptor(otelconnect.WithTracerProvider(tracing.GetGlobalTraceProvider()), otelconnect.WithPropagator(tracing.Propagator))
 		if err != nil {
 			tracing.RecordError(span, err)
 			s.logger.Err(err).Msg("failed to create interceptor")
@@ -90,7 +90,7 @@ func (s *service) getAllPeers(ctx context.Context) ([]peer, error) {
 		c, ok := s.peers[url]
 		s.peersMu.RUnlock()
 		if !ok {
-			interceptor, err := otelconnect.NewInterceptor(otelconnect.WithTracerProvider(tracing.GetGlobalTraceProvider()))
+			interceptor, err := otelconnect.NewInterceptor(otelconnect.WithTracerProvider(tracing.GetGlobalTraceProvider()), otelconnect.WithPropagator(tracing.Propagator))
 			if err != nil {
 				s.logger.Err(err).Msg("failed to create interceptor")
 				return nil, err
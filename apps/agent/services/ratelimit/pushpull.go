@@ -16,15 +16,17 @@ func (s *service) PushPull(ctx context.Context, req *ratelimitv1.PushPullRequest
 		Limit:      req.Request.Limit,
 		Duration:   time.Duration(req.Request.Duration) * time.Millisecond,
 		Cost:       req.Request.Cost,
+		Shadow:     req.Request.Shadow,
 	})
 
 	return &ratelimitv1.PushPullResponse{
 		Response: &ratelimitv1.RatelimitResponse{
-			Current:   int64(r.Current),
-			Limit:     int64(r.Limit),
-			Remaining: int64(r.Remaining),
-			Reset_:    r.Reset,
-			Success:   r.Pass,
+			Current:    int64(r.Current),
+			Limit:      int64(r.Limit),
+			Remaining:  int64(r.Remaining),
+			Reset_:     r.Reset,
+			Success:    r.Pass,
+			WouldBlock: r.WouldBlock,
 		},
 
 		Current:  r.currentWindow,
@@ -30,6 +30,7 @@ func (s *service) Ratelimit(ctx context.Context, req *ratelimitv1.RatelimitReque
 		Limit:      req.Limit,
 		Duration:   time.Duration(req.Duration) * time.Millisecond,
 		Cost:       req.Cost,
+		Shadow:     req.Shadow,
 	}
 	if req.Lease != nil {
 		ratelimitReq.Lease = &lease{
@@ -47,6 +48,7 @@ func (s *service) Ratelimit(ctx context.Context, req *ratelimitv1.RatelimitReque
 		// The control flow is a bit unusual here because we want to return early on
 		// success, rather than on error
 		if err == nil && originRes != nil {
+			recordDecision(limiterConsistent, originRes.Success && !originRes.WouldBlock)
 			return originRes, nil
 		}
 		if err != nil {
@@ -57,15 +59,17 @@ func (s *service) Ratelimit(ctx context.Context, req *ratelimitv1.RatelimitReque
 	}
 
 	taken := s.Take(ctx, ratelimitReq)
+	recordDecision(limiterMemory, taken.Pass && !taken.WouldBlock)
 
 	// s.logger.Warn().Str("taken", fmt.Sprintf("%+v", taken)).Send()
 
 	res := &ratelimitv1.RatelimitResponse{
-		Current:   int64(taken.Current),
-		Limit:     int64(taken.Limit),
-		Remaining: int64(taken.Remaining),
-		Reset_:    taken.Reset,
-		Success:   taken.Pass,
+		Current:    int64(taken.Current),
+		Limit:      int64(taken.Limit),
+		Remaining:  int64(taken.Remaining),
+		Reset_:     taken.Reset,
+		Success:    taken.Pass,
+		WouldBlock: taken.WouldBlock,
 	}
 
 	if s.syncBuffer != nil {
@@ -164,7 +168,7 @@ func (s *service) bufferSync(ctx context.Context, req *ratelimitv1.RatelimitRequ
 	origin, err := s.cluster.FindNode(key)
 	if err != nil {
 		tracing.RecordError(span, err)
-		s.logger.Warn().Err(err).Str("key", key).Msg("unable to find responsible nodes")
+		s.logger.Warn().Err(err).Str("bucketKey", key).Msg("unable to find responsible nodes")
 		return fault.Wrap(err)
 	}
 	span.SetAttributes(attribute.Int("channelSize", len(s.syncBuffer)))
@@ -5,30 +5,88 @@ import (
 	"time"
 
 	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
+	"github.com/unkeyed/unkey/apps/agent/pkg/util"
+	"google.golang.org/protobuf/proto"
 )
 
+// MultiRatelimit evaluates all ratelimits together and only consumes tokens if
+// all of them pass. See TakeMany for details.
 func (s *service) MultiRatelimit(ctx context.Context, req *ratelimitv1.RatelimitMultiRequest) (*ratelimitv1.RatelimitMultiResponse, error) {
+	ctx, span := tracing.Start(ctx, "ratelimit.MultiRatelimit")
+	defer span.End()
 
-	responses := make([]*ratelimitv1.RatelimitResponse, len(req.Ratelimits))
+	now := time.Now()
+	ratelimitReqs := make([]ratelimitRequest, len(req.Ratelimits))
 	for i, r := range req.Ratelimits {
-		res := s.Take(ctx, ratelimitRequest{
+		t := now
+		if r.Time != nil {
+			t = time.UnixMilli(r.GetTime())
+		} else {
+			r.Time = util.Pointer(now.UnixMilli())
+		}
+		ratelimitReqs[i] = ratelimitRequest{
+			Time:       t,
+			Name:       r.Name,
 			Identifier: r.Identifier,
 			Limit:      r.Limit,
 			Duration:   time.Duration(r.Duration) * time.Millisecond,
 			Cost:       r.Cost,
-		})
+			Shadow:     r.Shadow,
+		}
+	}
 
+	for i, r := range ratelimitReqs {
+		s.syncColdWindows(ctx, req.Ratelimits[i], r)
+	}
+
+	taken, success := s.TakeMany(ctx, ratelimitReqs)
+
+	responses := make([]*ratelimitv1.RatelimitResponse, len(taken))
+	for i, res := range taken {
+		recordDecision(limiterMemory, success && !res.WouldBlock)
 		responses[i] = &ratelimitv1.RatelimitResponse{
-			Limit:     res.Limit,
-			Remaining: res.Remaining,
-			Reset_:    res.Reset,
-			Success:   res.Pass,
-			Current:   res.Current,
+			Limit:      res.Limit,
+			Remaining:  res.Remaining,
+			Reset_:     res.Reset,
+			Success:    res.Pass,
+			Current:    res.Current,
+			WouldBlock: res.WouldBlock,
 		}
 
+		if s.syncBuffer != nil && success {
+			err := s.bufferSync(ctx, req.Ratelimits[i], ratelimitReqs[i].Time, success)
+			if err != nil {
+				s.logger.Err(err).Msg("failed to sync buffer")
+			}
+		}
 	}
 
 	return &ratelimitv1.RatelimitMultiResponse{
-		Ratelimits: responses}, nil
+		Ratelimits: responses,
+		Success:    success,
+	}, nil
 
 }
+
+// syncColdWindows pulls the state of the ratelimit from its origin if we have
+// no local windows yet, just like Ratelimit does, so TakeMany does not decide
+// based on an empty bucket. Nothing is consumed at the origin, the tokens
+// taken locally are pushed afterwards by bufferSync.
+func (s *service) syncColdWindows(ctx context.Context, req *ratelimitv1.RatelimitRequest, ratelimitReq ratelimitRequest) {
+	if s.cluster == nil {
+		return
+	}
+	prevExists, currExists := s.CheckWindows(ctx, ratelimitReq)
+	if prevExists || currExists {
+		return
+	}
+
+	pull := proto.Clone(req).(*ratelimitv1.RatelimitRequest)
+	pull.Cost = 0
+	_, err := s.ratelimitOrigin(ctx, pull)
+	if err != nil {
+		// fall back to local state
+		s.logger.Err(err).Msg("failed to sync with origin, falling back to local state")
+	}
+}
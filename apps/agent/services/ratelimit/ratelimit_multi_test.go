@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	connect "connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
+	"github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1/ratelimitv1connect"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cluster"
+	connectSrv "github.com/unkeyed/unkey/apps/agent/pkg/connect"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
+	"github.com/unkeyed/unkey/apps/agent/pkg/uid"
+)
+
+func TestMultiRatelimitSyncsColdWindowsWithOrigin(t *testing.T) {
+	logger := logging.NewNoopLogger()
+
+	services := []*service{}
+	clusters := []cluster.Cluster{}
+	serfAddrs := []string{}
+	for i := range 2 {
+		c, serfAddr, rpcAddr := createCluster(t, fmt.Sprintf("node-%d", i), serfAddrs)
+		serfAddrs = append(serfAddrs, serfAddr)
+		clusters = append(clusters, c)
+
+		srv, err := New(Config{
+			Logger:  logger,
+			Metrics: metrics.NewNoop(),
+			Cluster: c,
+		})
+		require.NoError(t, err)
+		services = append(services, srv)
+
+		cSrv, err := connectSrv.New(connectSrv.Config{
+			Logger:  logger,
+			Metrics: metrics.NewNoop(),
+			Image:   "does not matter",
+		})
+		require.NoError(t, err)
+		require.NoError(t, cSrv.AddService(connectSrv.NewRatelimitServer(srv, logger, "test-auth-token")))
+		u, err := url.Parse(rpcAddr)
+		require.NoError(t, err)
+		go cSrv.Listen(u.Host)
+
+		require.Eventually(t, func() bool {
+			client := ratelimitv1connect.NewRatelimitServiceClient(http.DefaultClient, rpcAddr)
+			res, livenessErr := client.Liveness(context.Background(), connect.NewRequest(&ratelimitv1.LivenessRequest{}))
+			return livenessErr == nil && res.Msg.Status == "ok"
+		}, time.Minute, 100*time.Millisecond)
+	}
+	for _, c := range clusters {
+		require.Eventually(t, func() bool {
+			return c.Size() == 2
+		}, time.Minute, 100*time.Millisecond)
+	}
+	t.Cleanup(func() {
+		for _, c := range clusters {
+			require.NoError(t, c.Shutdown())
+		}
+	})
+
+	identifier := uid.New("test")
+	limit := int64(10)
+	duration := time.Minute
+	origin, err := clusters[0].FindNode(bucketKey{identifier, limit, duration}.toString())
+	require.NoError(t, err)
+	originIndex, otherIndex := 0, 1
+	if origin.Id != clusters[0].NodeId() {
+		originIndex, otherIndex = 1, 0
+	}
+
+	// Exhaust the limit on the origin
+	res, err := services[originIndex].Ratelimit(context.Background(), &ratelimitv1.RatelimitRequest{
+		Identifier: identifier,
+		Limit:      limit,
+		Duration:   duration.Milliseconds(),
+		Cost:       limit,
+	})
+	require.NoError(t, err)
+	require.True(t, res.Success)
+
+	// The other node has never seen this identifier, it must ask the origin
+	// rather than pass based on its empty bucket
+	multiRes, err := services[otherIndex].MultiRatelimit(context.Background(), &ratelimitv1.RatelimitMultiRequest{
+		Ratelimits: []*ratelimitv1.RatelimitRequest{
+			{Identifier: identifier, Limit: limit, Duration: duration.Milliseconds(), Cost: 1},
+			{Identifier: uid.New("test"), Limit: 100, Duration: duration.Milliseconds(), Cost: 1},
+		},
+	})
+	require.NoError(t, err)
+	require.False(t, multiRes.Success)
+	require.Equal(t, limit, multiRes.Ratelimits[0].Current)
+}
@@ -2,6 +2,7 @@ package ratelimit
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	ratelimitv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/ratelimit/v1"
@@ -28,6 +29,8 @@ type ratelimitRequest struct {
 	Cost       int64
 	Duration   time.Duration
 	Lease      *lease
+	// In shadow mode, the request is evaluated but never blocked.
+	Shadow bool
 }
 
 type ratelimitResponse struct {
@@ -36,6 +39,8 @@ type ratelimitResponse struct {
 	Remaining int64
 	Reset     int64
 	Current   int64
+	// Set in shadow mode if the request would have been blocked.
+	WouldBlock bool
 
 	currentWindow  *ratelimitv1.Window
 	previousWindow *ratelimitv1.Window
@@ -63,7 +68,7 @@ func (r *service) removeExpiredIdentifiers() {
 	r.bucketsLock.Lock()
 	defer r.bucketsLock.Unlock()
 
-	activeRatelimits.Set(float64(len(r.buckets)))
+	defer func() { activeRatelimits.Set(float64(len(r.buckets))) }()
 	now := time.Now()
 	for id, bucket := range r.buckets {
 		bucket.Lock()
@@ -172,7 +177,10 @@ func (r *service) Take(ctx context.Context, req ratelimitRequest) ratelimitRespo
 			remaining = 0
 		}
 		return ratelimitResponse{
-			Pass:           false,
+			// In shadow mode we let the request pass without consuming tokens,
+			// so the counter behaves exactly as if the limit was enforced
+			Pass:           req.Shadow,
+			WouldBlock:     req.Shadow,
 			Remaining:      remaining,
 			Reset:          currentWindow.Start + currentWindow.Duration,
 			Limit:          req.Limit,
@@ -223,6 +231,118 @@ func (r *service) Take(ctx context.Context, req ratelimitRequest) ratelimitRespo
 	}
 }
 
+// TakeMany evaluates all requests in a single pass and only consumes tokens if
+// every request has enough capacity left. This allows enforcing an aggregate
+// limit, such as per workspace, together with a more specific one, such as per key,
+// without the aggregate limit being drained by requests that were rejected by
+// the specific one.
+//
+// The returned responses are in the same order as the requests. If not all
+// requests pass, no tokens are consumed and each response reflects the state
+// of its bucket without this request.
+func (r *service) TakeMany(ctx context.Context, reqs []ratelimitRequest) ([]ratelimitResponse, bool) {
+	ctx, span := tracing.Start(ctx, "slidingWindow.TakeMany")
+	defer span.End()
+
+	now := time.Now()
+	keys := make([]string, len(reqs))
+	buckets := make(map[string]*bucket)
+	for i := range reqs {
+		if reqs[i].Time.IsZero() {
+			reqs[i].Time = now
+		}
+		key := bucketKey{reqs[i].Identifier, reqs[i].Limit, reqs[i].Duration}
+		keys[i] = key.toString()
+		if _, ok := buckets[keys[i]]; !ok {
+			buckets[keys[i]], _ = r.getBucket(key)
+		}
+	}
+	span.SetAttributes(attribute.Int("buckets", len(buckets)))
+
+	// Always lock buckets in the same order to prevent deadlocks between
+	// concurrent calls that share some of the buckets
+	lockOrder := make([]string, 0, len(buckets))
+	for key := range buckets {
+		lockOrder = append(lockOrder, key)
+	}
+	sort.Strings(lockOrder)
+	for _, key := range lockOrder {
+		buckets[key].Lock()
+	}
+	defer func() {
+		for _, key := range lockOrder {
+			buckets[key].Unlock()
+		}
+	}()
+
+	// The same bucket may be referenced multiple times, so we need to account
+	// for the cost of earlier requests to the same bucket
+	pending := make(map[string]int64)
+	passed := make([]bool, len(reqs))
+	wouldBlock := make([]bool, len(reqs))
+	allPassed := true
+	for i, req := range reqs {
+		// FIXED-WINDOW
+		// see Take for the sliding window calculation
+		current := buckets[keys[i]].getCurrentWindow(req.Time).Counter + pending[keys[i]]
+		passed[i] = current+req.Cost <= req.Limit
+		if !passed[i] {
+			if req.Shadow {
+				passed[i] = true
+				wouldBlock[i] = true
+				continue
+			}
+			allPassed = false
+			continue
+		}
+		pending[keys[i]] += req.Cost
+	}
+
+	responses := make([]ratelimitResponse, len(reqs))
+	for i, req := range reqs {
+		b := buckets[keys[i]]
+		currentWindow := b.getCurrentWindow(req.Time)
+		previousWindow := b.getPreviousWindow(req.Time)
+
+		current := currentWindow.Counter
+		if allPassed && !wouldBlock[i] {
+			currentWindow.Counter += req.Cost
+			current = currentWindow.Counter
+			if currentWindow.Counter >= req.Limit && !currentWindow.MitigateBroadcasted && r.mitigateBuffer != nil {
+				currentWindow.MitigateBroadcasted = true
+				r.mitigateBuffer <- mitigateWindowRequest{
+					identifier: req.Identifier,
+					limit:      req.Limit,
+					duration:   req.Duration,
+					window:     currentWindow,
+				}
+			}
+		}
+
+		remaining := req.Limit - current
+		if remaining < 0 {
+			remaining = 0
+		}
+		if allPassed {
+			ratelimitsCount.WithLabelValues("true").Inc()
+		} else {
+			ratelimitsCount.WithLabelValues("false").Inc()
+		}
+		responses[i] = ratelimitResponse{
+			Pass:           passed[i],
+			WouldBlock:     wouldBlock[i],
+			Remaining:      remaining,
+			Reset:          currentWindow.Start + currentWindow.Duration,
+			Limit:          req.Limit,
+			Current:        current,
+			currentWindow:  currentWindow,
+			previousWindow: previousWindow,
+		}
+	}
+
+	return responses, allPassed
+}
+
 func (r *service) SetCounter(ctx context.Context, requests ...setCounterRequest) error {
 	ctx, span := tracing.Start(ctx, "slidingWindow.SetCounter")
 	defer span.End()
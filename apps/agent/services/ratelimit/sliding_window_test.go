@@ -116,3 +116,110 @@ func TestSlidingWindowAccuracy(t *testing.T) {
 	}
 
 }
+
+func TestTakeManyIsAtomic(t *testing.T) {
+	rl, err := New(Config{
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	global := ratelimitRequest{
+		Time:       now,
+		Identifier: uid.New("global"),
+		Limit:      10,
+		Duration:   time.Minute,
+		Cost:       1,
+	}
+	perKey := ratelimitRequest{
+		Time:       now,
+		Identifier: uid.New("key"),
+		Limit:      2,
+		Duration:   time.Minute,
+		Cost:       1,
+	}
+
+	for i := 0; i < 2; i++ {
+		res, pass := rl.TakeMany(context.Background(), []ratelimitRequest{global, perKey})
+		require.True(t, pass)
+		require.Len(t, res, 2)
+		require.Equal(t, int64(i+1), res[0].Current)
+		require.Equal(t, int64(i+1), res[1].Current)
+	}
+
+	// The per-key limit is exhausted, so the global limit must not be charged
+	for i := 0; i < 5; i++ {
+		res, pass := rl.TakeMany(context.Background(), []ratelimitRequest{global, perKey})
+		require.False(t, pass)
+		require.True(t, res[0].Pass)
+		require.False(t, res[1].Pass)
+		require.Equal(t, int64(2), res[0].Current)
+		require.Equal(t, int64(8), res[0].Remaining)
+	}
+
+	res := rl.Take(context.Background(), global)
+	require.True(t, res.Pass)
+	require.Equal(t, int64(3), res.Current)
+}
+
+func TestTakeManySameBucket(t *testing.T) {
+	rl, err := New(Config{
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	req := ratelimitRequest{
+		Time:       time.Now(),
+		Identifier: uid.New("test"),
+		Limit:      3,
+		Duration:   time.Minute,
+		Cost:       2,
+	}
+
+	// Both requests hit the same bucket and together exceed the limit
+	_, pass := rl.TakeMany(context.Background(), []ratelimitRequest{req, req})
+	require.False(t, pass)
+
+	res := rl.Take(context.Background(), req)
+	require.True(t, res.Pass)
+	require.Equal(t, int64(2), res.Current)
+}
+
+func TestTakeShadow(t *testing.T) {
+	rl, err := New(Config{
+		Logger:  logging.NewNoopLogger(),
+		Metrics: metrics.NewNoop(),
+	})
+	require.NoError(t, err)
+
+	req := ratelimitRequest{
+		Time:       time.Now(),
+		Identifier: uid.New("test"),
+		Limit:      2,
+		Duration:   time.Minute,
+		Cost:       1,
+		Shadow:     true,
+	}
+
+	for i := 0; i < 5; i++ {
+		res := rl.Take(context.Background(), req)
+		require.True(t, res.Pass)
+		require.Equal(t, i >= 2, res.WouldBlock)
+		require.LessOrEqual(t, res.Current, int64(2))
+	}
+
+	// A shadowed limit must not block the other limits
+	res, pass := rl.TakeMany(context.Background(), []ratelimitRequest{req, {
+		Time:       req.Time,
+		Identifier: uid.New("test"),
+		Limit:      10,
+		Duration:   time.Minute,
+		Cost:       1,
+	}})
+	require.True(t, pass)
+	require.True(t, res[0].WouldBlock)
+	require.Equal(t, int64(2), res[0].Current)
+	require.Equal(t, int64(1), res[1].Current)
+}
@@ -29,7 +29,7 @@ func (s *service) syncWithOrigin(req syncWithOriginRequest) {
 	client, peer, err := s.getPeerClient(ctx, key)
 	if err != nil {
 		tracing.RecordError(span, err)
-		s.logger.Warn().Err(err).Str("key", key).Msg("unable to create peer client")
+		s.logger.Warn().Err(err).Str("bucketKey", key).Msg("unable to create peer client")
 		return
 	}
 	if peer.Id == s.cluster.NodeId() {
@@ -6,7 +6,6 @@ import (
 	"fmt"
 
 	vaultv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/vault/v1"
-	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
 	"github.com/unkeyed/unkey/apps/agent/pkg/encryption"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
 	"google.golang.org/protobuf/proto"
@@ -31,13 +30,18 @@ func (s *Service) Decrypt(
 
 	cacheKey := fmt.Sprintf("%s-%s", req.Keyring, encrypted.EncryptionKeyId)
 
-	dek, hit := s.keyCache.Get(ctx, cacheKey)
-	if hit == cache.Miss {
-		dek, err = s.keyring.GetKey(ctx, req.Keyring, encrypted.EncryptionKeyId)
+	dek, found, err := s.keyLoader.GetOrLoad(ctx, cacheKey, func(ctx context.Context) (*vaultv1.DataEncryptionKey, bool, error) {
+		dek, err := s.keyring.GetKey(ctx, req.Keyring, encrypted.EncryptionKeyId)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get dek in keyring %s: %w", req.Keyring, err)
+			return nil, false, fmt.Errorf("failed to get dek in keyring %s: %w", req.Keyring, err)
 		}
-		s.keyCache.Set(ctx, cacheKey, dek)
+		return dek, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("dek %s not found in keyring %s", encrypted.EncryptionKeyId, req.Keyring)
 	}
 
 	plaintext, err := encryption.Decrypt(dek.Key, encrypted.Nonce, encrypted.Ciphertext)
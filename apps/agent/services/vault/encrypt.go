@@ -7,7 +7,6 @@ import (
 	"time"
 
 	vaultv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/vault/v1"
-	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
 	"github.com/unkeyed/unkey/apps/agent/pkg/encryption"
 	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
 	"go.opentelemetry.io/otel/attribute"
@@ -24,14 +23,18 @@ func (s *Service) Encrypt(
 
 	cacheKey := fmt.Sprintf("%s-%s", req.Keyring, LATEST)
 
-	dek, hit := s.keyCache.Get(ctx, cacheKey)
-	if hit != cache.Hit {
-		var err error
-		dek, err = s.keyring.GetOrCreateKey(ctx, req.Keyring, LATEST)
+	dek, found, err := s.keyLoader.GetOrLoad(ctx, cacheKey, func(ctx context.Context) (*vaultv1.DataEncryptionKey, bool, error) {
+		dek, err := s.keyring.GetOrCreateKey(ctx, req.Keyring, LATEST)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get latest dek in keyring %s: %w", req.Keyring, err)
+			return nil, false, fmt.Errorf("failed to get latest dek in keyring %s: %w", req.Keyring, err)
 		}
-		s.keyCache.Set(ctx, cacheKey, dek)
+		return dek, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no latest dek in keyring %s", req.Keyring)
 	}
 
 	nonce, ciphertext, err := encryption.Encrypt(dek.Key, []byte(req.GetData()))
@@ -9,6 +9,7 @@ import (
 	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
 	cacheMiddleware "github.com/unkeyed/unkey/apps/agent/pkg/cache/middleware"
 	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/pkg/membership"
 	"github.com/unkeyed/unkey/apps/agent/pkg/metrics"
 	"github.com/unkeyed/unkey/apps/agent/services/vault/keyring"
 	"github.com/unkeyed/unkey/apps/agent/services/vault/storage"
@@ -20,6 +21,11 @@ const LATEST = "LATEST"
 type Service struct {
 	logger   logging.Logger
 	keyCache cache.Cache[*vaultv1.DataEncryptionKey]
+	// Loads keys through keyCache, so concurrent misses hit storage only once
+	keyLoader *cache.Loader[*vaultv1.DataEncryptionKey]
+	// The unwrapped keyCache, for debugging
+	keyCacheInspector cache.Inspector
+	stopMetrics       func()
 
 	storage storage.Storage
 
@@ -30,10 +36,15 @@ type Service struct {
 }
 
 type Config struct {
-	Logger     logging.Logger
-	Storage    storage.Storage
-	Metrics    metrics.Metrics
-	MasterKeys []string
+	Logger logging.Logger
+	// Logs of the key cache, defaults to Logger
+	CacheLogger *logging.Logger
+	Storage     storage.Storage
+	Metrics     metrics.Metrics
+	MasterKeys  []string
+	// Optional, removals from the key cache are broadcasted to all other nodes
+	// in the cluster, for example after re-encrypting
+	Membership membership.Membership
 }
 
 func New(cfg Config) (*Service, error) {
@@ -54,26 +65,54 @@ func New(cfg Config) (*Service, error) {
 		return nil, fmt.Errorf("failed to create keyring: %w", err)
 	}
 
-	cache, err := cache.New[*vaultv1.DataEncryptionKey](cache.Config[*vaultv1.DataEncryptionKey]{
-		Fresh:    time.Hour,
-		Stale:    24 * time.Hour,
-		MaxSize:  10000,
-		Logger:   cfg.Logger,
+	cacheLogger := cfg.Logger
+	if cfg.CacheLogger != nil {
+		cacheLogger = *cfg.CacheLogger
+	}
+
+	memory, err := cache.New[*vaultv1.DataEncryptionKey](cache.Config[*vaultv1.DataEncryptionKey]{
+		Fresh: time.Hour,
+		Stale: 24 * time.Hour,
+		// 8 MiB, weighed by the encoded size of each key
+		MaxSize:  8 << 20,
+		Weigher:  cache.ProtoWeigher[*vaultv1.DataEncryptionKey](),
+		Logger:   cacheLogger,
 		Metrics:  cfg.Metrics,
 		Resource: "data_encryption_key",
 	})
 
+	measured, stopMetrics := cacheMiddleware.WithMetrics[*vaultv1.DataEncryptionKey](memory, cfg.Metrics, "data_encryption_key", "memory")
+	keyCache := cacheMiddleware.WithTracing(cacheMiddleware.WithLogging(measured, cacheLogger, "data_encryption_key"))
+	if cfg.Membership != nil {
+		keyCache = cacheMiddleware.WithInvalidation(keyCache, cfg.Membership, "data_encryption_key", cfg.Logger)
+	}
+
 	return &Service{
-		logger:         cfg.Logger,
-		storage:        cfg.Storage,
-		keyCache:       cacheMiddleware.WithTracing(cache),
-		decryptionKeys: decryptionKeys,
+		logger:            cfg.Logger,
+		storage:           cfg.Storage,
+		keyCache:          keyCache,
+		keyLoader:         cache.NewLoader(keyCache),
+		keyCacheInspector: memory,
+		stopMetrics:       stopMetrics,
+		decryptionKeys:    decryptionKeys,
 
 		encryptionKey: encryptionKey,
 		keyring:       keyring,
 	}, nil
 }
 
+// Close stops reporting the usage of the key cache.
+func (s *Service) Close() {
+	s.stopMetrics()
+}
+
+// Caches returns all caches of the service by their resource name.
+func (s *Service) Caches() map[string]cache.Inspector {
+	return map[string]cache.Inspector{
+		"data_encryption_key": s.keyCacheInspector,
+	}
+}
+
 func loadMasterKeys(masterKeys []string) (*vaultv1.KeyEncryptionKey, map[string]*vaultv1.KeyEncryptionKey, error) {
 	if len(masterKeys) == 0 {
 		return nil, nil, fmt.Errorf("no master keys provided")
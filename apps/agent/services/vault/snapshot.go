@@ -0,0 +1,58 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	vaultv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/vault/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/encryption"
+	"google.golang.org/protobuf/proto"
+)
+
+// PersistKeyCache keeps snapshots of the key cache at path, so a restarted
+// node does not need to load every key from storage again.
+// Snapshots contain plaintext data encryption keys and are therefore sealed
+// with the master key, just like the keys in storage.
+//
+// The returned function writes a final snapshot, call it during shutdown.
+func (s *Service) PersistKeyCache(ctx context.Context, path string) (func() error, error) {
+	return cache.Persist(ctx, s.keyCache, cache.PersistenceConfig{
+		Path:   path,
+		Seal:   s.sealSnapshot,
+		Open:   s.openSnapshot,
+		Logger: s.logger,
+	})
+}
+
+func (s *Service) sealSnapshot(snapshot []byte) ([]byte, error) {
+	nonce, ciphertext, err := encryption.Encrypt(s.encryptionKey.Key, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt snapshot: %w", err)
+	}
+	return proto.Marshal(&vaultv1.Encrypted{
+		Algorithm:       vaultv1.Algorithm_AES_256_GCM,
+		Nonce:           nonce,
+		Ciphertext:      ciphertext,
+		EncryptionKeyId: s.encryptionKey.Id,
+		Time:            time.Now().UnixMilli(),
+	})
+}
+
+func (s *Service) openSnapshot(sealed []byte) ([]byte, error) {
+	encrypted := &vaultv1.Encrypted{}
+	err := proto.Unmarshal(sealed, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+	kek, ok := s.decryptionKeys[encrypted.EncryptionKeyId]
+	if !ok {
+		return nil, fmt.Errorf("snapshot was sealed with unknown master key %s", encrypted.EncryptionKeyId)
+	}
+	snapshot, err := encryption.Decrypt(kek.Key, encrypted.Nonce, encrypted.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt snapshot: %w", err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,49 @@
+package vault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	vaultv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/vault/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/logging"
+	"github.com/unkeyed/unkey/apps/agent/services/vault/keys"
+	"github.com/unkeyed/unkey/apps/agent/services/vault/storage"
+)
+
+func TestPersistKeyCacheSealsKeys(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys")
+
+	_, masterKey, err := keys.GenerateMasterKey()
+	require.NoError(t, err)
+	newService := func() *Service {
+		store, err := storage.NewMemory(storage.MemoryConfig{Logger: logging.NewNoopLogger()})
+		require.NoError(t, err)
+		v, err := New(Config{Logger: logging.NewNoopLogger(), Storage: store, MasterKeys: []string{masterKey}})
+		require.NoError(t, err)
+		return v
+	}
+
+	v := newService()
+	stop, err := v.PersistKeyCache(ctx, path)
+	require.NoError(t, err)
+	dek := &vaultv1.DataEncryptionKey{Id: "dek_1", Key: []byte("0123456789abcdef0123456789abcdef")}
+	v.keyCache.Set(ctx, "keyring-dek_1", dek)
+	require.NoError(t, stop())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "dek_1")
+
+	restored := newService()
+	stop, err = restored.PersistKeyCache(ctx, path)
+	require.NoError(t, err)
+	defer stop()
+	value, hit := restored.keyCache.Get(ctx, "keyring-dek_1")
+	require.Equal(t, cache.Hit, hit)
+	require.Equal(t, dek.Key, value.Key)
+}
@@ -0,0 +1,39 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	vaultv1 "github.com/unkeyed/unkey/apps/agent/gen/proto/vault/v1"
+	"github.com/unkeyed/unkey/apps/agent/pkg/cache"
+	"github.com/unkeyed/unkey/apps/agent/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Warmup loads the latest data encryption key of each keyring into the cache,
+// so the first encryptions after a restart don't all hit storage at once.
+func (s *Service) Warmup(ctx context.Context, keyrings []string) error {
+	ctx, span := tracing.Start(ctx, tracing.NewSpanName("service.vault", "Warmup"))
+	defer span.End()
+	span.SetAttributes(attribute.Int("keyrings", len(keyrings)))
+
+	cacheKeys := make([]string, len(keyrings))
+	for i, keyring := range keyrings {
+		cacheKeys[i] = fmt.Sprintf("%s-%s", keyring, LATEST)
+	}
+
+	err := cache.Warmup(ctx, s.keyCache, cacheKeys, 10, func(ctx context.Context, cacheKey string) (*vaultv1.DataEncryptionKey, bool, error) {
+		keyring := strings.TrimSuffix(cacheKey, fmt.Sprintf("-%s", LATEST))
+		dek, err := s.keyring.GetOrCreateKey(ctx, keyring, LATEST)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get latest dek in keyring %s: %w", keyring, err)
+		}
+		return dek, true, nil
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	return nil
+}